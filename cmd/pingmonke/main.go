@@ -5,14 +5,27 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/ehawman/pingmonke-go/internal"
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "query":
+			os.Exit(runQuery(os.Args[2:]))
+		case "report":
+			os.Exit(runReport(os.Args[2:]))
+		}
+	}
+
 	verbose := flag.Bool("v", false, "Enable verbose mode")
 	debug := flag.Bool("debug-rollover", false, "Enable debug rollover mode")
 	configPath := flag.String("config", "config.yaml", "Path to config file")
@@ -30,8 +43,63 @@ func main() {
 	internal.SetDefaults(&cfg)
 	internal.PrepareLogDirectory(cfg.LogDir)
 
+	// The first SIGINT/SIGTERM starts a graceful shutdown; once it has been
+	// received, a second one kills the process immediately.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	state := internal.NewLiveStateWithRules(cfg.Targets, cfg.Events)
+	if cfg.Metrics.Listen != "" {
+		if err := internal.StartMetricsServer(ctx, cfg.Metrics.Listen, state); err != nil {
+			fmt.Println("[Metrics] Could not start exporter, continuing without it:", err)
+		}
+	}
+	if cfg.API.Listen != "" {
+		if err := internal.StartAPIServer(ctx, cfg, state); err != nil {
+			fmt.Println("[API] Could not start API server, continuing without it:", err)
+		}
+	}
+
+	var notifier *internal.Notifier
+	if len(cfg.Webhooks.Endpoints) > 0 {
+		var err error
+		if notifier, err = internal.NewNotifier(cfg.Webhooks); err != nil {
+			fmt.Println("[Webhook] Invalid webhook config, continuing without alerts:", err)
+		} else {
+			state.OnEvent(notifier.Notify)
+		}
+	}
+
+	// Announce events on the console as they start and end
+	transitions, stopTransitions := state.Subscribe(64)
+	go logEventTransitions(transitions)
+
 	fmt.Println("Starting pingmonke service...")
-	internal.StartScheduler(cfg)
+	err := internal.StartScheduler(ctx, cfg, state)
+	stopTransitions()
+	if notifier != nil {
+		notifier.Flush(cfg.ShutdownTimeout)
+	}
+	if err != nil {
+		fmt.Println("[Shutdown] Error:", err)
+		os.Exit(1)
+	}
 
+	fmt.Println("[Shutdown] pingmonke stopped cleanly")
 	os.Exit(0)
 }
+
+// logEventTransitions prints each event start and end as it is detected.
+func logEventTransitions(transitions <-chan internal.EventTransition) {
+	for t := range transitions {
+		switch t.Kind {
+		case internal.EventStarted:
+			fmt.Printf("[Event] %s: event started at %s\n", t.Target, t.Event.StartTime.Format("15:04:05"))
+		case internal.EventEnded:
+			fmt.Printf("[Event] %s: event ended after %v (%d of %d pings bad)\n", t.Target, t.Event.Duration.Round(time.Second), t.BadPings, t.TotalPings)
+		}
+	}
+}
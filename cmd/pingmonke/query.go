@@ -0,0 +1,108 @@
+// cmd/pingmonke/query.go
+// @version sqlite
+// @description pingmonke query subcommand: search the SQLite history for probes and events
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ehawman/pingmonke-go/internal"
+)
+
+const queryUsage = `Usage: pingmonke query [flags]
+
+Searches the history database written by the sqlite sink. Examples:
+
+  pingmonke query -status timeout -from 2026-01-07T09:00 -to 2026-01-07T17:00
+  pingmonke query -events -min-duration 5m -since 7d
+  pingmonke query -target isp -since 1h -format csv
+
+Flags:
+`
+
+// runQuery runs pingmonke query with args, returning the exit code.
+func runQuery(args []string) int {
+	fs := flag.NewFlagSet("query", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), queryUsage)
+		fs.PrintDefaults()
+	}
+	configPath := fs.String("config", "config.yaml", "Path to config file")
+	dbPath := fs.String("db", "", "History database (default: the sqlite sink's path)")
+	events := fs.Bool("events", false, "List events instead of probes")
+	target := fs.String("target", "", "Only this target")
+	statuses := fs.String("status", "", "Only these statuses, comma-separated (e.g. timeout,unreachable)")
+	from := fs.String("from", "", "Start time: a date, date and time, or RFC3339")
+	to := fs.String("to", "", "End time (exclusive): a date, date and time, or RFC3339")
+	since := fs.String("since", "", "Look back this far instead of -from (e.g. 90m, 24h, 7d)")
+	minDuration := fs.Duration("min-duration", 0, "Only events lasting at least this long (e.g. 5m)")
+	limit := fs.Int("limit", 1000, "Show at most this many of the most recent matches (0 for all)")
+	format := fs.String("format", "table", "Output format: table, csv or jsonl")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	// Config messages go to stderr so CSV and JSON output stays clean
+	stdout := os.Stdout
+	os.Stdout = os.Stderr
+	cfg := internal.LoadConfig(*configPath)
+	internal.SetDefaults(&cfg)
+	os.Stdout = stdout
+	loc := cfg.Location
+
+	opts := internal.QueryOptions{
+		Events:      *events,
+		Format:      *format,
+		Target:      *target,
+		MinDuration: *minDuration,
+		Limit:       *limit,
+	}
+	if *statuses != "" {
+		for _, s := range strings.Split(*statuses, ",") {
+			opts.Statuses = append(opts.Statuses, strings.TrimSpace(s))
+		}
+	}
+	var err error
+	if *from != "" {
+		if opts.From, err = internal.ParseQueryTime(*from, loc); err != nil {
+			return queryError(err)
+		}
+	}
+	if *to != "" {
+		if opts.To, err = internal.ParseQueryTime(*to, loc); err != nil {
+			return queryError(err)
+		}
+	}
+	if *since != "" {
+		lookback, err := internal.ParseLookback(*since)
+		if err != nil {
+			return queryError(err)
+		}
+		opts.From = time.Now().Add(-lookback)
+	}
+
+	path := *dbPath
+	if path == "" {
+		path = cfg.HistoryPath()
+	}
+	db, err := internal.OpenHistoryForReading(internal.ExpandHome(path), loc)
+	if err != nil {
+		return queryError(err)
+	}
+	defer db.Close()
+
+	if err := internal.RunQuery(os.Stdout, db, opts); err != nil {
+		return queryError(err)
+	}
+	return 0
+}
+
+func queryError(err error) int {
+	fmt.Fprintln(os.Stderr, "Error:", err)
+	return 1
+}
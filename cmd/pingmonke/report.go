@@ -0,0 +1,89 @@
+// cmd/pingmonke/report.go
+// @version report
+// @description pingmonke report subcommand: one consolidated report over a range of days of period files
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/ehawman/pingmonke-go/internal"
+)
+
+const reportUsage = `Usage: pingmonke report -from DATE [-to DATE] [flags]
+
+Reads every period file in the log directory from the start of -from to the
+end of -to and writes one report: uptime, loss, latency percentiles and
+events, by day and by hour of day. Time pingmonke wasn't running is listed
+as such and not counted as uptime. Examples:
+
+  pingmonke report -from 2026-09-01 -to 2026-09-30
+  pingmonke report -from 2026-09-01 -target isp -format csv -o september.csv
+  pingmonke report -from 2026-09-01 -to 2026-09-30 -format html -o september.html
+
+Flags:
+`
+
+// runReport runs pingmonke report with args, returning the exit code.
+func runReport(args []string) int {
+	fs := flag.NewFlagSet("report", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), reportUsage)
+		fs.PrintDefaults()
+	}
+	configPath := fs.String("config", "config.yaml", "Path to config file")
+	from := fs.String("from", "", "First day of the report (e.g. 2026-09-01)")
+	to := fs.String("to", "", "Last day of the report, included (default: today)")
+	target := fs.String("target", "", "Only this target")
+	format := fs.String("format", "text", "Output format: text, csv or html (one self-contained page with charts)")
+	out := fs.String("o", "", "Write the report to this file instead of stdout")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *from == "" {
+		fs.Usage()
+		return 2
+	}
+
+	// Config messages go to stderr so the report stays clean
+	stdout := os.Stdout
+	os.Stdout = os.Stderr
+	cfg := internal.LoadConfig(*configPath)
+	internal.SetDefaults(&cfg)
+	os.Stdout = stdout
+	loc := cfg.Location
+
+	opts := internal.ReportOptions{Target: *target, Format: *format}
+	var err error
+	if opts.From, err = time.ParseInLocation(time.DateOnly, *from, loc); err != nil {
+		return queryError(fmt.Errorf("cannot read -from %q as a date such as 2026-09-01", *from))
+	}
+	last := time.Now().In(loc)
+	if *to != "" {
+		if last, err = time.ParseInLocation(time.DateOnly, *to, loc); err != nil {
+			return queryError(fmt.Errorf("cannot read -to %q as a date such as 2026-09-30", *to))
+		}
+	}
+	opts.To = time.Date(last.Year(), last.Month(), last.Day()+1, 0, 0, 0, 0, loc)
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return queryError(err)
+		}
+		defer f.Close()
+		w = f
+	}
+	if err := internal.RunReport(w, cfg, opts); err != nil {
+		return queryError(err)
+	}
+	if *out != "" {
+		fmt.Fprintln(os.Stderr, "[Report] Wrote", *out)
+	}
+	return 0
+}
@@ -17,31 +17,48 @@ import (
 
 func main() {
 	file := flag.String("file", "", "Log file to tail")
+	target := flag.String("target", "", "Target name to tail when several targets are configured")
 	configPath := flag.String("config", "config.yaml", "Path to config file")
 	nonInteractive := flag.Bool("non-interactive", false, "Non-interactive mode (plain output)")
+	useDB := flag.Bool("db", false, "Tail the sqlite sink's history database instead of a log file")
+	dbFile := flag.String("db-file", "", "History database to tail (implies --db; default: the sqlite sink's path)")
 	flag.Parse()
 
 	// Load config
 	cfg := internal.LoadConfig(*configPath)
+	internal.SetDefaults(&cfg)
+
+	if *useDB || *dbFile != "" {
+		tailDatabase(cfg, *dbFile, *target, *nonInteractive)
+		return
+	}
 
 	// Track if file was explicitly provided
 	explicitFile := *file != ""
 
 	// If no file specified, try to find the most recent log file from config
 	if *file == "" {
-		*file = findMostRecentLogFile(internal.ExpandHome(cfg.LogDir))
+		*file = findMostRecentLogFile(internal.ExpandHome(cfg.LogDir), *target)
 		if *file == "" {
 			fmt.Println("Error: No ping log file found")
 			os.Exit(1)
 		}
 	}
 
+	// Color and judge pings by the thresholds of the target that wrote the file
+	thresholds := cfg.ThresholdsFor(internal.LogFileTarget(*file))
+
 	if *nonInteractive {
 		// Simple non-interactive output
-		displayNonInteractive(*file, cfg.Tailmonke.LinesToDisplay)
+		lines, err := internal.ReadPingFile(*file)
+		if err != nil {
+			fmt.Printf("Error reading file: %v\n", err)
+			os.Exit(1)
+		}
+		displayNonInteractive(lines, cfg.Tailmonke.LinesToDisplay, thresholds)
 	} else {
 		// Interactive TUI mode with bubbletea
-		err := internal.RunTailmonkeTUIWithOptions(*file, cfg.Tailmonke.LinesToDisplay, explicitFile)
+		err := internal.RunTailmonkeTUIWithOptions(*file, cfg, explicitFile, thresholds)
 		if err != nil {
 			fmt.Printf("Error running TUI: %v\n", err)
 			os.Exit(1)
@@ -49,14 +66,43 @@ func main() {
 	}
 }
 
-// displayNonInteractive shows the log file in simple text mode
-func displayNonInteractive(filePath string, linesToDisplay int) {
-	lines, err := internal.ReadPingFile(filePath)
+// tailDatabase tails target (or the most recently probed one) in the history database
+func tailDatabase(cfg internal.Config, path, target string, nonInteractive bool) {
+	if path == "" {
+		path = cfg.HistoryPath()
+	}
+	db, err := internal.OpenHistoryForReading(internal.ExpandHome(path), cfg.Location)
 	if err != nil {
-		fmt.Printf("Error reading file: %v\n", err)
+		fmt.Println("Error:", err)
+		os.Exit(1)
+	}
+	defer db.Close()
+
+	if target == "" {
+		if target, err = db.LatestTarget(); err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+	}
+	thresholds := cfg.ThresholdsFor(target)
+
+	if nonInteractive {
+		lines, err := db.RecentPings(target, internal.DBTailLines)
+		if err != nil {
+			fmt.Printf("Error reading database: %v\n", err)
+			os.Exit(1)
+		}
+		displayNonInteractive(lines, cfg.Tailmonke.LinesToDisplay, thresholds)
+		return
+	}
+	if err := internal.RunTailmonkeTUIFromDB(db, target, cfg, thresholds); err != nil {
+		fmt.Printf("Error running TUI: %v\n", err)
 		os.Exit(1)
 	}
+}
 
+// displayNonInteractive shows the latest lines in simple text mode
+func displayNonInteractive(lines []internal.PingLine, linesToDisplay int, thresholds internal.Thresholds) {
 	// Display header with green (default) health color
 	fmt.Println(internal.FormatHeader(internal.ColorGreen))
 	fmt.Println(strings.Repeat("-", 70))
@@ -69,17 +115,21 @@ func displayNonInteractive(filePath string, linesToDisplay int) {
 
 	widths := internal.GetColumnWidths(lines[start:])
 	for _, line := range lines[start:] {
-		fmt.Println(line.GetColoredLine(widths[:]))
+		fmt.Println(line.GetColoredLine(widths[:], thresholds))
 	}
 
 	// Show summary
 	fmt.Println(strings.Repeat("-", 70))
-	total, ok, delayed, timeout, avg := internal.GetSummaryStats(filePath)
-	fmt.Println(internal.FormatSummaryLine(total, ok, delayed, timeout, avg))
+	total, ok, delayed, timeout, avg := internal.SummarizeLines(lines)
+	fmt.Println(internal.FormatSummaryLine(total, ok, delayed, timeout, avg, thresholds))
+	if stats := internal.FormatStatsLine(internal.StatsForLines(lines, thresholds), thresholds); stats != "" {
+		fmt.Println(stats)
+	}
 }
 
-// findMostRecentLogFile finds the most recently modified *-pings.csv file in the log directory
-func findMostRecentLogFile(logDir string) string {
+// findMostRecentLogFile finds the most recently modified ping log (CSV or JSON Lines) in the log directory.
+// If target is set, only that target's files are considered.
+func findMostRecentLogFile(logDir string, target string) string {
 	entries, err := os.ReadDir(logDir)
 	if err != nil {
 		fmt.Printf("[Error] Could not read log directory %s: %v\n", logDir, err)
@@ -88,10 +138,10 @@ func findMostRecentLogFile(logDir string) string {
 
 	var logFiles []os.DirEntry
 	for _, entry := range entries {
-		if !entry.IsDir() && filepath.Ext(entry.Name()) == ".csv" {
+		if !entry.IsDir() {
 			name := entry.Name()
-			// Check if it matches the pattern *-pings.csv
-			if len(name) > 10 && name[len(name)-10:] == "-pings.csv" {
+			// Check if it matches the pattern *-pings.csv or *-pings.jsonl
+			if internal.IsPingLogFile(name) && (target == "" || internal.LogFileTarget(name) == target) {
 				logFiles = append(logFiles, entry)
 			}
 		}
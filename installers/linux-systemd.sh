@@ -149,6 +149,8 @@ Type=simple
 ExecStart=$HOME/.local/bin/pingmonke
 Restart=always
 RestartSec=10
+# pingmonke waits up to shutdown_timeout (20s) for in-flight probes on SIGTERM
+TimeoutStopSec=30
 StandardOutput=journal
 StandardError=journal
 SyslogIdentifier=pingmonke
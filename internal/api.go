@@ -0,0 +1,350 @@
+// internal/api.go
+// @version api
+// @description Read-only JSON API over the live state and the period logs: /status, /pings and /events
+
+package internal
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// APIConfig holds the JSON API settings.
+type APIConfig struct {
+	Listen string `yaml:"listen"` // e.g. 127.0.0.1:8787 or :8787 (localhost); empty disables the API
+	Token  string `yaml:"token"`  // when set, requests need "Authorization: Bearer <token>"
+}
+
+// apiEvent is an event as reported by /status and /events. Times are RFC3339
+// in the configured timezone; unset ones are omitted.
+type apiEvent struct {
+	Target          string  `json:"target,omitempty"`
+	Period          string  `json:"period,omitempty"`
+	Family          string  `json:"family,omitempty"`
+	Active          bool    `json:"active"`
+	StartTime       string  `json:"start_time,omitempty"`
+	EndTime         string  `json:"end_time,omitempty"`
+	LastBadPing     string  `json:"last_bad_ping,omitempty"`
+	LatestPing      string  `json:"latest_ping,omitempty"`
+	DurationSeconds float64 `json:"duration_seconds"`
+	Class           string  `json:"class,omitempty"`
+	Severity        float64 `json:"severity"`
+}
+
+// apiSummary is the GetSummaryStats view of a target's recent pings.
+type apiSummary struct {
+	Total        int     `json:"total"`
+	OK           int     `json:"ok"`
+	Delayed      int     `json:"delayed"` // degraded, delayed and severe
+	Failed       int     `json:"failed"`
+	AvgLatencyMs float64 `json:"avg_latency_ms"`
+}
+
+type apiTargetStatus struct {
+	Target  string     `json:"target"`
+	Host    string     `json:"host"`
+	Probe   string     `json:"probe"`
+	Event   apiEvent   `json:"event"`
+	Summary apiSummary `json:"summary"`
+}
+
+type apiPing struct {
+	Target    string `json:"target"`
+	StartTime string `json:"start_time"`
+	EndTime   string `json:"end_time"`
+	LatencyMs int64  `json:"latency_ms"`
+	Status    string `json:"status"`
+	Family    string `json:"family,omitempty"`
+
+	start time.Time // for sorting
+}
+
+// APIHandler serves the JSON API. All endpoints take an optional ?target=
+// to report on a single target.
+func APIHandler(cfg Config, state *LiveState) http.Handler {
+	api := &apiServer{cfg: cfg, state: state}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /status", api.status)
+	mux.HandleFunc("GET /pings", api.pings)
+	mux.HandleFunc("GET /events", api.events)
+	return requireToken(cfg.API.Token, mux)
+}
+
+// StartAPIServer serves the JSON API until ctx is cancelled. Like the metrics
+// exporter it returns once the listener is bound.
+func StartAPIServer(ctx context.Context, cfg Config, state *LiveState) error {
+	addr := apiListenAddr(cfg.API.Listen)
+	bound, err := startHTTPServer(ctx, addr, APIHandler(cfg, state), "API")
+	if err != nil {
+		return err
+	}
+	if cfg.API.Token == "" && !isLoopback(addr) {
+		fmt.Printf("[API] Warning: serving on %s without a token, anyone who can reach it can read your ping data\n", addr)
+	}
+	fmt.Printf("[API] Serving JSON API on http://%s\n", bound)
+	return nil
+}
+
+// apiListenAddr binds addresses without a host (":8787" or "8787") to
+// localhost; listing the wildcard host ("0.0.0.0:8787") exposes the API.
+func apiListenAddr(listen string) string {
+	if !strings.Contains(listen, ":") {
+		listen = ":" + listen
+	}
+	host, port, err := net.SplitHostPort(listen)
+	if err != nil || host != "" {
+		return listen
+	}
+	return net.JoinHostPort("127.0.0.1", port)
+}
+
+// isLoopback reports whether addr only accepts local connections.
+func isLoopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// requireToken rejects requests without the bearer token. An empty token
+// leaves the API open.
+func requireToken(token string, next http.Handler) http.Handler {
+	if token == "" {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		given, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="pingmonke"`)
+			writeAPIError(w, http.StatusUnauthorized, "missing or invalid token")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+type apiServer struct {
+	cfg   Config
+	state *LiveState
+}
+
+// status reports each target's event state and a summary of its recent pings.
+func (a *apiServer) status(w http.ResponseWriter, r *http.Request) {
+	snaps, ok := a.selectTargets(w, r)
+	if !ok {
+		return
+	}
+
+	statuses := []apiTargetStatus{}
+	for _, snap := range snaps {
+		total, okCount, delayed, failed, avg := SummarizeLines(snap.recent)
+		statuses = append(statuses, apiTargetStatus{
+			Target: snap.target.Label(),
+			Host:   snap.target.Host,
+			Probe:  snap.target.Probe,
+			Event:  a.eventStatus(snap.event),
+			Summary: apiSummary{
+				Total:        total,
+				OK:           okCount,
+				Delayed:      delayed,
+				Failed:       failed,
+				AvgLatencyMs: avg,
+			},
+		})
+	}
+	writeJSON(w, map[string]any{"targets": statuses})
+}
+
+// pings lists the pings kept in memory, oldest first. ?since= takes an
+// RFC3339 time or a duration back from now, such as 15m.
+func (a *apiServer) pings(w http.ResponseWriter, r *http.Request) {
+	snaps, ok := a.selectTargets(w, r)
+	if !ok {
+		return
+	}
+	var since time.Time
+	if v := r.URL.Query().Get("since"); v != "" {
+		var err error
+		if since, err = parseSince(v, time.Now()); err != nil {
+			writeAPIError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	loc := a.cfg.location()
+	pings := []apiPing{}
+	for _, snap := range snaps {
+		for _, line := range snap.recent {
+			start, err := parsePingTime(line.StartTime)
+			if err != nil {
+				continue
+			}
+			end, _ := parsePingTime(line.EndTime)
+			start, end = inZone(start, loc), inZone(end, loc)
+			if start.Before(since) {
+				continue
+			}
+			pings = append(pings, apiPing{
+				Target:    snap.target.Label(),
+				StartTime: start.Format(time.RFC3339Nano),
+				EndTime:   end.Format(time.RFC3339Nano),
+				LatencyMs: line.Latency,
+				Status:    line.Status,
+				Family:    line.Family,
+				start:     start,
+			})
+		}
+	}
+	slices.SortStableFunc(pings, func(a, b apiPing) int { return a.start.Compare(b.start) })
+	writeJSON(w, map[string]any{"pings": pings})
+}
+
+// events runs summary event detection over the log files of a period.
+// ?date= takes a period stamp as used in file names (2026-01-07,
+// 2026-01-07T14, ...) and matches every period that starts with it; it
+// defaults to the current period.
+func (a *apiServer) events(w http.ResponseWriter, r *http.Request) {
+	snaps, ok := a.selectTargets(w, r)
+	if !ok {
+		return
+	}
+	date := r.URL.Query().Get("date")
+	if date == "" {
+		start, _ := calculatePeriod(a.cfg)
+		date = start.Format(a.cfg.periodSpec().layout)
+	} else if !isPeriodStamp(date) {
+		writeAPIError(w, http.StatusBadRequest, fmt.Sprintf("date %q is not a period stamp such as 2026-01-07", date))
+		return
+	}
+
+	byName := map[string]TargetConfig{}
+	for _, snap := range snaps {
+		byName[snap.target.Name] = snap.target
+	}
+
+	logDir := ExpandHome(a.cfg.LogDir)
+	files, _ := filepath.Glob(filepath.Join(logDir, globEscape(date)+"*-pings.*"))
+	loc := a.cfg.location()
+	events := []apiEvent{}
+	for _, file := range files {
+		if !IsPingLogFile(file) {
+			continue
+		}
+		period, name := splitLogFileName(file)
+		target, ok := byName[name]
+		if !ok {
+			continue
+		}
+		pings, err := readPingRecords(file)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				fmt.Printf("[API] Error reading %s: %v\n", file, err)
+			}
+			continue
+		}
+		for _, event := range a.cfg.EventDetector(target.Thresholds).Detect(pings) {
+			events = append(events, apiEvent{
+				Target:          target.Label(),
+				Period:          period,
+				Family:          event.Family,
+				StartTime:       formatAPITime(inZone(event.StartTime, loc)),
+				EndTime:         formatAPITime(inZone(event.EndTime, loc)),
+				DurationSeconds: event.EndTime.Sub(event.StartTime).Seconds(),
+				Class:           event.Class,
+				Severity:        event.Severity,
+			})
+		}
+	}
+	writeJSON(w, map[string]any{"date": date, "events": events})
+}
+
+// selectTargets returns the live state of the ?target= target, or of every
+// target when it is absent. It writes a 404 for an unknown target.
+func (a *apiServer) selectTargets(w http.ResponseWriter, r *http.Request) ([]targetSnapshot, bool) {
+	snaps := a.state.snapshot()
+	name := r.URL.Query().Get("target")
+	if name == "" {
+		return snaps, true
+	}
+	for _, snap := range snaps {
+		if snap.target.Label() == name {
+			return []targetSnapshot{snap}, true
+		}
+	}
+	writeAPIError(w, http.StatusNotFound, fmt.Sprintf("unknown target %q", name))
+	return nil, false
+}
+
+func (a *apiServer) eventStatus(event EventStatus) apiEvent {
+	loc := a.cfg.location()
+	return apiEvent{
+		Active:          event.IsActive,
+		StartTime:       formatAPITime(inLocation(event.StartTime, loc)),
+		EndTime:         formatAPITime(inLocation(event.EndTime, loc)),
+		LastBadPing:     formatAPITime(inLocation(event.LastBadPing, loc)),
+		LatestPing:      formatAPITime(inLocation(event.LatestPingTime, loc)),
+		DurationSeconds: event.Duration.Seconds(),
+		Class:           event.Class,
+		Severity:        event.Severity,
+	}
+}
+
+// parseSince reads a ?since= value: an RFC3339 time or a duration before now.
+func parseSince(v string, now time.Time) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339Nano, v); err == nil {
+		return t, nil
+	}
+	if d, err := time.ParseDuration(v); err == nil && d >= 0 {
+		return now.Add(-d), nil
+	}
+	return time.Time{}, fmt.Errorf("since %q is neither an RFC3339 time nor a duration", v)
+}
+
+// isPeriodStamp reports whether v is a whole file name stamp of some period length.
+func isPeriodStamp(v string) bool {
+	for _, layout := range periodStampLayouts {
+		if _, err := time.Parse(layout, v); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// globEscape quotes the pattern characters filepath.Glob would interpret.
+func globEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "*", `\*`, "?", `\?`, "[", `\[`).Replace(s)
+}
+
+func formatAPITime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339Nano)
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
+
+func writeAPIError(w http.ResponseWriter, code int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]string{"error": msg})
+}
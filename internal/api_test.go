@@ -0,0 +1,188 @@
+package internal
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// getJSON requests path from h and decodes the response into v
+func getJSON(t *testing.T, h http.Handler, path string, v any) int {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+		t.Fatalf("GET %s: invalid JSON %q: %v", path, rec.Body.String(), err)
+	}
+	return rec.Code
+}
+
+// TestAPIStatus tests the event state and rolling summary per target
+func TestAPIStatus(t *testing.T) {
+	isp := TargetConfig{Name: "isp", Host: "1.1.1.1", Probe: "tcp", Thresholds: DefaultThresholds}
+	web := TargetConfig{Name: "web", Host: "example.com", Probe: "http", Thresholds: DefaultThresholds}
+	state := NewLiveState([]TargetConfig{isp, web})
+	cfg := Config{Location: time.UTC, Targets: []TargetConfig{isp, web}}
+
+	base := time.Date(2026, 1, 7, 14, 0, 0, 0, time.UTC)
+	recordPing(state, isp, base, "v4", "ok", 10*time.Millisecond)
+	recordPing(state, isp, base.Add(15*time.Second), "v4", "timeout", 0)
+	recordPing(state, isp, base.Add(30*time.Second), "v4", "timeout", 0)
+	recordPing(state, isp, base.Add(45*time.Second), "v4", "delayed", 300*time.Millisecond)
+
+	var resp struct{ Targets []apiTargetStatus }
+	if code := getJSON(t, APIHandler(cfg, state), "/status", &resp); code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", code)
+	}
+	if len(resp.Targets) != 2 || resp.Targets[0].Target != "isp" || resp.Targets[1].Target != "web" {
+		t.Fatalf("Expected isp and web in config order, got %+v", resp.Targets)
+	}
+
+	got := resp.Targets[0]
+	if !got.Event.Active || got.Event.StartTime != "2026-01-07T14:00:15Z" {
+		t.Errorf("Expected an active event from 14:00:15, got %+v", got.Event)
+	}
+	want := apiSummary{Total: 4, OK: 1, Delayed: 1, Failed: 2, AvgLatencyMs: 155}
+	if got.Summary != want {
+		t.Errorf("Expected summary %+v, got %+v", want, got.Summary)
+	}
+	if resp.Targets[1].Event.Active || resp.Targets[1].Summary.Total != 0 {
+		t.Errorf("Expected an idle web target, got %+v", resp.Targets[1])
+	}
+
+	var errResp map[string]string
+	if code := getJSON(t, APIHandler(cfg, state), "/status?target=nope", &errResp); code != http.StatusNotFound || errResp["error"] == "" {
+		t.Errorf("Expected 404 with an error for an unknown target, got %d %v", code, errResp)
+	}
+}
+
+// TestAPIPings tests listing recent pings with ?since= and ?target=
+func TestAPIPings(t *testing.T) {
+	isp := TargetConfig{Name: "isp", Thresholds: DefaultThresholds}
+	web := TargetConfig{Name: "web", Thresholds: DefaultThresholds}
+	state := NewLiveState([]TargetConfig{isp, web})
+	cfg := Config{Location: time.UTC}
+
+	base := time.Date(2026, 1, 7, 14, 0, 0, 0, time.UTC)
+	recordPing(state, isp, base, "v4", "ok", 10*time.Millisecond)
+	recordPing(state, web, base.Add(5*time.Second), "v6", "ok", 20*time.Millisecond)
+	recordPing(state, isp, base.Add(15*time.Second), "v4", "timeout", 0)
+
+	var resp struct{ Pings []apiPing }
+	getJSON(t, APIHandler(cfg, state), "/pings", &resp)
+	if len(resp.Pings) != 3 || resp.Pings[1].Target != "web" || resp.Pings[1].Family != "v6" {
+		t.Fatalf("Expected 3 pings merged by time, got %+v", resp.Pings)
+	}
+
+	resp.Pings = nil
+	getJSON(t, APIHandler(cfg, state), "/pings?target=isp&since=2026-01-07T14:00:01Z", &resp)
+	if len(resp.Pings) != 1 || resp.Pings[0].Status != "timeout" || resp.Pings[0].StartTime != "2026-01-07T14:00:15Z" {
+		t.Errorf("Expected only the isp timeout, got %+v", resp.Pings)
+	}
+
+	var errResp map[string]string
+	if code := getJSON(t, APIHandler(cfg, state), "/pings?since=yesterday", &errResp); code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a bad since, got %d", code)
+	}
+}
+
+// TestAPIEvents tests event detection over a period's log file
+func TestAPIEvents(t *testing.T) {
+	dir := t.TempDir()
+	isp := TargetConfig{Name: "isp", Thresholds: DefaultThresholds}
+	cfg := Config{LogDir: dir, Location: time.UTC, Targets: []TargetConfig{isp}}
+
+	f, err := os.Create(filepath.Join(dir, "2026-01-07-isp-pings.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := csv.NewWriter(f)
+	w.Write(pingCSVHeader)
+	base := time.Date(2026, 1, 7, 14, 0, 0, 0, time.UTC)
+	statuses := []string{"ok", "timeout", "timeout", "ok", "ok", "ok", "ok", "ok", "ok"}
+	for i, status := range statuses {
+		at := base.Add(time.Duration(i) * 15 * time.Second)
+		w.Write([]string{formatTimestamp(at), formatTimestamp(at), "10", status, "v4"})
+	}
+	w.Flush()
+	f.Close()
+
+	var resp struct {
+		Date   string
+		Events []apiEvent
+	}
+	if code := getJSON(t, APIHandler(cfg, NewLiveState(cfg.Targets)), "/events?date=2026-01-07", &resp); code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", code)
+	}
+	if len(resp.Events) != 1 {
+		t.Fatalf("Expected 1 event, got %+v", resp.Events)
+	}
+	got := resp.Events[0]
+	if got.Target != "isp" || got.Period != "2026-01-07" || got.StartTime != "2026-01-07T14:00:15Z" || got.EndTime != "2026-01-07T14:00:45Z" {
+		t.Errorf("Unexpected event %+v", got)
+	}
+
+	resp.Events = nil
+	getJSON(t, APIHandler(cfg, NewLiveState(cfg.Targets)), "/events?date=2026-01-08", &resp)
+	if len(resp.Events) != 0 {
+		t.Errorf("Expected no events on another day, got %+v", resp.Events)
+	}
+
+	var errResp map[string]string
+	if code := getJSON(t, APIHandler(cfg, NewLiveState(cfg.Targets)), "/events?date=2026-01-0*", &errResp); code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a bad date, got %d", code)
+	}
+}
+
+// TestAPIToken tests bearer token auth
+func TestAPIToken(t *testing.T) {
+	cfg := Config{Location: time.UTC, API: APIConfig{Token: "s3cret"}}
+	h := APIHandler(cfg, NewLiveState(nil))
+
+	for header, wantCode := range map[string]int{
+		"":              http.StatusUnauthorized,
+		"Bearer wrong":  http.StatusUnauthorized,
+		"s3cret":        http.StatusUnauthorized,
+		"Bearer s3cret": http.StatusOK,
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/status", nil)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != wantCode {
+			t.Errorf("Authorization %q: expected %d, got %d", header, wantCode, rec.Code)
+		}
+	}
+}
+
+// TestAPIListenAddr tests that host-less addresses bind to localhost
+func TestAPIListenAddr(t *testing.T) {
+	tests := []struct {
+		listen   string
+		want     string
+		loopback bool
+	}{
+		{":8787", "127.0.0.1:8787", true},
+		{"8787", "127.0.0.1:8787", true},
+		{"localhost:8787", "localhost:8787", true},
+		{"[::1]:8787", "[::1]:8787", true},
+		{"0.0.0.0:8787", "0.0.0.0:8787", false},
+		{"192.168.1.10:8787", "192.168.1.10:8787", false},
+	}
+	for _, tt := range tests {
+		got := apiListenAddr(tt.listen)
+		if got != tt.want {
+			t.Errorf("apiListenAddr(%q) = %q, want %q", tt.listen, got, tt.want)
+		}
+		if isLoopback(got) != tt.loopback {
+			t.Errorf("isLoopback(%q) = %v, want %v", got, !tt.loopback, tt.loopback)
+		}
+	}
+}
@@ -21,15 +21,27 @@ type TailmonkeModel struct {
 	lastError        string
 	lastRefresh      time.Time
 	summaryLine      string
+	statsLine        string // Percentiles, jitter and loss under the summary
 	columnWidths     [3]int
-	newFilePath      string      // Detected new file available for switching
-	lastFileCheck    time.Time   // Last time we checked for new files
-	explicitFile     bool        // If true, user provided --file flag, disable file detection
-	lastNotification string      // Last notification message to display
-	notificationTime time.Time   // When the notification was set
-	eventStatus      EventStatus // Current event status
+	newFilePath      string          // Detected new file available for switching
+	lastFileCheck    time.Time       // Last time we checked for new files
+	explicitFile     bool            // If true, user provided --file flag, disable file detection
+	lastNotification string          // Last notification message to display
+	notificationTime time.Time       // When the notification was set
+	eventStatus      EventStatus     // Current event status
+	detector         *StreamDetector // Follows events across reloads, fed only new lines
+	fedFile          string          // File (or db target) the detector was fed from
+	lastFed          PingLine        // Last line fed to the detector
+	thresholds       Thresholds      // Latency tiers of the tailed target
+	cfg              Config          // Event rules and summary settings
+	db               *HistoryDB      // Read from the history database instead of filePath when set
+	dbTarget         string          // Target tailed from db
+	dbVersion        int64           // db data version last loaded
 }
 
+// DBTailLines is how many of the latest pings tailmonke --db loads and summarizes
+const DBTailLines = 1000
+
 // Message types for bubbletea
 type FileUpdatedMsg struct {
 	lines []PingLine
@@ -40,19 +52,41 @@ type TickMsg time.Time
 
 // NewTailmonkeModel creates a new TUI model
 func NewTailmonkeModel(filePath string, linesToDisplay int) *TailmonkeModel {
-	return NewTailmonkeModelWithOptions(filePath, linesToDisplay, false)
+	return NewTailmonkeModelWithOptions(filePath, defaultTailConfig(linesToDisplay), false, DefaultThresholds)
+}
+
+// defaultTailConfig is the config of a tailmonke started without one.
+func defaultTailConfig(linesToDisplay int) Config {
+	return Config{Events: DefaultEventRules, Tailmonke: TailmonkeConfig{LinesToDisplay: linesToDisplay}}
 }
 
-// NewTailmonkeModelWithOptions creates a new TUI model with explicit options
-func NewTailmonkeModelWithOptions(filePath string, linesToDisplay int, explicitFile bool) *TailmonkeModel {
+// NewTailmonkeModelWithOptions creates a new TUI model with explicit options.
+// Events are detected, and summaries written, with cfg's settings.
+func NewTailmonkeModelWithOptions(filePath string, cfg Config, explicitFile bool, th Thresholds) *TailmonkeModel {
 	logDir := filepath.Dir(filePath)
 	return &TailmonkeModel{
 		filePath:       filePath,
 		logDir:         logDir,
-		linesToDisplay: linesToDisplay,
+		linesToDisplay: cfg.Tailmonke.LinesToDisplay,
 		lastRefresh:    time.Now(),
 		lastFileCheck:  time.Now(),
 		explicitFile:   explicitFile,
+		thresholds:     th,
+		cfg:            cfg,
+	}
+}
+
+// NewTailmonkeModelFromDB creates a TUI model tailing target in the history database
+func NewTailmonkeModelFromDB(db *HistoryDB, target string, cfg Config, th Thresholds) *TailmonkeModel {
+	return &TailmonkeModel{
+		linesToDisplay: cfg.Tailmonke.LinesToDisplay,
+		lastRefresh:    time.Now(),
+		lastFileCheck:  time.Now(),
+		explicitFile:   true, // nothing to switch to
+		thresholds:     th,
+		cfg:            cfg,
+		db:             db,
+		dbTarget:       target,
 	}
 }
 
@@ -98,8 +132,12 @@ func (m *TailmonkeModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	case TickMsg:
 		// Check if file has been updated
-		info, err := os.Stat(m.filePath)
-		if err == nil && info.ModTime().After(m.lastRefresh.Add(-time.Second)) {
+		if m.db != nil {
+			if version, err := m.db.DataVersion(); err == nil && version != m.dbVersion {
+				m.dbVersion = version
+				return m, m.loadFile()
+			}
+		} else if info, err := os.Stat(m.filePath); err == nil && info.ModTime().After(m.lastRefresh.Add(-time.Second)) {
 			return m, m.loadFile()
 		}
 
@@ -146,8 +184,9 @@ func (m *TailmonkeModel) View() string {
 	headerSpace := 1  // Header row
 	notifySpace := 1  // Notification line (always present)
 	summarySpace := 1 // Summary stats line
+	statsSpace := 1   // Percentile, jitter and loss line
 	eventSpace := 1   // Event status line
-	availableHeight := m.height - headerSpace - notifySpace - summarySpace - eventSpace
+	availableHeight := m.height - headerSpace - notifySpace - summarySpace - statsSpace - eventSpace
 
 	if availableHeight < 3 {
 		return "Terminal too small"
@@ -171,7 +210,7 @@ func (m *TailmonkeModel) View() string {
 	// Apply dimming if new file available
 	visibleLines := m.lines[startIdx:]
 	for _, line := range visibleLines {
-		lineStr := line.GetColoredLine(m.columnWidths[:])
+		lineStr := line.GetColoredLine(m.columnWidths[:], m.thresholds)
 		// Dim the line if new file is available
 		if m.newFilePath != "" {
 			lineStr = fmt.Sprintf("\033[2m%s\033[0m", lineStr) // Dim effect
@@ -203,6 +242,7 @@ func (m *TailmonkeModel) View() string {
 
 	// 4. Summary statistics line
 	output += m.summaryLine + "\n"
+	output += m.statsLine + "\n"
 
 	// 5. Event status line
 	eventLine := FormatEventLine(m.eventStatus, healthColor)
@@ -218,12 +258,22 @@ func (m *TailmonkeModel) View() string {
 // loadFile reads the ping file and returns a message
 func (m *TailmonkeModel) loadFile() tea.Cmd {
 	return func() tea.Msg {
-		lines, err := ReadPingFile(m.filePath)
+		var lines []PingLine
+		var err error
+		skipped := ""
+		if m.db != nil {
+			lines, err = m.db.RecentPings(m.dbTarget, DBTailLines)
+		} else {
+			var log *pingLog
+			if log, err = readPingLog(m.filePath); err == nil {
+				lines, skipped = log.lines(), log.malformedSummary()
+			}
+		}
 		if err != nil {
 			m.lastError = err.Error()
 			return FileUpdatedMsg{lines: []PingLine{}, time: time.Now()}
 		}
-		m.lastError = ""
+		m.lastError = skipped // malformed rows are skipped but worth knowing about
 		m.updateColumnWidths(lines)
 		return FileUpdatedMsg{lines: lines, time: time.Now()}
 	}
@@ -236,19 +286,61 @@ func (m *TailmonkeModel) updateColumnWidths(lines []PingLine) {
 
 // updateSummary calculates and formats the summary line
 func (m *TailmonkeModel) updateSummary() {
-	total, ok, delayed, timeout, avg := GetSummaryStats(m.filePath)
-	m.summaryLine = FormatSummaryLine(total, ok, delayed, timeout, avg)
+	var total, ok, delayed, timeout int
+	var avg float64
+	if m.db != nil {
+		total, ok, delayed, timeout, avg = SummarizeLines(m.lines)
+	} else {
+		total, ok, delayed, timeout, avg = GetSummaryStats(m.filePath)
+	}
+	m.summaryLine = FormatSummaryLine(total, ok, delayed, timeout, avg, m.thresholds)
+	m.statsLine = FormatStatsLine(StatsForLines(m.lines, m.thresholds), m.thresholds)
 }
 
-// updateHealthState updates the health color based on event status
+// updateHealthState updates the health color based on event status. Only
+// lines added since the last reload are fed to the detector; a different or
+// rewritten file starts it over.
 func (m *TailmonkeModel) updateHealthState() {
-	m.eventStatus = DetectEvent(m.lines)
+	source := m.filePath
+	if m.db != nil {
+		source = m.dbTarget
+	}
+	from := -1
+	if m.detector != nil && m.fedFile == source {
+		from = m.unfedLine()
+	}
+	if from < 0 {
+		m.detector = m.cfg.StreamDetector(m.thresholds)
+		m.fedFile, m.lastFed = source, PingLine{}
+		from = 0
+	}
+	for _, line := range m.lines[from:] {
+		m.detector.addLine(line)
+		m.lastFed = line
+	}
+	m.eventStatus = m.detector.Status()
+}
+
+// unfedLine returns the index of the first line after the last one fed to
+// the detector, or -1 if that line is no longer there.
+func (m *TailmonkeModel) unfedLine() int {
+	for i := len(m.lines) - 1; i >= 0; i-- {
+		line := m.lines[i]
+		if line.StartTime == m.lastFed.StartTime && line.Family == m.lastFed.Family && line.Status == m.lastFed.Status {
+			return i + 1
+		}
+	}
+	return -1
 }
 
 // regenerateSummary regenerates the summary file for the current log
 func (m *TailmonkeModel) regenerateSummary() {
-	cfg := &Config{DebugMode: false}                          // Assume normal mode for summary generation
-	msg := generateSummaryWithLogging(m.filePath, *cfg, true) // capture message
+	if m.db != nil {
+		m.lastNotification = "[Summary] Events are stored when the period closes, see pingmonke query -events"
+		m.notificationTime = time.Now()
+		return
+	}
+	msg := generateSummaryWithLogging(m.filePath, m.cfg, m.thresholds, true) // capture message
 	m.lastNotification = msg
 	m.notificationTime = time.Now()
 }
@@ -267,6 +359,7 @@ func (m *TailmonkeModel) findNewerLogFile() string {
 
 	var newerFile string
 	var newerTime time.Time
+	currentTarget := LogFileTarget(m.filePath)
 
 	for _, entry := range entries {
 		if entry.IsDir() {
@@ -274,8 +367,8 @@ func (m *TailmonkeModel) findNewerLogFile() string {
 		}
 
 		name := entry.Name()
-		// Only consider main log files, not test files
-		if filepath.Ext(name) == ".csv" && len(name) > 10 && name[len(name)-10:] == "-pings.csv" {
+		// Only consider main log files of the same target, not test files
+		if IsPingLogFile(name) && LogFileTarget(name) == currentTarget {
 			info, err := entry.Info()
 			if err != nil {
 				continue
@@ -305,12 +398,21 @@ func (m *TailmonkeModel) tickCmd() tea.Cmd {
 
 // RunTailmonkeTUI starts the interactive TUI (backward compatible)
 func RunTailmonkeTUI(filePath string, linesToDisplay int) error {
-	return RunTailmonkeTUIWithOptions(filePath, linesToDisplay, false)
+	return RunTailmonkeTUIWithOptions(filePath, defaultTailConfig(linesToDisplay), false, DefaultThresholds)
+}
+
+// RunTailmonkeTUIWithOptions starts the interactive TUI with explicit file flag,
+// the loaded config and the tailed target's thresholds
+func RunTailmonkeTUIWithOptions(filePath string, cfg Config, explicitFile bool, th Thresholds) error {
+	model := NewTailmonkeModelWithOptions(filePath, cfg, explicitFile, th)
+	p := tea.NewProgram(model, tea.WithAltScreen())
+	_, err := p.Run()
+	return err
 }
 
-// RunTailmonkeTUIWithOptions starts the interactive TUI with explicit file flag
-func RunTailmonkeTUIWithOptions(filePath string, linesToDisplay int, explicitFile bool) error {
-	model := NewTailmonkeModelWithOptions(filePath, linesToDisplay, explicitFile)
+// RunTailmonkeTUIFromDB starts the interactive TUI on target in the history database
+func RunTailmonkeTUIFromDB(db *HistoryDB, target string, cfg Config, th Thresholds) error {
+	model := NewTailmonkeModelFromDB(db, target, cfg, th)
 	p := tea.NewProgram(model, tea.WithAltScreen())
 	_, err := p.Run()
 	return err
@@ -0,0 +1,123 @@
+// internal/classify.go
+// @version classify
+// @description Event classification (outage, degradation, mixed, flapping, micro-blip) and severity scores
+
+package internal
+
+import (
+	"math"
+	"time"
+)
+
+// Event classes, from the mix of bad pings in an event and its duration
+const (
+	EventOutage      = "outage"      // mostly failed pings: timeouts, unreachable, DNS errors, ...
+	EventDegradation = "degradation" // pings that were answered, but slowly; none lost
+	EventMixed       = "mixed"       // mostly slow pings, with some lost among them
+	EventFlapping    = "flapping"    // many short runs of bad pings between good ones
+	EventMicroBlip   = "micro-blip"  // over within microBlipDuration
+)
+
+const (
+	// microBlipDuration is how short an event must be to be a micro-blip
+	microBlipDuration = time.Minute
+	// flappingRuns is how many separate runs of bad pings make an event
+	// flapping, when they average no more than flappingRunLength pings
+	flappingRuns      = 3
+	flappingRunLength = 2
+	// severityFullDuration is how long an event must last to score in full
+	severityFullDuration = time.Hour
+)
+
+// eventMix counts an event's pings, from its first bad ping to its last, by
+// how they went wrong.
+type eventMix struct {
+	total   int
+	lost    int // failed outright
+	delayed int
+	severe  int
+	badRuns int // separate runs of consecutive bad pings
+}
+
+// Ways a ping can be bad; badKind returns "" for a good one
+const (
+	pingLost    = "lost"
+	pingDelayed = "delayed"
+	pingSevere  = "severe"
+)
+
+// badKind reports how a ping is bad under th, or "" if it isn't. It agrees
+// with isBadPing.
+func badKind(p PingRecord, th Thresholds) string {
+	if isFailureStatus(p.Status) {
+		return pingLost
+	}
+	switch classifyStatus(time.Duration(p.Latency)*time.Millisecond, th) {
+	case "delayed":
+		return pingDelayed
+	case "severe":
+		return pingSevere
+	}
+	return ""
+}
+
+// add counts one ping of kind; afterGood says whether the ping before it in
+// the event was good, so a bad ping starts a new run.
+func (m *eventMix) add(kind string, afterGood bool) {
+	m.total++
+	switch kind {
+	case pingLost:
+		m.lost++
+	case pingDelayed:
+		m.delayed++
+	case pingSevere:
+		m.severe++
+	default:
+		return
+	}
+	if afterGood || m.bad() == 1 {
+		m.badRuns++
+	}
+}
+
+func (m eventMix) bad() int {
+	return m.lost + m.delayed + m.severe
+}
+
+func (m eventMix) plus(o eventMix) eventMix {
+	return eventMix{
+		total:   m.total + o.total,
+		lost:    m.lost + o.lost,
+		delayed: m.delayed + o.delayed,
+		severe:  m.severe + o.severe,
+		badRuns: m.badRuns + o.badRuns,
+	}
+}
+
+// classify returns the class and severity of an event with this mix of
+// pings that has lasted duration. Severity runs from 0 to 100: the share of
+// pings lost counts in full, severe and delayed ones partly, scaled up with
+// the event's duration until it reaches severityFullDuration.
+func (m eventMix) classify(duration time.Duration) (class string, severity float64) {
+	switch {
+	case duration < microBlipDuration:
+		class = EventMicroBlip
+	case m.badRuns >= flappingRuns && m.bad() <= flappingRunLength*m.badRuns:
+		class = EventFlapping
+	case 2*m.lost >= m.bad():
+		class = EventOutage
+	case m.lost == 0:
+		class = EventDegradation
+	default:
+		class = EventMixed
+	}
+
+	if m.total == 0 {
+		return class, 0
+	}
+	impact := (float64(m.lost) + 0.6*float64(m.severe) + 0.3*float64(m.delayed)) / float64(m.total)
+	minutes := max(duration.Minutes(), 0)
+	length := math.Min(1, math.Log1p(minutes)/math.Log1p(severityFullDuration.Minutes()))
+	severity = math.Round(1000*impact*(0.5+0.5*length)) / 10
+	return class, severity
+}
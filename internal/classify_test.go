@@ -0,0 +1,122 @@
+package internal
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestEventClassify tests classes and severity scores from an event's ping mix
+func TestEventClassify(t *testing.T) {
+	tests := []struct {
+		name     string
+		mix      eventMix
+		duration time.Duration
+		class    string
+		severity float64
+	}{
+		{"Short", eventMix{total: 3, lost: 3, badRuns: 1}, 45 * time.Second, EventMicroBlip, 0},
+		{"Hour of timeouts", eventMix{total: 240, lost: 240, badRuns: 1}, time.Hour, EventOutage, 100},
+		{"Longer than an hour", eventMix{total: 480, lost: 240, badRuns: 1}, 2 * time.Hour, EventOutage, 50},
+		{"Slow for an hour", eventMix{total: 240, delayed: 240, badRuns: 1}, time.Hour, EventDegradation, 30},
+		{"Half lost", eventMix{total: 40, lost: 10, severe: 10, badRuns: 2}, time.Hour, EventOutage, 40},
+		{"Slow with some lost", eventMix{total: 40, lost: 4, delayed: 16, severe: 4, badRuns: 2}, time.Hour, EventMixed, 28},
+		{"Slow with one lost", eventMix{total: 240, lost: 1, delayed: 239, badRuns: 1}, time.Hour, EventMixed, 0},
+		{"Short runs", eventMix{total: 20, lost: 3, delayed: 3, badRuns: 4}, time.Hour, EventFlapping, 19.5},
+		{"Long runs", eventMix{total: 20, lost: 8, delayed: 8, badRuns: 3}, time.Hour, EventOutage, 52},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			class, severity := tt.mix.classify(tt.duration)
+			if class != tt.class {
+				t.Errorf("Expected %s, got %s", tt.class, class)
+			}
+			if tt.severity != 0 && severity != tt.severity {
+				t.Errorf("Expected severity %.1f, got %.1f", tt.severity, severity)
+			}
+		})
+	}
+
+	// Severity grows with duration for the same mix
+	mix := eventMix{total: 10, lost: 10, badRuns: 1}
+	_, short := mix.classify(2 * time.Minute)
+	_, long := mix.classify(20 * time.Minute)
+	if short <= 50 || short >= long || long >= 100 {
+		t.Errorf("Expected severity to grow with duration, got %.1f then %.1f", short, long)
+	}
+}
+
+// TestEventDetectorClassifies tests that detected events carry their class,
+// and that the summary, event line and live notices report it
+func TestEventDetectorClassifies(t *testing.T) {
+	base := time.Date(2026, 1, 7, 14, 0, 0, 0, time.UTC)
+	series := func(statuses ...string) []PingRecord {
+		var pings []PingRecord
+		for i, status := range statuses {
+			at := base.Add(time.Duration(i) * 15 * time.Second)
+			p := PingRecord{StartTime: at, EndTime: at, Latency: 10, Status: status}
+			if status == "delayed" {
+				p.Latency = 150
+			}
+			pings = append(pings, p)
+		}
+		return pings
+	}
+	repeat := func(n int, statuses ...string) []string {
+		var all []string
+		for range n {
+			all = append(all, statuses...)
+		}
+		return all
+	}
+	detect := func(pings []PingRecord) Event {
+		t.Helper()
+		events := NewEventDetector(DefaultEventRules, DefaultThresholds).Detect(pings)
+		if len(events) != 1 {
+			t.Fatalf("Expected one event, got %+v", events)
+		}
+		return events[0]
+	}
+
+	blip := detect(series("ok", "timeout", "timeout", "ok", "ok", "ok", "ok"))
+	if blip.Class != EventMicroBlip {
+		t.Errorf("Expected a micro-blip, got %+v", blip)
+	}
+	outage := detect(series(append(repeat(8, "timeout"), repeat(4, "ok")...)...))
+	if outage.Class != EventOutage || outage.Severity <= 50 {
+		t.Errorf("Expected an outage, got %+v", outage)
+	}
+	slow := detect(series(append(repeat(8, "delayed"), repeat(4, "ok")...)...))
+	if slow.Class != EventDegradation || slow.Severity >= outage.Severity {
+		t.Errorf("Expected a milder degradation, got %+v", slow)
+	}
+	flapping := detect(series(append(repeat(4, "timeout", "ok", "ok"), repeat(4, "ok")...)...))
+	if flapping.Class != EventFlapping {
+		t.Errorf("Expected flapping, got %+v", flapping)
+	}
+
+	// The event line shows the class of the current event
+	var lines []PingLine
+	for _, p := range series(repeat(8, "timeout")...) {
+		lines = append(lines, PingLine{StartTime: formatTimestamp(p.StartTime), EndTime: formatTimestamp(p.EndTime), Latency: p.Latency, Status: p.Status})
+	}
+	status := NewEventDetector(DefaultEventRules, DefaultThresholds).Status(lines)
+	if status.Class != EventOutage {
+		t.Errorf("Expected an active outage, got %+v", status)
+	}
+	if line := FormatEventLine(status, ColorRed); !strings.Contains(line, "outage, severity") {
+		t.Errorf("Expected the class on the event line, got %q", line)
+	}
+
+	// Live notices carry the class so far
+	target := TargetConfig{Name: "isp", Thresholds: DefaultThresholds}
+	state := NewLiveState([]TargetConfig{target})
+	var notices []EventNotice
+	state.OnEvent(func(n EventNotice) { notices = append(notices, n) })
+	for _, p := range series(append(repeat(8, "timeout"), repeat(4, "ok")...)...) {
+		recordPing(state, target, p.StartTime, "v4", p.Status, 10*time.Millisecond)
+	}
+	if len(notices) != 2 || notices[0].Class != EventMicroBlip || notices[1].Class != outage.Class || notices[1].Severity != outage.Severity {
+		t.Errorf("Expected a micro-blip start and an outage end like the summary's, got %+v", notices)
+	}
+}
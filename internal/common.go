@@ -7,22 +7,44 @@ package internal
 import (
 	"fmt"
 	"os"
+	"strings"
 	"time"
+	_ "time/tzdata" // so timezone names resolve on systems without a zoneinfo database (Windows)
 
 	"gopkg.in/yaml.v3"
 )
 
 // Config holds global settings for pingmonke.
 type Config struct {
-	Target        string          `yaml:"target"`
-	LogDir        string          `yaml:"log_dir"`
-	Interval      time.Duration   `yaml:"interval"`
-	DebugInterval time.Duration   `yaml:"debug_interval"`
-	Port          int             `yaml:"port"`
-	UseICMP       bool            `yaml:"use_icmp"`
-	Tailmonke     TailmonkeConfig `yaml:"tailmonke"`
-	Verbose       bool
-	DebugMode     bool
+	Target            string          `yaml:"target"`
+	LogDir            string          `yaml:"log_dir"`
+	LogFlushInterval  time.Duration   `yaml:"log_flush_interval"`  // how long rows may sit in the write buffer; 0 flushes every row
+	LogFsync          bool            `yaml:"log_fsync"`           // fsync the log file after each flush
+	LogFormat         string          `yaml:"log_format"`          // csv or jsonl, for the default log sink
+	Sinks             []SinkConfig    `yaml:"sinks"`               // where results go; default is CSV files in log_dir
+	KeepDays          int             `yaml:"keep_days"`           // delete period files last written longer ago than this; 0 keeps them forever
+	CompressAfterDays int             `yaml:"compress_after_days"` // gzip period files last written longer ago than this; 0 never compresses
+	SummaryHTML       bool            `yaml:"summary_html"`        // also write each summary as a self-contained HTML page
+	Interval          time.Duration   `yaml:"interval"`
+	DebugInterval     time.Duration   `yaml:"debug_interval"`
+	Port              int             `yaml:"port"`
+	UseICMP           bool            `yaml:"use_icmp"`
+	AddressFamily     string          `yaml:"address_family"`   // v4, v6 or both
+	Thresholds        Thresholds      `yaml:"thresholds"`       // default for targets without their own
+	Events            EventRules      `yaml:"events"`           // when bad pings add up to an event
+	ShutdownTimeout   time.Duration   `yaml:"shutdown_timeout"` // how long in-flight probes get to finish on SIGINT/SIGTERM
+	Timezone          string          `yaml:"timezone"`         // "local" or an IANA name such as America/Chicago
+	Location          *time.Location  `yaml:"-"`                // Timezone, resolved by SetDefaults
+	Period            string          `yaml:"period"`           // 1h, 6h, 24h, 168h, ... or a cron-like boundary spec
+	Targets           []TargetConfig  `yaml:"targets"`
+	Tailmonke         TailmonkeConfig `yaml:"tailmonke"`
+	Metrics           MetricsConfig   `yaml:"metrics"`
+	API               APIConfig       `yaml:"api"`
+	Webhooks          WebhooksConfig  `yaml:"webhooks"`
+	Verbose           bool
+	DebugMode         bool
+
+	period periodSpec // Period, parsed by SetDefaults
 }
 
 // TailmonkeConfig holds tailmonke-specific settings
@@ -30,15 +52,33 @@ type TailmonkeConfig struct {
 	LinesToDisplay int `yaml:"lines_to_display"`
 }
 
+// MetricsConfig holds the Prometheus exporter settings
+type MetricsConfig struct {
+	Listen string `yaml:"listen"` // e.g. 127.0.0.1:9273; empty disables the exporter
+}
+
 // LoadConfig reads and parses the YAML config file.
 func LoadConfig(path string) Config {
 	cfg := Config{
-		Target:        "google.com",
-		LogDir:        defaultLogDir(),
-		Interval:      15 * time.Second,
-		DebugInterval: 5 * time.Second,
-		Port:          80,
-		UseICMP:       false,
+		Target:           "google.com",
+		LogDir:           defaultLogDir(),
+		LogFlushInterval: time.Second,
+		LogFormat:        logFormatCSV,
+		Interval:         15 * time.Second,
+		DebugInterval:    5 * time.Second,
+		Port:             80,
+		UseICMP:          false,
+		AddressFamily:    "v4",
+		Thresholds:       DefaultThresholds,
+		Events:           DefaultEventRules,
+		ShutdownTimeout:  20 * time.Second,
+		Timezone:         "local",
+		Period:           "24h",
+		Webhooks: WebhooksConfig{
+			Retries:  3,
+			Backoff:  2 * time.Second,
+			Cooldown: 5 * time.Minute,
+		},
 		Tailmonke: TailmonkeConfig{
 			LinesToDisplay: 20,
 		},
@@ -67,11 +107,73 @@ func LoadConfig(path string) Config {
 	return cfg
 }
 
-// SetDefaults placeholder.
+// SetDefaults fills in and validates settings the YAML may have left empty.
 func SetDefaults(cfg *Config) {
 	if cfg.LogDir == "" {
 		cfg.LogDir = defaultLogDir()
 	}
+	cfg.Location = loadTimezone(cfg.Timezone)
+	period, err := parsePeriod(cfg.Period)
+	if err != nil {
+		fmt.Printf("[Config] %v, using daily periods\n", err)
+		period = dailyPeriod
+	}
+	cfg.period = period
+	switch cfg.LogFormat = strings.ToLower(cfg.LogFormat); cfg.LogFormat {
+	case logFormatCSV, logFormatJSONL:
+	case "":
+		cfg.LogFormat = logFormatCSV
+	default:
+		fmt.Printf("[Config] Unknown log_format %q, using csv\n", cfg.LogFormat)
+		cfg.LogFormat = logFormatCSV
+	}
+	if cfg.LogFlushInterval < 0 {
+		cfg.LogFlushInterval = 0
+	}
+	if cfg.KeepDays < 0 {
+		cfg.KeepDays = 0
+	}
+	if cfg.CompressAfterDays < 0 {
+		cfg.CompressAfterDays = 0
+	}
+	if cfg.DebugInterval < minInterval {
+		cfg.DebugInterval = minInterval
+	}
+	if cfg.ShutdownTimeout <= 0 {
+		cfg.ShutdownTimeout = 20 * time.Second
+	}
+	if cfg.Webhooks.Retries < 0 {
+		cfg.Webhooks.Retries = 0
+	}
+	if cfg.Webhooks.Backoff <= 0 {
+		cfg.Webhooks.Backoff = 2 * time.Second
+	}
+	cfg.Thresholds = validThresholds(cfg.Thresholds, "defaults")
+	cfg.Events = validEventRules(cfg.Events)
+	normalizeTargets(cfg)
+	normalizeSinks(cfg)
+}
+
+// loadTimezone resolves the timezone setting, falling back to the system
+// zone when it is empty or unknown.
+func loadTimezone(name string) *time.Location {
+	if name == "" || strings.EqualFold(name, "local") {
+		return time.Local
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		fmt.Printf("[Config] Unknown timezone %q (%v), using local time\n", name, err)
+		return time.Local
+	}
+	return loc
+}
+
+// location is the zone periods, file names and timestamps are expressed in.
+func (cfg Config) location() *time.Location {
+	if cfg.Location == nil {
+		return time.Local
+	}
+	return cfg.Location
 }
 
 // PrepareLogDirectory ensures the log directory exists.
@@ -0,0 +1,296 @@
+// internal/detector.go
+// @version detector
+// @description One configurable event detector shared by summaries, tailmonke and the live state
+
+package internal
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// EventRules are the tunable rules that decide when an event starts and ends.
+// An event starts once BadCount of the last Window pings are bad, with the
+// first of them no more than Within before the latest. It ends after
+// RecoverPings consecutive good pings spanning at least RecoverTime.
+type EventRules struct {
+	Window       int           `yaml:"window"`        // how many recent pings are checked for bad ones
+	BadCount     int           `yaml:"bad_count"`     // bad pings in the window that start an event
+	Within       time.Duration `yaml:"within"`        // how close together those bad pings must be; 0 for no limit
+	RecoverPings int           `yaml:"recover_pings"` // consecutive good pings that end an event
+	RecoverTime  time.Duration `yaml:"recover_time"`  // how long those good pings must span; 0 for no minimum
+}
+
+// DefaultEventRules start an event at 2 bad pings out of the last 4 within a
+// minute, and end it after 4 good pings in a row.
+var DefaultEventRules = EventRules{Window: 4, BadCount: 2, Within: time.Minute, RecoverPings: 4}
+
+// validEventRules fills in missing counts from the defaults and clamps
+// settings that could never be met.
+func validEventRules(r EventRules) EventRules {
+	if r == (EventRules{}) {
+		return DefaultEventRules
+	}
+	if r.Window <= 0 {
+		r.Window = DefaultEventRules.Window
+	}
+	if r.BadCount <= 0 {
+		r.BadCount = DefaultEventRules.BadCount
+	}
+	if r.BadCount > r.Window {
+		fmt.Printf("[Config] events bad_count %d is larger than window %d, using %d\n", r.BadCount, r.Window, r.Window)
+		r.BadCount = r.Window
+	}
+	if r.RecoverPings <= 0 {
+		r.RecoverPings = DefaultEventRules.RecoverPings
+	}
+	r.Within = max(r.Within, 0)
+	r.RecoverTime = max(r.RecoverTime, 0)
+	return r
+}
+
+// EventDetector finds events in a series of pings, judging each ping against
+// the target's thresholds. Summaries, tailmonke, the API and the live state
+// all detect events through it, so they agree on the same pings.
+type EventDetector struct {
+	rules EventRules
+	th    Thresholds
+}
+
+// NewEventDetector creates a detector applying rules to pings judged by th.
+func NewEventDetector(rules EventRules, th Thresholds) EventDetector {
+	return EventDetector{rules: validEventRules(rules), th: th}
+}
+
+// EventDetector returns the configured detector for a target with thresholds th.
+func (cfg Config) EventDetector(th Thresholds) EventDetector {
+	return NewEventDetector(cfg.Events, th)
+}
+
+// Detect returns the events in pings, oldest first. The last one is Ongoing
+// if the pings end before it recovered. Dual-stack logs are split by address
+// family first, so an IPv6-only outage is reported separately from one that
+// hits both families.
+func (d EventDetector) Detect(pings []PingRecord) []Event {
+	if families := pingFamilies(pings); len(families) > 1 {
+		return d.detectDualStack(pings, families)
+	}
+	return d.detectSeries(pings)
+}
+
+// Status returns the state of the most recent event in tailed lines, for
+// tailmonke's event line and the live state.
+func (d EventDetector) Status(lines []PingLine) EventStatus {
+	pings := lineRecords(lines)
+	if len(pings) == 0 {
+		return EventStatus{}
+	}
+	status := EventStatus{LatestPingTime: pings[len(pings)-1].StartTime}
+	events := d.Detect(pings)
+	if len(events) == 0 {
+		return status
+	}
+
+	e := events[len(events)-1]
+	status.Class, status.Severity = e.Class, e.Severity
+	status.StartTime = e.StartTime
+	status.LastBadPing = pings[e.EndIndex].StartTime
+	if e.Ongoing {
+		status.IsActive = true
+		status.Duration = status.LatestPingTime.Sub(e.StartTime)
+	} else {
+		status.EndTime = e.EndTime
+		status.Duration = e.EndTime.Sub(e.StartTime)
+	}
+	return status
+}
+
+// detectSeries runs the rules over a single series of pings.
+func (d EventDetector) detectSeries(pings []PingRecord) []Event {
+	var events []Event
+	t := seriesTracker{rules: d.rules, th: d.th}
+	for i, p := range pings {
+		if _, ended := t.add(i, p); ended {
+			events = append(events, t.event.classified())
+		}
+	}
+	if t.active {
+		e := t.event
+		e.Ongoing = true
+		e.EndTime = pings[len(pings)-1].StartTime
+		events = append(events, e.classified())
+	}
+	return events
+}
+
+// seriesTracker applies the rules to one series, a ping at a time.
+type seriesTracker struct {
+	rules     EventRules
+	th        Thresholds
+	recent    []trackedPing // at most Window, oldest first
+	active    bool
+	event     Event     // the current, or last ended, event
+	lastBad   time.Time // when the latest bad ping of the event was sent
+	good      int       // consecutive good pings since the last bad one of the event
+	goodStart time.Time // when that run of good pings began
+	goodIndex int
+}
+
+// trackedPing is what the tracker remembers of a recent ping.
+type trackedPing struct {
+	index int
+	start time.Time
+	kind  string // badKind, "" if good
+}
+
+// add feeds the ping at index i of the series, reporting whether it started
+// or ended an event.
+func (t *seriesTracker) add(i int, p PingRecord) (started, ended bool) {
+	kind := badKind(p, t.th)
+	bad := kind != ""
+	if len(t.recent) == t.rules.Window {
+		t.recent = append(t.recent[:0], t.recent[1:]...)
+	}
+	t.recent = append(t.recent, trackedPing{index: i, start: p.StartTime, kind: kind})
+
+	if t.active {
+		if bad {
+			// The good pings since the last bad one turned out to be part of the event
+			for range t.good {
+				t.event.mix.add("", false)
+			}
+			t.event.mix.add(kind, t.good > 0)
+			t.event.EndIndex = i
+			t.lastBad = p.StartTime
+			t.good = 0
+			return false, false
+		}
+		if t.good == 0 {
+			t.goodStart, t.goodIndex = p.StartTime, i
+		}
+		t.good++
+		if t.good < t.rules.RecoverPings || p.StartTime.Sub(t.goodStart) < t.rules.RecoverTime {
+			return false, false
+		}
+		// The event ended when the pings came good again; bad pings before
+		// that no longer count toward the next one
+		t.active = false
+		t.event.EndTime = t.goodStart
+		for len(t.recent) > 0 && t.recent[0].index < t.goodIndex {
+			t.recent = t.recent[1:]
+		}
+		return false, true
+	}
+
+	if !bad {
+		return false, false
+	}
+	// The oldest bad ping close enough to this one starts the event, if
+	// there are enough bad pings from there on
+	for j, r := range t.recent {
+		if r.kind == "" || (t.rules.Within > 0 && p.StartTime.Sub(r.start) > t.rules.Within) {
+			continue
+		}
+		var mix eventMix
+		for k, later := range t.recent[j:] {
+			mix.add(later.kind, k > 0 && t.recent[j+k-1].kind == "")
+		}
+		if mix.bad() >= t.rules.BadCount {
+			t.active = true
+			t.lastBad = p.StartTime
+			t.good = 0
+			t.event = Event{StartIndex: r.index, EndIndex: i, StartTime: r.start, mix: mix}
+			return true, false
+		}
+		break
+	}
+	return false, false
+}
+
+// pingFamilies returns the distinct address families present, in first-seen order
+func pingFamilies(pings []PingRecord) []string {
+	var families []string
+	seen := map[string]bool{}
+	for _, p := range pings {
+		if !seen[p.Family] {
+			seen[p.Family] = true
+			families = append(families, p.Family)
+		}
+	}
+	return families
+}
+
+// detectDualStack detects events per address family, then merges events that
+// overlap in time. A merged event covering every family is a full outage;
+// one seen on a single family is reported as that family only.
+func (d EventDetector) detectDualStack(pings []PingRecord, families []string) []Event {
+	var events []Event
+	for _, family := range families {
+		var series []PingRecord
+		var indices []int // position of each series ping in pings
+		for i, p := range pings {
+			if p.Family == family {
+				series = append(series, p)
+				indices = append(indices, i)
+			}
+		}
+		for _, e := range d.detectSeries(series) {
+			e.StartIndex = indices[e.StartIndex]
+			e.EndIndex = indices[e.EndIndex]
+			e.Family = family
+			events = append(events, e)
+		}
+	}
+
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].StartTime.Before(events[j].StartTime)
+	})
+
+	var merged []Event
+	affected := map[string]bool{}
+	for _, e := range events {
+		if n := len(merged); n > 0 && !e.StartTime.After(merged[n-1].EndTime) {
+			last := &merged[n-1]
+			if e.EndTime.After(last.EndTime) {
+				last.EndTime = e.EndTime
+			}
+			if e.EndIndex > last.EndIndex {
+				last.EndIndex = e.EndIndex
+			}
+			last.Ongoing = last.Ongoing || e.Ongoing
+			last.mix = last.mix.plus(e.mix)
+			*last = last.classified()
+			affected[e.Family] = true
+			if len(affected) == len(families) {
+				last.Family = "both"
+			}
+			continue
+		}
+		merged = append(merged, e)
+		affected = map[string]bool{e.Family: true}
+	}
+
+	return merged
+}
+
+// lineRecords converts tailed lines to records, skipping any whose start
+// time doesn't parse.
+func lineRecords(lines []PingLine) []PingRecord {
+	pings := make([]PingRecord, 0, len(lines))
+	for _, line := range lines {
+		start, err := parsePingTime(line.StartTime)
+		if err != nil {
+			continue
+		}
+		end, _ := parsePingTime(line.EndTime)
+		pings = append(pings, PingRecord{StartTime: start, EndTime: end, Latency: line.Latency, Status: line.Status, Family: line.Family})
+	}
+	return pings
+}
+
+// classified returns e with its class and severity set from its pings.
+func (e Event) classified() Event {
+	e.Class, e.Severity = e.mix.classify(e.EndTime.Sub(e.StartTime))
+	return e
+}
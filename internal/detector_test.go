@@ -0,0 +1,232 @@
+package internal
+
+import (
+	"encoding/csv"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// TestEventRules tests tuning the window, bad count and recovery
+func TestEventRules(t *testing.T) {
+	base := time.Date(2026, 1, 7, 14, 0, 0, 0, time.UTC)
+	var pings []PingRecord
+	for i, status := range []string{"ok", "timeout", "ok", "timeout", "timeout", "ok", "ok", "timeout", "ok", "ok", "ok", "ok"} {
+		at := base.Add(time.Duration(i) * 15 * time.Second)
+		pings = append(pings, PingRecord{StartTime: at, EndTime: at, Status: status, Latency: 10})
+	}
+
+	tests := []struct {
+		name  string
+		rules EventRules
+		want  [][2]int // start and end index of each event
+		ended []bool
+	}{
+		{"Defaults", DefaultEventRules, [][2]int{{1, 7}}, []bool{true}},
+		{"Three in a row", EventRules{Window: 3, BadCount: 3, RecoverPings: 2}, nil, nil},
+		{"Three in five", EventRules{Window: 5, BadCount: 3, RecoverPings: 2}, [][2]int{{1, 4}}, []bool{true}},
+		{"Quick recovery", EventRules{Window: 2, BadCount: 2, RecoverPings: 2}, [][2]int{{3, 4}}, []bool{true}},
+		{"Close together", EventRules{Window: 4, BadCount: 2, Within: 20 * time.Second, RecoverPings: 4}, [][2]int{{3, 7}}, []bool{true}},
+		{"Slow recovery", EventRules{Window: 4, BadCount: 2, RecoverPings: 2, RecoverTime: time.Minute}, [][2]int{{1, 7}}, []bool{false}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events := NewEventDetector(tt.rules, DefaultThresholds).Detect(pings)
+			if len(events) != len(tt.want) {
+				t.Fatalf("Expected %d events, got %+v", len(tt.want), events)
+			}
+			for i, e := range events {
+				if e.StartIndex != tt.want[i][0] || e.EndIndex != tt.want[i][1] || e.Ongoing == tt.ended[i] {
+					t.Errorf("Event %d: expected pings %v (ended %v), got %+v", i, tt.want[i], tt.ended[i], e)
+				}
+				if e.Ongoing && !e.EndTime.Equal(pings[len(pings)-1].StartTime) {
+					t.Errorf("Event %d: expected an ongoing event to run to the latest ping, got %v", i, e.EndTime)
+				}
+			}
+		})
+	}
+
+	// Unset counts fall back to the defaults, and bad_count can't exceed the window
+	if r := validEventRules(EventRules{Window: 3, BadCount: 5}); r.BadCount != 3 || r.RecoverPings != DefaultEventRules.RecoverPings {
+		t.Errorf("Unexpected rules %+v", r)
+	}
+	if r := validEventRules(EventRules{}); r != DefaultEventRules {
+		t.Errorf("Expected the default rules for an empty events section, got %+v", r)
+	}
+}
+
+// TestEventDetectorPathsAgree tests that the summary, tailmonke and the live
+// state report the same events for the same pings
+func TestEventDetectorPathsAgree(t *testing.T) {
+	rules := []EventRules{
+		DefaultEventRules,
+		{Window: 6, BadCount: 3, Within: 45 * time.Second, RecoverPings: 3, RecoverTime: 30 * time.Second},
+		{Window: 2, BadCount: 2, RecoverPings: 1},
+	}
+	for _, families := range [][]string{{"v4"}, {"v4", "v6"}} {
+		for n, r := range rules {
+			pings := randomPings(int64(n+1), families, 400)
+			cfg := Config{Events: r}
+			th := Thresholds{OK: 30 * time.Millisecond, Degraded: 80 * time.Millisecond}
+
+			summary := summaryEvents(t, cfg, th, pings)
+			if len(summary) == 0 {
+				t.Fatalf("%v %+v: expected some events to compare", families, r)
+			}
+			if tail := tailEvents(cfg, th, pings); !sameEvents(summary, tail) {
+				t.Errorf("%v %+v: summary events\n%v\ndiffer from tailmonke's\n%v", families, r, summary, tail)
+			}
+			// Live notices go out as soon as an event is seen, before a
+			// dual-stack event's start can move earlier, so compare one family
+			if len(families) == 1 {
+				if live := liveEvents(r, th, pings); !sameEvents(summary, live) {
+					t.Errorf("%+v: summary events\n%v\ndiffer from the live state's\n%v", r, summary, live)
+				}
+			}
+		}
+	}
+}
+
+// randomPings builds a reproducible series with bursts of bad pings, one
+// ping per family every 15 seconds.
+func randomPings(seed int64, families []string, ticks int) []PingRecord {
+	rng := rand.New(rand.NewSource(seed))
+	base := time.Date(2026, 1, 7, 0, 0, 0, 0, time.UTC)
+	var pings []PingRecord
+	badness := 0.0
+	for i := 0; i < ticks; i++ {
+		// Drift in and out of trouble
+		if rng.Float64() < 0.08 {
+			badness = rng.Float64() * 0.9
+		} else if rng.Float64() < 0.1 {
+			badness = 0
+		}
+		at := base.Add(time.Duration(i) * 15 * time.Second)
+		for _, family := range families {
+			p := PingRecord{StartTime: at, EndTime: at.Add(20 * time.Millisecond), Latency: 20, Status: "ok", Family: family}
+			if rng.Float64() < badness {
+				if rng.Intn(2) == 0 {
+					p.Status, p.Latency, p.EndTime = "timeout", 0, at.Add(time.Second)
+				} else {
+					p.Status, p.Latency = "delayed", 150
+				}
+			}
+			pings = append(pings, p)
+		}
+	}
+	return pings
+}
+
+// detectedEvent is an event as every path can report it.
+type detectedEvent struct {
+	start, end, class string
+}
+
+func sameEvents(a, b []detectedEvent) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// summaryEvents writes pings to a log, summarizes it and reads the events back
+func summaryEvents(t *testing.T, cfg Config, th Thresholds, pings []PingRecord) []detectedEvent {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "2026-01-07-isp-pings.csv")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := csv.NewWriter(f)
+	w.Write(pingCSVHeader)
+	for _, p := range pings {
+		w.Write([]string{formatTimestamp(p.StartTime), formatTimestamp(p.EndTime), strconv.FormatInt(p.Latency, 10), p.Status, p.Family})
+	}
+	w.Flush()
+	f.Close()
+
+	generateSummary(path, cfg, th)
+	sf, err := os.Open(filepath.Join(filepath.Dir(path), "2026-01-07-isp-pings-summary.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sf.Close()
+	reader := csv.NewReader(sf)
+	reader.FieldsPerRecord = -1
+	rows, err := reader.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var events []detectedEvent
+	for _, row := range rows {
+		switch row[0] {
+		case "Start":
+			events = append(events, detectedEvent{start: row[1]})
+		case "End":
+			events[len(events)-1].end = row[1]
+		case "Class":
+			events[len(events)-1].class = row[1]
+		}
+	}
+	return events
+}
+
+// tailEvents replays the log as tailmonke sees it grow, one ping at a time,
+// noting each event's start and where it last ended or reached.
+func tailEvents(cfg Config, th Thresholds, pings []PingRecord) []detectedEvent {
+	var lines []PingLine
+	var events []detectedEvent
+	for _, p := range pings {
+		row := pingRow(ProbeResult{StartTime: p.StartTime, EndTime: p.EndTime, Latency: time.Duration(p.Latency) * time.Millisecond, Status: p.Status, Family: p.Family})
+		lines = append(lines, PingLine{StartTime: row[0], EndTime: row[1], Latency: p.Latency, Status: p.Status, Family: p.Family})
+
+		status := cfg.EventDetector(th).Status(lines)
+		if status.StartTime.IsZero() {
+			continue
+		}
+		end := status.EndTime
+		if status.IsActive {
+			end = status.LatestPingTime
+		}
+		// A dual-stack event can gain an earlier start once the other
+		// family's pings add up to an event too
+		e := detectedEvent{formatTimestamp(status.StartTime), formatTimestamp(end), status.Class}
+		if n := len(events); n > 0 && e.start <= events[n-1].end {
+			events[n-1] = e
+		} else {
+			events = append(events, e)
+		}
+	}
+	return events
+}
+
+// liveEvents records pings in a LiveState and collects its notices
+func liveEvents(rules EventRules, th Thresholds, pings []PingRecord) []detectedEvent {
+	target := TargetConfig{Name: "isp", Thresholds: th}
+	state := NewLiveStateWithRules([]TargetConfig{target}, rules)
+	var events []detectedEvent
+	state.OnEvent(func(n EventNotice) {
+		if n.Kind == "start" {
+			events = append(events, detectedEvent{start: formatTimestamp(n.StartTime)})
+		} else {
+			events[len(events)-1].end = formatTimestamp(n.EndTime)
+			events[len(events)-1].class = n.Class
+		}
+	})
+	for _, p := range pings {
+		state.Record(target, ProbeResult{StartTime: p.StartTime, EndTime: p.EndTime, Latency: time.Duration(p.Latency) * time.Millisecond, Status: p.Status, Family: p.Family})
+	}
+	if n := len(events); n > 0 && events[n-1].end == "" {
+		events[n-1].end = formatTimestamp(pings[len(pings)-1].StartTime)
+		events[n-1].class = state.snapshot()[0].event.Class
+	}
+	return events
+}
@@ -0,0 +1,239 @@
+// internal/dnsprobe.go
+// @version dns-probe
+// @description DNS resolution probe against the system or an explicit resolver, with rcode classification
+
+package internal
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// DNS probe failure reasons, mapped to their own statuses by statusForError.
+var (
+	ErrDNSNXDomain = errors.New("dns: name does not exist (NXDOMAIN)")
+	ErrDNSServFail = errors.New("dns: server failure (SERVFAIL)")
+	ErrDNSRefused  = errors.New("dns: query refused (REFUSED)")
+	ErrDNSRcode    = errors.New("dns: error response")
+	ErrDNSNoAnswer = errors.New("dns: no records of the requested type")
+)
+
+// resolvConfPath is where the system resolver is looked up on Unix systems.
+var resolvConfPath = "/etc/resolv.conf"
+
+// DNSProbeConfig holds the settings for targets with probe: dns.
+type DNSProbeConfig struct {
+	Resolver string `yaml:"resolver"` // "system" or an explicit "ip:53"
+	Name     string `yaml:"name"`     // name to resolve, defaults to the target host
+	Type     string `yaml:"type"`     // A, AAAA, CNAME, MX, NS, TXT, SOA, PTR or SRV
+}
+
+// DNSResult is the outcome of a single DNS query.
+type DNSResult struct {
+	Latency time.Duration
+	Rcode   string // NOERROR, NXDOMAIN, SERVFAIL, ... or "" when no response arrived
+	Answers int
+	Server  string // resolver address actually queried
+}
+
+var dnsTypes = map[string]dnsmessage.Type{
+	"A":     dnsmessage.TypeA,
+	"AAAA":  dnsmessage.TypeAAAA,
+	"CNAME": dnsmessage.TypeCNAME,
+	"MX":    dnsmessage.TypeMX,
+	"NS":    dnsmessage.TypeNS,
+	"TXT":   dnsmessage.TypeTXT,
+	"SOA":   dnsmessage.TypeSOA,
+	"PTR":   dnsmessage.TypePTR,
+	"SRV":   dnsmessage.TypeSRV,
+}
+
+// DnsPing sends one query for probe.Name to the configured resolver and
+// measures how long the answer took. Error rcodes and empty answers are
+// returned as ErrDNS* errors alongside the result.
+func DnsPing(probe DNSProbeConfig, timeout time.Duration) (DNSResult, error) {
+	server := probe.Resolver
+	if server == "" || server == "system" {
+		server = systemNameserver()
+		if server == "" {
+			return lookupWithGoResolver(probe, timeout)
+		}
+	}
+	result := DNSResult{Server: server}
+
+	qtype, ok := dnsTypes[strings.ToUpper(probe.Type)]
+	if !ok {
+		return result, fmt.Errorf("dns: unsupported record type %q", probe.Type)
+	}
+	name, err := dnsmessage.NewName(dnsFQDN(probe.Name))
+	if err != nil {
+		return result, err
+	}
+
+	id := uint16(rand.Uint32())
+	builder := dnsmessage.NewBuilder(nil, dnsmessage.Header{ID: id, RecursionDesired: true})
+	builder.EnableCompression()
+	builder.StartQuestions()
+	builder.Question(dnsmessage.Question{Name: name, Type: qtype, Class: dnsmessage.ClassINET})
+	query, err := builder.Finish()
+	if err != nil {
+		return result, err
+	}
+
+	start := time.Now()
+	conn, err := net.DialTimeout("udp", server, timeout)
+	if err != nil {
+		return result, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(start.Add(timeout))
+
+	if _, err := conn.Write(query); err != nil {
+		return result, err
+	}
+
+	buf := make([]byte, 4096)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return result, err
+		}
+
+		var parser dnsmessage.Parser
+		header, err := parser.Start(buf[:n])
+		if err != nil || header.ID != id || !header.Response {
+			continue // stray or malformed datagram, keep waiting
+		}
+		result.Latency = time.Since(start)
+		result.Rcode = rcodeName(header.RCode)
+
+		if err := parser.SkipAllQuestions(); err != nil {
+			return result, err
+		}
+		answers, err := parser.AllAnswers()
+		if err != nil && !header.Truncated {
+			return result, err
+		}
+		for _, answer := range answers {
+			if answer.Header.Type == qtype {
+				result.Answers++
+			}
+		}
+
+		return result, rcodeError(header.RCode, result.Answers)
+	}
+}
+
+// rcodeError converts the response code into one of the ErrDNS* reasons.
+func rcodeError(rcode dnsmessage.RCode, answers int) error {
+	switch rcode {
+	case dnsmessage.RCodeSuccess:
+		if answers == 0 {
+			return ErrDNSNoAnswer
+		}
+		return nil
+	case dnsmessage.RCodeNameError:
+		return ErrDNSNXDomain
+	case dnsmessage.RCodeServerFailure:
+		return ErrDNSServFail
+	case dnsmessage.RCodeRefused:
+		return ErrDNSRefused
+	}
+	return fmt.Errorf("%w: %s", ErrDNSRcode, rcodeName(rcode))
+}
+
+// rcodeName returns the conventional upper-case name of a response code.
+func rcodeName(rcode dnsmessage.RCode) string {
+	switch rcode {
+	case dnsmessage.RCodeSuccess:
+		return "NOERROR"
+	case dnsmessage.RCodeFormatError:
+		return "FORMERR"
+	case dnsmessage.RCodeServerFailure:
+		return "SERVFAIL"
+	case dnsmessage.RCodeNameError:
+		return "NXDOMAIN"
+	case dnsmessage.RCodeNotImplemented:
+		return "NOTIMP"
+	case dnsmessage.RCodeRefused:
+		return "REFUSED"
+	}
+	return fmt.Sprintf("RCODE%d", rcode)
+}
+
+// dnsFQDN makes sure the name ends in a dot, as dnsmessage requires.
+func dnsFQDN(name string) string {
+	if strings.HasSuffix(name, ".") {
+		return name
+	}
+	return name + "."
+}
+
+// systemNameserver returns the first nameserver in resolv.conf as "ip:53",
+// or "" where there is no resolv.conf (Windows).
+func systemNameserver() string {
+	f, err := os.Open(resolvConfPath)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "nameserver" {
+			return net.JoinHostPort(fields[1], "53")
+		}
+	}
+	return ""
+}
+
+// lookupWithGoResolver is the fallback for systems without resolv.conf. The
+// Go resolver doesn't expose response codes, so they are inferred from the
+// error it returns, and only address records can be queried.
+func lookupWithGoResolver(probe DNSProbeConfig, timeout time.Duration) (DNSResult, error) {
+	result := DNSResult{Server: "system"}
+
+	network := "ip4"
+	switch strings.ToUpper(probe.Type) {
+	case "A":
+	case "AAAA":
+		network = "ip6"
+	default:
+		return result, fmt.Errorf("dns: record type %q needs an explicit resolver on this system", probe.Type)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	start := time.Now()
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, network, probe.Name)
+	result.Latency = time.Since(start)
+
+	var dnsErr *net.DNSError
+	switch {
+	case err == nil:
+		result.Rcode = "NOERROR"
+		result.Answers = len(addrs)
+		return result, nil
+	case errors.As(err, &dnsErr) && dnsErr.IsNotFound:
+		result.Rcode = "NXDOMAIN"
+		return result, ErrDNSNXDomain
+	case errors.As(err, &dnsErr) && dnsErr.IsTimeout:
+		result.Latency = 0
+		return result, err
+	case errors.As(err, &dnsErr):
+		result.Rcode = "SERVFAIL"
+		return result, fmt.Errorf("%w: %v", ErrDNSServFail, err)
+	}
+	return result, err
+}
@@ -0,0 +1,166 @@
+package internal
+
+import (
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// startFakeResolver answers queries on 127.0.0.1 with an rcode chosen by the
+// queried name, and never answers "silent.test."
+func startFakeResolver(t *testing.T) string {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Could not start fake resolver: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			var parser dnsmessage.Parser
+			header, err := parser.Start(buf[:n])
+			if err != nil {
+				continue
+			}
+			question, err := parser.Question()
+			if err != nil {
+				continue
+			}
+
+			reply := dnsmessage.Header{ID: header.ID, Response: true, RecursionAvailable: true}
+			withAnswer := false
+			switch question.Name.String() {
+			case "ok.test.":
+				withAnswer = true
+			case "missing.test.":
+				reply.RCode = dnsmessage.RCodeNameError
+			case "broken.test.":
+				reply.RCode = dnsmessage.RCodeServerFailure
+			case "denied.test.":
+				reply.RCode = dnsmessage.RCodeRefused
+			case "silent.test.":
+				continue
+			}
+
+			builder := dnsmessage.NewBuilder(nil, reply)
+			builder.StartQuestions()
+			builder.Question(question)
+			if withAnswer {
+				builder.StartAnswers()
+				builder.AResource(
+					dnsmessage.ResourceHeader{Name: question.Name, Class: dnsmessage.ClassINET, TTL: 60},
+					dnsmessage.AResource{A: [4]byte{192, 0, 2, 10}},
+				)
+			}
+			msg, err := builder.Finish()
+			if err != nil {
+				continue
+			}
+			conn.WriteTo(msg, addr)
+		}
+	}()
+
+	return conn.LocalAddr().String()
+}
+
+// TestDnsPingRcodes tests classification of resolver responses
+func TestDnsPingRcodes(t *testing.T) {
+	resolver := startFakeResolver(t)
+
+	tests := []struct {
+		name       string
+		query      string
+		wantErr    error
+		wantRcode  string
+		wantStatus string
+	}{
+		{name: "Answer", query: "ok.test", wantRcode: "NOERROR"},
+		{name: "NXDOMAIN", query: "missing.test", wantErr: ErrDNSNXDomain, wantRcode: "NXDOMAIN", wantStatus: "nxdomain"},
+		{name: "SERVFAIL", query: "broken.test", wantErr: ErrDNSServFail, wantRcode: "SERVFAIL", wantStatus: "servfail"},
+		{name: "REFUSED", query: "denied.test", wantErr: ErrDNSRefused, wantRcode: "REFUSED", wantStatus: "refused"},
+		{name: "Empty answer", query: "empty.test", wantErr: ErrDNSNoAnswer, wantRcode: "NOERROR", wantStatus: "no_answer"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := DnsPing(DNSProbeConfig{Resolver: resolver, Name: tt.query, Type: "A"}, 2*time.Second)
+			if tt.wantErr == nil && err != nil {
+				t.Fatalf("Expected success, got %v", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Expected %v, got %v", tt.wantErr, err)
+			}
+			if result.Rcode != tt.wantRcode {
+				t.Errorf("Expected rcode %s, got %s", tt.wantRcode, result.Rcode)
+			}
+			if result.Latency <= 0 {
+				t.Errorf("Expected a measured latency, got %v", result.Latency)
+			}
+			if tt.wantStatus != "" {
+				if status := statusForError(err); status != tt.wantStatus {
+					t.Errorf("Expected status %q, got %q", tt.wantStatus, status)
+				}
+			}
+		})
+	}
+}
+
+// TestDnsPingTimeout tests that an unresponsive resolver is a timeout, not an rcode
+func TestDnsPingTimeout(t *testing.T) {
+	resolver := startFakeResolver(t)
+
+	result, err := DnsPing(DNSProbeConfig{Resolver: resolver, Name: "silent.test", Type: "A"}, 200*time.Millisecond)
+	if err == nil {
+		t.Fatal("Expected an error from an unresponsive resolver")
+	}
+	if status := statusForError(err); status != "timeout" {
+		t.Errorf("Expected status timeout, got %q", status)
+	}
+	if result.Rcode != "" {
+		t.Errorf("Expected no rcode without a response, got %q", result.Rcode)
+	}
+}
+
+// TestSystemNameserver tests reading the first nameserver from resolv.conf
+func TestSystemNameserver(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "resolv.conf")
+	os.WriteFile(path, []byte("# generated\nsearch lan\nnameserver 2001:db8::53\nnameserver 192.0.2.53\n"), 0644)
+
+	saved := resolvConfPath
+	resolvConfPath = path
+	defer func() { resolvConfPath = saved }()
+
+	if got := systemNameserver(); got != "[2001:db8::53]:53" {
+		t.Errorf("Expected [2001:db8::53]:53, got %q", got)
+	}
+}
+
+// TestDNSFailuresAreBadPings tests that DNS failure statuses drive event detection
+func TestDNSFailuresAreBadPings(t *testing.T) {
+	baseTime := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	pings := []PingRecord{
+		{StartTime: baseTime, Status: "ok", Latency: 12},
+		{StartTime: baseTime.Add(5 * time.Second), Status: "servfail", Latency: 3},
+		{StartTime: baseTime.Add(10 * time.Second), Status: "nxdomain", Latency: 4},
+		{StartTime: baseTime.Add(15 * time.Second), Status: "ok", Latency: 12},
+	}
+
+	events := NewEventDetector(DefaultEventRules, DefaultThresholds).Detect(pings)
+	if len(events) != 1 {
+		t.Fatalf("Expected 1 event, got %d", len(events))
+	}
+	if got := eventStatusBreakdown(pings, DefaultThresholds); got != "nxdomain=1 servfail=1" {
+		t.Errorf("Expected breakdown 'nxdomain=1 servfail=1', got %q", got)
+	}
+}
@@ -320,14 +320,14 @@ func TestDetectEvent_TwoPings_BothBad(t *testing.T) {
 
 	event := DetectEvent(lines)
 
-	// With backward-walking, we find bad pings even in small datasets
-	// But since we need 4 pings to create a window, nothing is found in the backward loop
-	// So we should have no event detected
-	if event.IsActive {
-		t.Error("Expected no active event with only 2 pings (can't form 4-ping window), got active")
+	// The window doesn't have to fill up first, so a fresh file can start
+	// with an event, the same as its summary reports
+	if !event.IsActive {
+		t.Error("Expected active event with 2 bad pings, got inactive")
 	}
-	if !event.StartTime.IsZero() {
-		t.Error("Expected zero StartTime, got:", event.StartTime)
+	startTime, _ := time.Parse("2006-01-02 15:04:05.000", "2026-01-08 16:00:00.000")
+	if event.StartTime != startTime {
+		t.Errorf("Expected StartTime %v, got %v", startTime, event.StartTime)
 	}
 }
 
@@ -340,13 +340,12 @@ func TestDetectEvent_ThreePings_TwoBad(t *testing.T) {
 
 	event := DetectEvent(lines)
 
-	// With backward-walking, we need 4 pings to form a window (i >= 3)
-	// So nothing is found in the backward loop
-	if event.IsActive {
-		t.Error("Expected no active event with only 3 pings (can't form 4-ping window), got active")
+	// One good ping is not enough to end it
+	if !event.IsActive {
+		t.Error("Expected active event with 2 bad pings in 3, got inactive")
 	}
-	if !event.StartTime.IsZero() {
-		t.Error("Expected zero StartTime, got:", event.StartTime)
+	if event.Duration != 30*time.Second {
+		t.Errorf("Expected duration 30s, got %v", event.Duration)
 	}
 }
 
@@ -410,3 +409,49 @@ func TestDetectEvent_FourPings_AllBad(t *testing.T) {
 		t.Errorf("Expected StartTime %v, got %v", startTime, event.StartTime)
 	}
 }
+
+func TestDetectEventWithThresholds_TightTiers(t *testing.T) {
+	// 60ms pings are fine by default but delayed for a LAN target with tight tiers
+	lines := []PingLine{
+		makePing("2026-01-08 16:00:00.000", "ok", 2),
+		makePing("2026-01-08 16:00:15.000", "delayed", 60),
+		makePing("2026-01-08 16:00:30.000", "degraded", 8),
+		makePing("2026-01-08 16:00:45.000", "delayed", 70),
+	}
+	lan := Thresholds{OK: 5 * time.Millisecond, Degraded: 20 * time.Millisecond, Delayed: 100 * time.Millisecond}
+
+	if event := DetectEvent(lines); event.IsActive {
+		t.Error("Expected no active event under default thresholds")
+	}
+
+	event := DetectEventWithThresholds(lines, lan)
+	if !event.IsActive {
+		t.Fatal("Expected active event under LAN thresholds")
+	}
+	startTime, _ := time.Parse("2006-01-02 15:04:05.000", "2026-01-08 16:00:15.000")
+	if event.StartTime != startTime {
+		t.Errorf("Expected StartTime %v, got %v", startTime, event.StartTime)
+	}
+}
+
+func TestDetectEventWithThresholds_DegradedEndsEvent(t *testing.T) {
+	// Degraded pings count as good, so four of them end an event
+	lines := []PingLine{
+		makePing("2026-01-08 16:00:00.000", "severe", 300),
+		makePing("2026-01-08 16:00:15.000", "severe", 320),
+		makePing("2026-01-08 16:00:30.000", "degraded", 40),
+		makePing("2026-01-08 16:00:45.000", "degraded", 45),
+		makePing("2026-01-08 16:01:00.000", "ok", 20),
+		makePing("2026-01-08 16:01:15.000", "degraded", 50),
+	}
+	th := Thresholds{OK: 30 * time.Millisecond, Degraded: 80 * time.Millisecond, Delayed: 250 * time.Millisecond}
+
+	event := DetectEventWithThresholds(lines, th)
+	if event.IsActive {
+		t.Error("Expected event to have ended")
+	}
+	endTime, _ := time.Parse("2006-01-02 15:04:05.000", "2026-01-08 16:00:30.000")
+	if event.EndTime != endTime {
+		t.Errorf("Expected EndTime %v, got %v", endTime, event.EndTime)
+	}
+}
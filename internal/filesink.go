@@ -0,0 +1,130 @@
+// internal/filesink.go
+// @version jsonl
+// @description Log file result sink: one CSV or JSON Lines file per target and period, summarized when the period closes
+
+package internal
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// fileSink writes each target's results to its period log file in format
+// (csv or jsonl), the files tailmonke and the summaries read.
+type fileSink struct {
+	cfg       Config
+	format    string
+	summarize bool                // write summaries at period close
+	files     map[string]*logFile // by target label
+	order     []*logFile          // in config order, for summaries
+}
+
+type logFile struct {
+	target TargetConfig
+	path   string
+	f      *os.File
+	w      *bufio.Writer
+	csv    *csv.Writer // csv format only, writing into w
+	dirty  bool
+}
+
+func newFileSink(cfg Config, format string) *fileSink {
+	return &fileSink{cfg: cfg, format: format, summarize: true}
+}
+
+func (s *fileSink) Name() string { return s.format }
+
+// OpenPeriod creates (or reopens) each target's file for the period.
+func (s *fileSink) OpenPeriod(start time.Time, targets []TargetConfig) error {
+	s.files = map[string]*logFile{}
+	s.order = nil
+	var errs error
+	for _, target := range targets {
+		file := &logFile{target: target, path: prepareLogFile(start, s.cfg, target, s.format)}
+		f, err := os.OpenFile(file.path, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0644)
+		if err != nil {
+			errs = errors.Join(errs, err)
+		} else {
+			file.f, file.w = f, bufio.NewWriter(f)
+			if s.format == logFormatCSV {
+				file.csv = csv.NewWriter(file.w)
+			}
+		}
+		s.files[target.Label()] = file
+		s.order = append(s.order, file)
+	}
+	return errs
+}
+
+func (s *fileSink) Write(target TargetConfig, result ProbeResult) error {
+	file := s.files[target.Label()]
+	if file == nil || file.w == nil {
+		return fmt.Errorf("no open log file for %s", target.Label())
+	}
+	file.dirty = true
+	if file.csv != nil {
+		file.csv.Write(pingRow(result))
+		file.csv.Flush() // into the buffered file writer
+		return file.csv.Error()
+	}
+	line, err := json.Marshal(newProbeRecord(target, result))
+	if err != nil {
+		return err
+	}
+	_, err = file.w.Write(append(line, '\n'))
+	return err
+}
+
+// Flush writes buffered rows out, syncing them to disk with log_fsync.
+func (s *fileSink) Flush() error {
+	var errs error
+	for _, file := range s.order {
+		if !file.dirty {
+			continue
+		}
+		file.dirty = false
+		if err := file.w.Flush(); err != nil {
+			errs = errors.Join(errs, fmt.Errorf("%s: %w", file.path, err))
+			continue
+		}
+		if s.cfg.LogFsync {
+			if err := file.f.Sync(); err != nil {
+				errs = errors.Join(errs, fmt.Errorf("%s: %w", file.path, err))
+			}
+		}
+	}
+	return errs
+}
+
+// ClosePeriod closes the period's files and writes their summaries, unless
+// another log file sink writes them.
+func (s *fileSink) ClosePeriod() error {
+	err := s.Flush()
+	for _, file := range s.order {
+		if file.f != nil {
+			file.f.Close()
+		}
+		if s.summarize {
+			generateSummary(file.path, s.cfg, file.target.Thresholds)
+		}
+	}
+	s.files, s.order = nil, nil
+	return err
+}
+
+// Close closes any files still open without summarizing them.
+func (s *fileSink) Close() error {
+	err := s.Flush()
+	for _, file := range s.order {
+		if file.f != nil {
+			file.f.Close()
+		}
+	}
+	s.files, s.order = nil, nil
+	return err
+}
@@ -0,0 +1,423 @@
+// internal/history.go
+// @version sqlite
+// @description SQLite probe and event history: schema, inserts and the queries behind pingmonke query and tailmonke --db
+
+package internal
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite" // pure-Go driver, registers "sqlite"
+)
+
+// historySchema creates the history tables. Times are Unix milliseconds so
+// they compare and index as integers whatever zone wrote them.
+const historySchema = `
+CREATE TABLE IF NOT EXISTS probes (
+	id          INTEGER PRIMARY KEY,
+	target      TEXT    NOT NULL,
+	host        TEXT    NOT NULL,
+	probe       TEXT    NOT NULL,
+	start_time  INTEGER NOT NULL,
+	end_time    INTEGER NOT NULL,
+	latency_ms  INTEGER NOT NULL,
+	status      TEXT    NOT NULL,
+	family      TEXT    NOT NULL,
+	dns_ms      INTEGER,
+	connect_ms  INTEGER,
+	tls_ms      INTEGER,
+	ttfb_ms     INTEGER,
+	http_status INTEGER,
+	rcode       TEXT    NOT NULL,
+	ip          TEXT    NOT NULL,
+	error       TEXT    NOT NULL
+);
+CREATE INDEX IF NOT EXISTS probes_target_time ON probes (target, start_time);
+CREATE INDEX IF NOT EXISTS probes_status_time ON probes (status, start_time);
+CREATE INDEX IF NOT EXISTS probes_time ON probes (start_time);
+
+CREATE TABLE IF NOT EXISTS events (
+	id          INTEGER PRIMARY KEY,
+	target      TEXT    NOT NULL,
+	period      TEXT    NOT NULL,
+	family      TEXT    NOT NULL,
+	start_time  INTEGER NOT NULL,
+	end_time    INTEGER NOT NULL,
+	duration_ms INTEGER NOT NULL,
+	bad_pings   INTEGER NOT NULL,
+	total_pings INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS events_target_time ON events (target, start_time);
+CREATE INDEX IF NOT EXISTS events_time ON events (start_time);
+CREATE INDEX IF NOT EXISTS events_duration ON events (duration_ms);
+`
+
+// HistoryDB is the SQLite history written by the sqlite sink.
+type HistoryDB struct {
+	db  *sql.DB
+	loc *time.Location // zone times are returned in
+}
+
+// HistoryProbe is one stored probe result.
+type HistoryProbe struct {
+	Target string
+	Host   string
+	Probe  string
+	Result ProbeResult
+}
+
+// HistoryEvent is one stored event.
+type HistoryEvent struct {
+	Target     string
+	Period     string // stamp of the period it was detected in
+	Family     string
+	StartTime  time.Time
+	EndTime    time.Time
+	BadPings   int
+	TotalPings int
+}
+
+// ProbeQuery selects stored probes. Zero fields don't filter.
+type ProbeQuery struct {
+	Target   string
+	Statuses []string
+	From, To time.Time
+	Limit    int
+}
+
+// EventQuery selects stored events. Zero fields don't filter.
+type EventQuery struct {
+	Target      string
+	From, To    time.Time
+	MinDuration time.Duration
+	Limit       int
+}
+
+// OpenHistory opens (creating if needed) the history database at path,
+// returning times in loc. WAL mode lets tailmonke and pingmonke query read
+// while the service writes.
+func OpenHistory(path string, loc *time.Location) (*HistoryDB, error) {
+	if loc == nil {
+		loc = time.Local
+	}
+	dsn := "file:" + filepath.ToSlash(path) + "?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)"
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, err
+	}
+	// One connection, so PRAGMA data_version always asks the same one
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(historySchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &HistoryDB{db: db, loc: loc}, nil
+}
+
+// OpenHistoryForReading opens an existing history database, rather than
+// creating an empty one when the path is wrong.
+func OpenHistoryForReading(path string, loc *time.Location) (*HistoryDB, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("no history database at %s (is the sqlite sink enabled?)", path)
+	}
+	return OpenHistory(path, loc)
+}
+
+func (h *HistoryDB) Close() error {
+	return h.db.Close()
+}
+
+// HistoryPath returns the database the sqlite sink writes to: the path of
+// the first sqlite sink, or pingmonke.db in log_dir.
+func (cfg Config) HistoryPath() string {
+	for _, sc := range cfg.Sinks {
+		if sc.Type == "sqlite" && sc.Path != "" {
+			return ExpandHome(sc.Path)
+		}
+	}
+	logDir := cfg.LogDir
+	if logDir == "" {
+		logDir = defaultLogDir()
+	}
+	return filepath.Join(ExpandHome(logDir), "pingmonke.db")
+}
+
+// insertProbes stores probes in one transaction.
+func (h *HistoryDB) insertProbes(probes []HistoryProbe) error {
+	tx, err := h.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`INSERT INTO probes (target, host, probe, start_time, end_time, latency_ms, status, family,
+		dns_ms, connect_ms, tls_ms, ttfb_ms, http_status, rcode, ip, error)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, p := range probes {
+		r := p.Result
+		errText := ""
+		if r.Err != nil {
+			errText = r.Err.Error()
+		}
+		var httpStatus any
+		if r.HTTPStatus != 0 {
+			httpStatus = r.HTTPStatus
+		}
+		_, err := stmt.Exec(p.Target, p.Host, p.Probe, r.StartTime.UnixMilli(), r.EndTime.UnixMilli(), r.Latency.Milliseconds(),
+			r.Status, r.Family, nullPhase(r.DNS), nullPhase(r.Connect), nullPhase(r.TLS), nullPhase(r.TTFB),
+			httpStatus, r.Rcode, r.IP, errText)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// replaceEvents stores the events detected in one target's period, replacing
+// any stored for it before (such as from before a restart).
+func (h *HistoryDB) replaceEvents(target, period string, events []HistoryEvent) error {
+	tx, err := h.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM events WHERE target = ? AND period = ?`, target, period); err != nil {
+		return err
+	}
+	for _, e := range events {
+		_, err := tx.Exec(`INSERT INTO events (target, period, family, start_time, end_time, duration_ms, bad_pings, total_pings)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			target, period, e.Family, e.StartTime.UnixMilli(), e.EndTime.UnixMilli(), e.EndTime.Sub(e.StartTime).Milliseconds(),
+			e.BadPings, e.TotalPings)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// QueryProbes returns the probes q selects, oldest first. With a limit, the
+// most recent ones are kept.
+func (h *HistoryDB) QueryProbes(q ProbeQuery) ([]HistoryProbe, error) {
+	var where []string
+	var args []any
+	if q.Target != "" {
+		where = append(where, "target = ?")
+		args = append(args, q.Target)
+	}
+	if len(q.Statuses) > 0 {
+		where = append(where, "status IN ("+strings.TrimSuffix(strings.Repeat("?, ", len(q.Statuses)), ", ")+")")
+		for _, s := range q.Statuses {
+			args = append(args, s)
+		}
+	}
+	where, args = timeRange(where, args, q.From, q.To)
+
+	query := `SELECT target, host, probe, start_time, end_time, latency_ms, status, family,
+		dns_ms, connect_ms, tls_ms, ttfb_ms, http_status, rcode, ip, error FROM probes` + whereClause(where) +
+		` ORDER BY start_time DESC, id DESC` + limitClause(q.Limit)
+	rows, err := h.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var probes []HistoryProbe
+	for rows.Next() {
+		var p HistoryProbe
+		var start, end, latency int64
+		var dns, connect, tls, ttfb, httpStatus sql.NullInt64
+		var errText string
+		r := &p.Result
+		err := rows.Scan(&p.Target, &p.Host, &p.Probe, &start, &end, &latency, &r.Status, &r.Family,
+			&dns, &connect, &tls, &ttfb, &httpStatus, &r.Rcode, &r.IP, &errText)
+		if err != nil {
+			return nil, err
+		}
+		r.StartTime = time.UnixMilli(start).In(h.loc)
+		r.EndTime = time.UnixMilli(end).In(h.loc)
+		r.Latency = time.Duration(latency) * time.Millisecond
+		r.DNS, r.Connect, r.TLS, r.TTFB = phaseFromNull(dns), phaseFromNull(connect), phaseFromNull(tls), phaseFromNull(ttfb)
+		r.HTTPStatus = int(httpStatus.Int64)
+		if errText != "" {
+			r.Err = historyError(errText)
+		}
+		probes = append(probes, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	reverse(probes)
+	return probes, nil
+}
+
+// QueryEvents returns the events q selects, oldest first. With a limit, the
+// most recent ones are kept.
+func (h *HistoryDB) QueryEvents(q EventQuery) ([]HistoryEvent, error) {
+	var where []string
+	var args []any
+	if q.Target != "" {
+		where = append(where, "target = ?")
+		args = append(args, q.Target)
+	}
+	if q.MinDuration > 0 {
+		where = append(where, "duration_ms >= ?")
+		args = append(args, q.MinDuration.Milliseconds())
+	}
+	where, args = timeRange(where, args, q.From, q.To)
+
+	query := `SELECT target, period, family, start_time, end_time, bad_pings, total_pings FROM events` +
+		whereClause(where) + ` ORDER BY start_time DESC, id DESC` + limitClause(q.Limit)
+	rows, err := h.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []HistoryEvent
+	for rows.Next() {
+		var e HistoryEvent
+		var start, end int64
+		if err := rows.Scan(&e.Target, &e.Period, &e.Family, &start, &end, &e.BadPings, &e.TotalPings); err != nil {
+			return nil, err
+		}
+		e.StartTime, e.EndTime = time.UnixMilli(start).In(h.loc), time.UnixMilli(end).In(h.loc)
+		events = append(events, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	reverse(events)
+	return events, nil
+}
+
+// RecentPings returns the last limit pings of target as tailmonke lines.
+func (h *HistoryDB) RecentPings(target string, limit int) ([]PingLine, error) {
+	probes, err := h.QueryProbes(ProbeQuery{Target: target, Limit: limit})
+	if err != nil {
+		return nil, err
+	}
+	lines := make([]PingLine, 0, len(probes))
+	for _, p := range probes {
+		row := pingRow(p.Result)
+		lines = append(lines, PingLine{
+			StartTime: row[0],
+			EndTime:   row[1],
+			Latency:   p.Result.Latency.Milliseconds(),
+			Status:    p.Result.Status,
+			Family:    p.Result.Family,
+			Raw:       row,
+		})
+	}
+	return lines, nil
+}
+
+// LatestTarget returns the target of the most recently stored probe.
+func (h *HistoryDB) LatestTarget() (string, error) {
+	var target string
+	err := h.db.QueryRow(`SELECT target FROM probes ORDER BY start_time DESC, id DESC LIMIT 1`).Scan(&target)
+	if err == sql.ErrNoRows {
+		return "", fmt.Errorf("no probes stored yet")
+	}
+	return target, err
+}
+
+// DataVersion changes whenever another connection commits, so readers can
+// poll it cheaply to see whether there is anything new.
+func (h *HistoryDB) DataVersion() (int64, error) {
+	var v int64
+	err := h.db.QueryRow(`PRAGMA data_version`).Scan(&v)
+	return v, err
+}
+
+// ParseQueryTime reads a time for a query: RFC3339, or a date or date and
+// time (2026-01-07, 2026-01-07T14:30, "2026-01-07 14:30:05") in loc.
+func ParseQueryTime(v string, loc *time.Location) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339Nano, v); err == nil {
+		return t, nil
+	}
+	for _, layout := range []string{"2006-01-02", "2006-01-02T15:04", "2006-01-02 15:04", "2006-01-02T15:04:05", "2006-01-02 15:04:05"} {
+		if t, err := time.ParseInLocation(layout, v, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("cannot read %q as a time, use e.g. 2026-01-07, 2026-01-07T14:30 or RFC3339", v)
+}
+
+// ParseLookback reads how far back to look: a duration, or a number of days such as 7d.
+func ParseLookback(v string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(v, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err == nil && n >= 0 {
+			return time.Duration(n) * 24 * time.Hour, nil
+		}
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("cannot read %q as a duration, use e.g. 90m, 24h or 7d", v)
+	}
+	return d, nil
+}
+
+// historyError is a stored probe error, of which only the text survives.
+type historyError string
+
+func (e historyError) Error() string { return string(e) }
+
+func nullPhase(d time.Duration) any {
+	if d < 0 {
+		return nil
+	}
+	return d.Milliseconds()
+}
+
+func phaseFromNull(v sql.NullInt64) time.Duration {
+	if !v.Valid {
+		return noPhase
+	}
+	return time.Duration(v.Int64) * time.Millisecond
+}
+
+func timeRange(where []string, args []any, from, to time.Time) ([]string, []any) {
+	if !from.IsZero() {
+		where = append(where, "start_time >= ?")
+		args = append(args, from.UnixMilli())
+	}
+	if !to.IsZero() {
+		where = append(where, "start_time < ?")
+		args = append(args, to.UnixMilli())
+	}
+	return where, args
+}
+
+func whereClause(where []string) string {
+	if len(where) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(where, " AND ")
+}
+
+func limitClause(limit int) string {
+	if limit <= 0 {
+		return ""
+	}
+	return " LIMIT " + strconv.Itoa(limit)
+}
+
+func reverse[T any](s []T) {
+	for i, j := 0, len(s)-1; i < j; i, j = i+1, j-1 {
+		s[i], s[j] = s[j], s[i]
+	}
+}
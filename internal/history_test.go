@@ -0,0 +1,163 @@
+package internal
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeHistory runs statuses, 15s apart from first, through a sqlite sink in the period from start
+func writeHistory(t *testing.T, cfg Config, target TargetConfig, start, first time.Time, statuses []string) {
+	t.Helper()
+	sink := newSQLiteSink(cfg, cfg.HistoryPath())
+	if err := sink.OpenPeriod(start, []TargetConfig{target}); err != nil {
+		t.Fatal(err)
+	}
+	for i, status := range statuses {
+		result := probeAt(first.Add(time.Duration(i)*15*time.Second), status)
+		result.Latency = 20 * time.Millisecond
+		if status == "timeout" {
+			result.Latency, result.Err = 0, errors.New("i/o timeout")
+		}
+		if err := sink.Write(target, result); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := sink.ClosePeriod(); err != nil {
+		t.Fatal(err)
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestSQLiteSink tests storing probes and a period's events, and querying them back
+func TestSQLiteSink(t *testing.T) {
+	cfg := Config{LogDir: t.TempDir(), Location: time.UTC}
+	SetDefaults(&cfg)
+	cfg.Location = time.UTC
+	isp := TargetConfig{Name: "isp", Host: "1.1.1.1", Probe: "tcp", Thresholds: DefaultThresholds}
+	start := time.Date(2026, 1, 7, 0, 0, 0, 0, time.UTC)
+	statuses := []string{"ok", "timeout", "timeout", "timeout", "ok", "ok", "ok", "ok", "ok", "ok"}
+	writeHistory(t, cfg, isp, start, start, statuses)
+	// A restart rewrites the period's events rather than duplicating them
+	writeHistory(t, cfg, isp, start, start.Add(time.Duration(len(statuses))*15*time.Second), []string{"ok"})
+
+	db, err := OpenHistoryForReading(cfg.HistoryPath(), time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	probes, err := db.QueryProbes(ProbeQuery{Statuses: []string{"timeout"}, From: start.Add(20 * time.Second), To: start.Add(time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(probes) != 2 || probes[0].Result.StartTime != start.Add(30*time.Second) || probes[0].Target != "isp" ||
+		probes[0].Host != "1.1.1.1" || probes[0].Result.Err == nil || probes[0].Result.Err.Error() != "i/o timeout" || probes[0].Result.TTFB != noPhase {
+		t.Errorf("Unexpected timeouts %+v", probes)
+	}
+
+	events, err := db.QueryEvents(EventQuery{Target: "isp"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("Expected 1 event, got %+v", events)
+	}
+	e := events[0]
+	if e.Period != "2026-01-07" || e.StartTime != start.Add(15*time.Second) || e.EndTime != start.Add(time.Minute) || e.BadPings != 3 || e.TotalPings != 3 {
+		t.Errorf("Unexpected event %+v", e)
+	}
+	if events, _ := db.QueryEvents(EventQuery{MinDuration: 5 * time.Minute}); len(events) != 0 {
+		t.Errorf("Expected no events of 5 minutes or more, got %+v", events)
+	}
+
+	lines, err := db.RecentPings("isp", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != 3 || lines[2].StartTime != "2026-01-07 00:02:30.000" || lines[0].Status != "ok" {
+		t.Errorf("Unexpected recent pings %+v", lines)
+	}
+	if target, err := db.LatestTarget(); err != nil || target != "isp" {
+		t.Errorf("Expected latest target isp, got %q %v", target, err)
+	}
+}
+
+// TestRunQuery tests the pingmonke query output formats
+func TestRunQuery(t *testing.T) {
+	cfg := Config{LogDir: t.TempDir()}
+	SetDefaults(&cfg)
+	cfg.Location = time.UTC
+	start := time.Date(2026, 1, 7, 0, 0, 0, 0, time.UTC)
+	writeHistory(t, cfg, TargetConfig{Name: "isp", Thresholds: DefaultThresholds}, start, start, []string{"ok", "timeout", "timeout", "ok"})
+
+	db, err := OpenHistoryForReading(cfg.HistoryPath(), time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	tests := []struct {
+		opts QueryOptions
+		want []string
+	}{
+		{QueryOptions{Statuses: []string{"timeout"}}, []string{"2026-01-07 00:00:15.000  isp", "2 probes"}},
+		{QueryOptions{Format: "csv", Limit: 1}, []string{"Target,Ping Init,", "\nisp,2026-01-07 00:00:45.000,"}},
+		{QueryOptions{Format: "jsonl", Target: "isp", Statuses: []string{"timeout"}}, []string{`"status":"timeout"`, `"error":"i/o timeout"`}},
+		{QueryOptions{Events: true}, []string{"2026-01-07 00:00:15.000", "1 events"}},
+		{QueryOptions{Events: true, Format: "jsonl"}, []string{`"period":"2026-01-07"`, `"bad_pings":2`}},
+	}
+	for _, tt := range tests {
+		var out bytes.Buffer
+		if err := RunQuery(&out, db, tt.opts); err != nil {
+			t.Fatalf("%+v: %v", tt.opts, err)
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(out.String(), want) {
+				t.Errorf("%+v: expected %q in\n%s", tt.opts, want, out.String())
+			}
+		}
+	}
+
+	if err := RunQuery(&bytes.Buffer{}, db, QueryOptions{Format: "xml"}); err == nil {
+		t.Error("Expected an error for an unknown format")
+	}
+	if _, err := OpenHistoryForReading(filepath.Join(cfg.LogDir, "missing.db"), time.UTC); err == nil {
+		t.Error("Expected an error opening a missing database")
+	}
+}
+
+// TestParseQueryTime tests the time and lookback forms pingmonke query accepts
+func TestParseQueryTime(t *testing.T) {
+	loc := time.FixedZone("CST", -6*3600)
+	for input, want := range map[string]time.Time{
+		"2026-01-07":           time.Date(2026, 1, 7, 0, 0, 0, 0, loc),
+		"2026-01-07T14:30":     time.Date(2026, 1, 7, 14, 30, 0, 0, loc),
+		"2026-01-07 14:30:05":  time.Date(2026, 1, 7, 14, 30, 5, 0, loc),
+		"2026-01-07T14:30:00Z": time.Date(2026, 1, 7, 14, 30, 0, 0, time.UTC),
+	} {
+		got, err := ParseQueryTime(input, loc)
+		if err != nil || !got.Equal(want) {
+			t.Errorf("ParseQueryTime(%q) = %v, %v; want %v", input, got, err, want)
+		}
+	}
+	if _, err := ParseQueryTime("last week", loc); err == nil {
+		t.Error("Expected an error for an unreadable time")
+	}
+
+	for input, want := range map[string]time.Duration{"7d": 7 * 24 * time.Hour, "90m": 90 * time.Minute} {
+		if got, err := ParseLookback(input); err != nil || got != want {
+			t.Errorf("ParseLookback(%q) = %v, %v; want %v", input, got, err, want)
+		}
+	}
+	for _, input := range []string{"d", "-1h", "week"} {
+		if _, err := ParseLookback(input); err == nil {
+			t.Errorf("Expected an error for lookback %q", input)
+		}
+	}
+}
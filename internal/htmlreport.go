@@ -0,0 +1,491 @@
+// internal/htmlreport.go
+// @version html
+// @description Self-contained HTML summaries and reports: inline SVG latency and status charts, event tables with context pings
+
+package internal
+
+import (
+	"fmt"
+	"html"
+	"html/template"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// htmlPage is a summary or report rendered as a single HTML file. Styles and
+// charts are inline and there is no script, so the file can be mailed as an
+// attachment and opened anywhere.
+type htmlPage struct {
+	Title    string
+	Subtitle string
+	Sections []htmlSection
+}
+
+// htmlSection covers one target of a report, or the log of a summary.
+type htmlSection struct {
+	Title    string
+	Facts    []htmlFact
+	Latency  template.HTML // SVG latency-over-time chart
+	Statuses template.HTML // SVG status distribution chart
+	Report   bool          // show the day, hour and gap tables
+	Days     []htmlBucket
+	Hours    []htmlBucket
+	Events   []htmlEvent
+	Gaps     []htmlGap
+}
+
+type htmlFact struct {
+	Label, Value string
+}
+
+// htmlBucket is a row of a report's day or hour table.
+type htmlBucket struct {
+	Label, Coverage, Uptime, Pings, Loss, P50, P95, P99, Events, Down string
+	Unmonitored                                                       bool
+}
+
+// htmlEvent is a row of the event table, with the pings of the event and
+// contextPings either side of it.
+type htmlEvent struct {
+	Start, End, Duration, Scope, Class, Severity, Statuses string
+	Pings                                                  []htmlPing
+}
+
+type htmlPing struct {
+	Start, End, Latency, Status, Family string
+	Tier                                string // CSS class for the latency tier
+	Context                             bool   // before or after the event
+}
+
+type htmlGap struct {
+	Start, End, Duration string
+}
+
+// contextPings is how many pings either side of an event summaries show.
+const contextPings = 3
+
+// eventContext returns the range of pings, inclusive, shown for an event:
+// its own and contextPings either side.
+func eventContext(e Event, n int) (first, last int) {
+	return max(e.StartIndex-contextPings, 0), min(e.EndIndex+contextPings, n-1)
+}
+
+// writeSummaryHTML writes the HTML version of a period summary.
+func writeSummaryHTML(path, logFile string, pings []PingRecord, events []Event, th Thresholds) error {
+	stats := computeStats(pings, th)
+	section := htmlSection{
+		Title:    LogFileTarget(logFile),
+		Facts:    statsFacts(stats),
+		Statuses: statusChartSVG(pings),
+		Events:   htmlEvents(pings, events, th),
+	}
+	section.Facts = append([]htmlFact{{"Events", strconv.Itoa(len(events))}}, section.Facts...)
+	if len(pings) > 0 {
+		section.Latency = latencyChartSVG(pings, events, nil, pings[0].StartTime, pings[len(pings)-1].EndTime, th)
+	}
+	if section.Title == "" {
+		section.Title = "Pings"
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	page := htmlPage{Title: "pingmonke summary", Subtitle: strings.TrimSuffix(filepath.Base(logFile), gzipExt), Sections: []htmlSection{section}}
+	if err := htmlTemplate.Execute(f, page); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// writeReportHTML writes a multi-day report as HTML.
+func writeReportHTML(w io.Writer, r *Report) error {
+	page := htmlPage{Title: "pingmonke report", Subtitle: r.reportRange()}
+	for _, tr := range r.Targets {
+		o := tr.Overall
+		section := htmlSection{
+			Title: fmt.Sprintf("%s (%s)", tr.Target.Label(), tr.Target.Host),
+			Facts: append([]htmlFact{
+				{"Period files", strconv.Itoa(tr.Files)},
+				{"Monitored", fmt.Sprintf("%s of %s (%.2f%%)", formatReportDuration(o.Monitored), formatReportDuration(o.Span), o.Coverage())},
+				{"Uptime", uptimeText(o)},
+				{"Down", fmt.Sprintf("%s in %d events", formatReportDuration(o.Down), o.Events)},
+			}, statsFacts(o.Stats)...),
+			Latency:  latencyChartSVG(tr.pings, tr.Events, tr.Gaps, r.From, earlier(r.To, r.From.Add(o.Span)), tr.Target.Thresholds),
+			Statuses: statusChartSVG(tr.pings),
+			Report:   true,
+			Events:   htmlEvents(tr.pings, tr.Events, tr.Target.Thresholds),
+		}
+		for _, b := range tr.Days {
+			if b.Span > 0 {
+				section.Days = append(section.Days, newHTMLBucket(b))
+			}
+		}
+		for _, b := range tr.Hours {
+			if b.Span > 0 {
+				section.Hours = append(section.Hours, newHTMLBucket(b))
+			}
+		}
+		for _, g := range tr.Gaps {
+			section.Gaps = append(section.Gaps, htmlGap{formatTimestamp(g.Start), formatTimestamp(g.End), formatReportDuration(g.End.Sub(g.Start))})
+		}
+		page.Sections = append(page.Sections, section)
+	}
+	return htmlTemplate.Execute(w, page)
+}
+
+func statsFacts(s PingStats) []htmlFact {
+	return []htmlFact{
+		{"Pings", strconv.Itoa(s.Total)},
+		{"Loss", fmt.Sprintf("%.2f%% (longest streak %d)", s.LossPct, s.LossStreak)},
+		{"Availability", fmt.Sprintf("%.2f%%", s.Available)},
+		{"Latency", fmt.Sprintf("p50 %dms, p90 %dms, p95 %dms, p99 %dms, max %dms", s.P50, s.P90, s.P95, s.P99, s.Max)},
+		{"Jitter", fmt.Sprintf("%.1fms", s.Jitter)},
+	}
+}
+
+func newHTMLBucket(b ReportBucket) htmlBucket {
+	if b.Monitored == 0 {
+		return htmlBucket{Label: b.Label, Coverage: "0.0%", Uptime: "not monitored", Unmonitored: true}
+	}
+	return htmlBucket{
+		Label:    b.Label,
+		Coverage: fmt.Sprintf("%.1f%%", b.Coverage()),
+		Uptime:   uptimeText(b),
+		Pings:    strconv.Itoa(b.Stats.Total),
+		Loss:     fmt.Sprintf("%.2f%%", b.Stats.LossPct),
+		P50:      fmt.Sprintf("%dms", b.Stats.P50),
+		P95:      fmt.Sprintf("%dms", b.Stats.P95),
+		P99:      fmt.Sprintf("%dms", b.Stats.P99),
+		Events:   strconv.Itoa(b.Events),
+		Down:     formatReportDuration(b.Down),
+	}
+}
+
+// htmlEvents builds the event table rows, with each event's context pings.
+func htmlEvents(pings []PingRecord, events []Event, th Thresholds) []htmlEvent {
+	var rows []htmlEvent
+	for _, e := range events {
+		row := htmlEvent{
+			Start:    formatTimestamp(e.StartTime),
+			End:      formatTimestamp(e.EndTime),
+			Duration: formatReportDuration(e.EndTime.Sub(e.StartTime)),
+			Scope:    eventScopeLabel(e.Family),
+			Class:    e.Class,
+			Severity: fmt.Sprintf("%.1f", e.Severity),
+			Statuses: eventStatusBreakdown(pings[e.StartIndex:e.EndIndex+1], th),
+		}
+		if e.Ongoing {
+			row.End += " (ongoing)"
+		}
+		first, last := eventContext(e, len(pings))
+		for j := first; j <= last; j++ {
+			p := pings[j]
+			row.Pings = append(row.Pings, htmlPing{
+				Start:   formatTimestamp(p.StartTime),
+				End:     formatTimestamp(p.EndTime),
+				Latency: strconv.FormatInt(p.Latency, 10),
+				Status:  p.Status,
+				Family:  p.Family,
+				Tier:    pingTier(p, th),
+				Context: j < e.StartIndex || j > e.EndIndex,
+			})
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// pingTier names the tier a ping falls in, for coloring: lost, or its
+// latency tier under th.
+func pingTier(p PingRecord, th Thresholds) string {
+	if isFailureStatus(p.Status) {
+		return "lost"
+	}
+	return classifyStatus(time.Duration(p.Latency)*time.Millisecond, th)
+}
+
+// Chart colors, by latency tier and event class
+var (
+	tierColors = map[string]string{
+		"ok": "#2f9e44", "degraded": "#c9a800", "delayed": "#e8590c", "severe": "#c2255c", "lost": "#c92a2a",
+	}
+	classColors = map[string]string{
+		EventOutage: "#e03131", EventDegradation: "#f08c00", EventMixed: "#d6336c", EventFlapping: "#7048e8", EventMicroBlip: "#868e96",
+	}
+)
+
+// Latency chart layout, in SVG user units
+const (
+	chartWidth  = 960
+	chartHeight = 260
+	chartLeft   = 52
+	chartRight  = 12
+	chartTop    = 14
+	chartBottom = 28
+)
+
+// latencyChartSVG draws answered latency over [from, to): the median and
+// maximum of each pixel column, lost pings as red marks along the top,
+// events shaded in the color of their class and gaps in grey. Columns keep
+// the chart small however many pings there are.
+func latencyChartSVG(pings []PingRecord, events []Event, gaps []ReportGap, from, to time.Time, th Thresholds) template.HTML {
+	if !to.After(from) {
+		to = from.Add(time.Minute)
+	}
+	plotW := float64(chartWidth - chartLeft - chartRight)
+	plotH := float64(chartHeight - chartTop - chartBottom)
+	span := float64(to.Sub(from))
+	x := func(t time.Time) float64 {
+		return chartLeft + math.Max(0, math.Min(1, float64(t.Sub(from))/span))*plotW
+	}
+
+	// Gather each column's pings
+	columns := int(plotW)
+	answered := make([][]int64, columns)
+	lost := make([]int, columns)
+	total := make([]int, columns)
+	var all []int64
+	for _, p := range pings {
+		c := int((x(p.StartTime) - chartLeft) / plotW * float64(columns))
+		c = min(max(c, 0), columns-1)
+		total[c]++
+		if isFailureStatus(p.Status) {
+			lost[c]++
+			continue
+		}
+		answered[c] = append(answered[c], p.Latency)
+		all = append(all, p.Latency)
+	}
+
+	// Scale to the 99th percentile, so a few spikes don't flatten the rest,
+	// and always show the ok threshold
+	top := float64(th.OK.Milliseconds()) * 1.5
+	if len(all) > 0 {
+		slices.Sort(all)
+		top = math.Max(top, float64(percentile(all, 99))*1.25)
+	}
+	top = niceCeil(math.Max(top, 10))
+	y := func(ms float64) float64 {
+		return chartTop + plotH - math.Min(ms, top)/top*plotH
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg class="chart" viewBox="0 0 %d %d" xmlns="http://www.w3.org/2000/svg" role="img" aria-label="Latency over time">`, chartWidth, chartHeight)
+	fmt.Fprintf(&b, `<rect x="%d" y="%d" width="%.0f" height="%.0f" fill="#fff" stroke="#ced4da"/>`, chartLeft, chartTop, plotW, plotH)
+
+	for _, g := range gaps {
+		fmt.Fprintf(&b, `<rect x="%.1f" y="%d" width="%.1f" height="%.0f" fill="#adb5bd" opacity="0.35"><title>Not monitored %s to %s</title></rect>`,
+			x(g.Start), chartTop, math.Max(x(g.End)-x(g.Start), 1), plotH, svgText(formatTimestamp(g.Start)), svgText(formatTimestamp(g.End)))
+	}
+	for _, e := range events {
+		fmt.Fprintf(&b, `<rect x="%.1f" y="%d" width="%.1f" height="%.0f" fill="%s" opacity="0.22"><title>%s %s to %s, severity %.1f</title></rect>`,
+			x(e.StartTime), chartTop, math.Max(x(e.EndTime)-x(e.StartTime), 1), plotH, classColors[e.Class],
+			svgText(e.Class), svgText(formatTimestamp(e.StartTime)), svgText(formatTimestamp(e.EndTime)), e.Severity)
+	}
+
+	// Threshold lines and the latency axis
+	for _, t := range []struct {
+		name string
+		d    time.Duration
+	}{{"ok", th.OK}, {"degraded", th.Degraded}, {"delayed", th.Delayed}} {
+		if ms := float64(t.d.Milliseconds()); ms > 0 && ms < top {
+			fmt.Fprintf(&b, `<line x1="%d" x2="%d" y1="%.1f" y2="%.1f" stroke="%s" stroke-dasharray="4 3"><title>%s threshold %v</title></line>`,
+				chartLeft, chartWidth-chartRight, y(ms), y(ms), tierColors[t.name], t.name, t.d)
+		}
+	}
+	for _, v := range []float64{0, top / 2, top} {
+		fmt.Fprintf(&b, `<text x="%d" y="%.1f" text-anchor="end" dominant-baseline="middle">%sms</text>`, chartLeft-6, y(v), strconv.FormatFloat(v, 'f', -1, 64))
+	}
+
+	// Time axis: up to 8 evenly spaced labels
+	layout := "15:04"
+	if to.Sub(from) > 24*time.Hour {
+		layout = "01-02 15:04"
+	}
+	for i := 0; i <= 7; i++ {
+		t := from.Add(time.Duration(float64(to.Sub(from)) * float64(i) / 7))
+		anchor := "middle"
+		switch i {
+		case 0:
+			anchor = "start"
+		case 7:
+			anchor = "end"
+		}
+		fmt.Fprintf(&b, `<text x="%.1f" y="%d" text-anchor="%s">%s</text>`, x(t), chartHeight-8, anchor, t.Format(layout))
+	}
+
+	// Median and maximum of each column, broken where there are no answers
+	var median, peak strings.Builder
+	for c := range columns {
+		latencies := answered[c]
+		if lost[c] > 0 {
+			fmt.Fprintf(&b, `<rect x="%d" y="%d" width="1" height="6" fill="%s" opacity="%.2f"/>`,
+				chartLeft+c, chartTop, tierColors["lost"], 0.3+0.7*float64(lost[c])/float64(total[c]))
+		}
+		if len(latencies) == 0 {
+			continue
+		}
+		slices.Sort(latencies)
+		cmd := "L"
+		if c == 0 || len(answered[c-1]) == 0 {
+			cmd = "M"
+		}
+		px := float64(chartLeft+c) + 0.5
+		fmt.Fprintf(&median, "%s%.1f %.1f", cmd, px, y(float64(percentile(latencies, 50))))
+		fmt.Fprintf(&peak, "%s%.1f %.1f", cmd, px, y(float64(latencies[len(latencies)-1])))
+	}
+	fmt.Fprintf(&b, `<path d="%s" fill="none" stroke="#74c0fc" stroke-width="1"/>`, peak.String())
+	fmt.Fprintf(&b, `<path d="%s" fill="none" stroke="#1864ab" stroke-width="1.5"/>`, median.String())
+	b.WriteString(`</svg>`)
+	return template.HTML(b.String())
+}
+
+// statusChartSVG draws how many pings had each status, most common first.
+func statusChartSVG(pings []PingRecord) template.HTML {
+	counts := map[string]int{}
+	for _, p := range pings {
+		counts[p.Status]++
+	}
+	statuses := make([]string, 0, len(counts))
+	for s := range counts {
+		statuses = append(statuses, s)
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		if counts[statuses[i]] != counts[statuses[j]] {
+			return counts[statuses[i]] > counts[statuses[j]]
+		}
+		return statuses[i] < statuses[j]
+	})
+
+	const rowH, labelW, barW = 24, 110, 640
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg class="chart statuses" viewBox="0 0 %d %d" xmlns="http://www.w3.org/2000/svg" role="img" aria-label="Status distribution">`,
+		labelW+barW+120, max(len(statuses), 1)*rowH)
+	for i, s := range statuses {
+		color, ok := tierColors[s]
+		if !ok {
+			color = tierColors["lost"]
+			if !isFailureStatus(s) {
+				color = "#ae3ec9"
+			}
+		}
+		share := float64(counts[s]) / float64(len(pings))
+		yRow := i * rowH
+		fmt.Fprintf(&b, `<text x="%d" y="%d" text-anchor="end" dominant-baseline="middle">%s</text>`, labelW-8, yRow+rowH/2, svgText(s))
+		fmt.Fprintf(&b, `<rect x="%d" y="%d" width="%.1f" height="%d" fill="%s"/>`, labelW, yRow+4, math.Max(share*barW, 1), rowH-8, color)
+		fmt.Fprintf(&b, `<text x="%.1f" y="%d" dominant-baseline="middle">%d (%.2f%%)</text>`, float64(labelW)+math.Max(share*barW, 1)+6, yRow+rowH/2, counts[s], 100*share)
+	}
+	b.WriteString(`</svg>`)
+	return template.HTML(b.String())
+}
+
+// niceCeil rounds v up to 1, 2 or 5 times a power of ten.
+func niceCeil(v float64) float64 {
+	mag := math.Pow(10, math.Floor(math.Log10(v)))
+	for _, m := range []float64{1, 2, 5, 10} {
+		if v <= m*mag {
+			return m * mag
+		}
+	}
+	return 10 * mag
+}
+
+// svgText escapes s for SVG text and attributes.
+func svgText(s string) string {
+	return html.EscapeString(s)
+}
+
+var htmlTemplate = template.Must(template.New("page").Funcs(template.FuncMap{
+	"contextPings": func() int { return contextPings },
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}: {{.Subtitle}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; color: #212529; margin: 2em auto; max-width: 1000px; padding: 0 1em; }
+h1 { font-size: 1.6em; margin-bottom: 0; }
+h2 { border-bottom: 1px solid #dee2e6; padding-bottom: .2em; margin-top: 2em; }
+h3 { font-size: 1.05em; margin-top: 1.6em; }
+.subtitle { color: #6c757d; margin-top: .2em; }
+dl.facts { display: grid; grid-template-columns: max-content auto; gap: .2em 1.2em; }
+dl.facts dt { font-weight: 600; }
+dl.facts dd { margin: 0; }
+svg.chart { width: 100%; height: auto; font-size: 11px; fill: #495057; }
+.legend span { display: inline-block; margin-right: 1em; font-size: .85em; }
+.legend i { display: inline-block; width: .9em; height: .9em; margin-right: .3em; vertical-align: -.1em; }
+table { border-collapse: collapse; width: 100%; font-size: .9em; margin: .5em 0; }
+th, td { text-align: left; padding: .25em .6em; border-bottom: 1px solid #e9ecef; }
+th { background: #f1f3f5; }
+td.num { text-align: right; }
+tr.unmonitored td { color: #868e96; background: #f8f9fa; font-style: italic; }
+tr.context td { color: #868e96; }
+details summary { cursor: pointer; }
+.ok { color: #2f9e44; } .degraded { color: #a98d00; } .delayed { color: #e8590c; } .severe { color: #c2255c; } .lost { color: #c92a2a; font-weight: 600; }
+.class { font-weight: 600; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p class="subtitle">{{.Subtitle}}</p>
+{{range .Sections}}
+<h2>{{.Title}}</h2>
+<dl class="facts">{{range .Facts}}<dt>{{.Label}}</dt><dd>{{.Value}}</dd>{{end}}</dl>
+{{if .Latency}}
+<h3>Latency over time</h3>
+{{.Latency}}
+<div class="legend"><span><i style="background:#1864ab"></i>median</span><span><i style="background:#74c0fc"></i>maximum</span><span><i style="background:#c92a2a"></i>lost pings</span><span><i style="background:#e03131;opacity:.3"></i>outage</span><span><i style="background:#f08c00;opacity:.3"></i>degradation</span><span><i style="background:#d6336c;opacity:.3"></i>mixed</span><span><i style="background:#7048e8;opacity:.3"></i>flapping</span><span><i style="background:#868e96;opacity:.3"></i>micro-blip</span>{{if .Report}}<span><i style="background:#adb5bd;opacity:.5"></i>not monitored</span>{{end}}</div>
+{{end}}
+<h3>Status distribution</h3>
+{{.Statuses}}
+{{if .Report}}
+<h3>By day</h3>
+<table>
+<tr><th>Day</th><th>Monitored</th><th>Uptime</th><th>Pings</th><th>Loss</th><th>p50</th><th>p95</th><th>p99</th><th>Events</th><th>Down</th></tr>
+{{range .Days}}{{template "bucket" .}}{{end}}
+</table>
+<h3>By hour of day</h3>
+<table>
+<tr><th>Hour</th><th>Monitored</th><th>Uptime</th><th>Pings</th><th>Loss</th><th>p50</th><th>p95</th><th>p99</th><th>Events</th><th>Down</th></tr>
+{{range .Hours}}{{template "bucket" .}}{{end}}
+</table>
+{{end}}
+<h3>Events ({{len .Events}})</h3>
+{{if .Events}}
+<table>
+<tr><th>Start</th><th>End</th><th>Duration</th><th>Scope</th><th>Class</th><th>Severity</th><th>Statuses</th></tr>
+{{range .Events}}
+<tr><td>{{.Start}}</td><td>{{.End}}</td><td>{{.Duration}}</td><td>{{.Scope}}</td><td class="class">{{.Class}}</td><td class="num">{{.Severity}}</td><td>{{.Statuses}}</td></tr>
+<tr><td colspan="7"><details><summary>Pings of the event, with {{contextPings}} either side in grey</summary>
+<table>
+<tr><th>Sent</th><th>Received</th><th>Latency (ms)</th><th>Status</th><th>AF</th></tr>
+{{range .Pings}}<tr{{if .Context}} class="context"{{end}}><td>{{.Start}}</td><td>{{.End}}</td><td class="num {{.Tier}}">{{.Latency}}</td><td class="{{.Tier}}">{{.Status}}</td><td>{{.Family}}</td></tr>{{end}}
+</table></details></td></tr>
+{{end}}
+</table>
+{{else}}<p>No events.</p>{{end}}
+{{if .Report}}
+<h3>Not monitored ({{len .Gaps}})</h3>
+<p>pingmonke wasn't running, so these times count neither as uptime nor as downtime.</p>
+{{if .Gaps}}
+<table>
+<tr><th>Start</th><th>End</th><th>Duration</th></tr>
+{{range .Gaps}}<tr><td>{{.Start}}</td><td>{{.End}}</td><td>{{.Duration}}</td></tr>{{end}}
+</table>
+{{end}}
+{{end}}
+{{end}}
+</body>
+</html>
+{{define "bucket"}}<tr{{if .Unmonitored}} class="unmonitored"{{end}}><td>{{.Label}}</td><td class="num">{{.Coverage}}</td><td class="num">{{.Uptime}}</td><td class="num">{{.Pings}}</td><td class="num">{{.Loss}}</td><td class="num">{{.P50}}</td><td class="num">{{.P95}}</td><td class="num">{{.P99}}</td><td class="num">{{.Events}}</td><td class="num">{{.Down}}</td></tr>{{end}}
+`))
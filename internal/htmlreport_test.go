@@ -0,0 +1,110 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// checkSelfContained fails if page loads anything from elsewhere or holds an
+// SVG chart that isn't well-formed.
+func checkSelfContained(t *testing.T, page string) {
+	t.Helper()
+	for _, bad := range []string{"<script", "src=", "href=", "@import", "url("} {
+		if strings.Contains(page, bad) {
+			t.Errorf("Expected a self-contained page, found %q", bad)
+		}
+	}
+	charts := 0
+	for rest := page; ; {
+		start := strings.Index(rest, "<svg")
+		if start < 0 {
+			break
+		}
+		end := strings.Index(rest[start:], "</svg>") + start + len("</svg>")
+		dec := xml.NewDecoder(strings.NewReader(rest[start:end]))
+		for {
+			if _, err := dec.Token(); err == io.EOF {
+				break
+			} else if err != nil {
+				t.Fatalf("Malformed chart: %v", err)
+			}
+		}
+		charts++
+		rest = rest[end:]
+	}
+	if charts == 0 {
+		t.Error("Expected inline SVG charts")
+	}
+}
+
+// TestSummaryHTML tests the HTML summary written next to the CSV one
+func TestSummaryHTML(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "2026-09-01-isp-pings.csv")
+	day := time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)
+	writeReportLog(t, path, day, day.Add(2*time.Hour), day.Add(time.Hour), 5*time.Minute)
+
+	generateSummary(path, Config{}, DefaultThresholds)
+	if _, err := os.Stat(filepath.Join(dir, "2026-09-01-isp-pings-summary.html")); !os.IsNotExist(err) {
+		t.Fatalf("Expected no HTML summary unless enabled, got %v", err)
+	}
+
+	generateSummary(path, Config{SummaryHTML: true}, DefaultThresholds)
+	b, err := os.ReadFile(filepath.Join(dir, "2026-09-01-isp-pings-summary.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	page := string(b)
+	checkSelfContained(t, page)
+	for _, want := range []string{"<h2>isp</h2>", "Latency over time", "Status distribution", "Events (1)", `class="class">outage`, "2026-09-01 01:00:00.000", "timeout=20"} {
+		if !strings.Contains(page, want) {
+			t.Errorf("Expected %q in the summary", want)
+		}
+	}
+	// The event's shaded region, and its 20 pings with 3 either side
+	if !strings.Contains(page, "<title>outage 2026-09-01 01:00:00.000 to 2026-09-01 01:05:00.000") {
+		t.Error("Expected the event shaded on the latency chart")
+	}
+	if n := strings.Count(page, `<tr class="context">`); n != 6 {
+		t.Errorf("Expected 6 context pings, got %d", n)
+	}
+	if n := strings.Count(page, `<td class="lost">timeout</td>`); n != 20 {
+		t.Errorf("Expected the event's 20 timeouts, got %d", n)
+	}
+}
+
+// TestReportHTML tests the HTML report, with a day pingmonke wasn't running
+func TestReportHTML(t *testing.T) {
+	dir := t.TempDir()
+	target := TargetConfig{Name: "isp", Host: "192.0.2.1", Interval: 15 * time.Second, Thresholds: DefaultThresholds}
+	cfg := Config{LogDir: dir, Location: time.UTC, Targets: []TargetConfig{target}}
+	day := func(d, h int) time.Time { return time.Date(2026, 9, d, h, 0, 0, 0, time.UTC) }
+	writeReportLog(t, filepath.Join(dir, "2026-09-01-isp-pings.csv"), day(1, 0), day(1, 12), day(1, 6), 10*time.Minute)
+	writeReportLog(t, filepath.Join(dir, "2026-09-03-isp-pings.csv"), day(3, 0), day(4, 0), time.Time{}, 0)
+
+	report, err := buildReport(cfg, ReportOptions{From: day(1, 0), To: day(4, 0)}, day(10, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := writeReportHTML(&buf, report); err != nil {
+		t.Fatal(err)
+	}
+	page := buf.String()
+	checkSelfContained(t, page)
+	for _, want := range []string{
+		"2026-09-01 to 2026-09-03 (UTC)", "isp (192.0.2.1)", "By day", "By hour of day",
+		`<tr class="unmonitored"><td>2026-09-02</td>`, "<title>Not monitored 2026-09-01 12:00:00.000 to 2026-09-03 00:00:00.000</title>",
+		"Not monitored (1)", "Events (1)",
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("Expected %q in the report", want)
+		}
+	}
+}
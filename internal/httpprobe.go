@@ -0,0 +1,187 @@
+// internal/httpprobe.go
+// @version http-probe
+// @description HTTP/HTTPS probe with per-phase timings from net/http/httptrace
+
+package internal
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HTTP probe failure reasons, mapped to their own statuses by statusForError.
+var (
+	ErrHTTPStatus = errors.New("http: unexpected status code")
+	ErrHTTPBody   = errors.New("http: expected body text not found")
+)
+
+// noPhase marks a timing phase that did not happen during a probe (for
+// example DNS when the target is an IP literal, or TLS for plain HTTP).
+const noPhase time.Duration = -1
+
+// httpBodyLimit caps how much of a response body is read per probe.
+const httpBodyLimit = 1 << 20
+
+// HTTPProbeConfig holds the settings for targets with probe: http.
+type HTTPProbeConfig struct {
+	URL                string `yaml:"url"`    // defaults to http(s)://host:port/
+	Method             string `yaml:"method"` // GET or HEAD
+	ExpectStatus       []int  `yaml:"expect_status"`
+	ExpectBody         string `yaml:"expect_body"` // substring the body must contain (GET only)
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify"`
+}
+
+// HTTPTimings breaks an HTTP probe down into its phases. Phases that did not
+// happen are set to noPhase.
+type HTTPTimings struct {
+	DNS        time.Duration
+	Connect    time.Duration
+	TLS        time.Duration
+	TTFB       time.Duration // request start to first response byte
+	Total      time.Duration
+	StatusCode int
+	IP         string // address of the connection that served the request
+}
+
+// HttpPing issues one request over the given address family ("v4" or "v6")
+// on a fresh connection and records how long each phase took. A response is
+// a failure if its status code is not expected or the body lacks ExpectBody.
+func HttpPing(probe HTTPProbeConfig, family string, timeout time.Duration) (HTTPTimings, error) {
+	timings := HTTPTimings{DNS: noPhase, Connect: noPhase, TLS: noPhase, TTFB: noPhase}
+
+	network := "tcp4"
+	if family == "v6" {
+		network = "tcp6"
+	}
+	dialer := &net.Dialer{Timeout: timeout}
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, _, addr string) (net.Conn, error) {
+			return dialer.DialContext(ctx, network, addr)
+		},
+		TLSClientConfig:     &tls.Config{InsecureSkipVerify: probe.InsecureSkipVerify},
+		TLSHandshakeTimeout: timeout,
+		DisableKeepAlives:   true, // every probe measures a full connection setup
+	}
+	defer transport.CloseIdleConnections()
+
+	client := &http.Client{
+		Transport: transport,
+		Timeout:   timeout,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	// Trace callbacks can fire on the transport's dial goroutine
+	var mu sync.Mutex
+	var dnsStart, connectStart, tlsStart time.Time
+	start := time.Now()
+	trace := &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			mu.Lock()
+			dnsStart = time.Now()
+			mu.Unlock()
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			mu.Lock()
+			timings.DNS = time.Since(dnsStart)
+			mu.Unlock()
+		},
+		ConnectStart: func(string, string) {
+			mu.Lock()
+			connectStart = time.Now()
+			mu.Unlock()
+		},
+		ConnectDone: func(_, addr string, err error) {
+			mu.Lock()
+			if err == nil {
+				timings.Connect = time.Since(connectStart)
+				if host, _, err := net.SplitHostPort(addr); err == nil {
+					timings.IP = host
+				}
+			}
+			mu.Unlock()
+		},
+		TLSHandshakeStart: func() {
+			mu.Lock()
+			tlsStart = time.Now()
+			mu.Unlock()
+		},
+		TLSHandshakeDone: func(_ tls.ConnectionState, err error) {
+			mu.Lock()
+			if err == nil {
+				timings.TLS = time.Since(tlsStart)
+			}
+			mu.Unlock()
+		},
+		GotFirstResponseByte: func() {
+			mu.Lock()
+			timings.TTFB = time.Since(start)
+			mu.Unlock()
+		},
+	}
+
+	req, err := http.NewRequestWithContext(httptrace.WithClientTrace(context.Background(), trace), probe.Method, probe.URL, nil)
+	if err != nil {
+		return timings, err
+	}
+	req.Header.Set("User-Agent", "pingmonke")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		mu.Lock()
+		defer mu.Unlock()
+		return timings, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, httpBodyLimit))
+	total := time.Since(start)
+
+	mu.Lock()
+	defer mu.Unlock()
+	timings.Total = total
+	timings.StatusCode = resp.StatusCode
+	if err != nil {
+		return timings, err
+	}
+
+	if !expectedStatus(probe.ExpectStatus, resp.StatusCode) {
+		return timings, fmt.Errorf("%w: %s", ErrHTTPStatus, resp.Status)
+	}
+	if probe.ExpectBody != "" && !strings.Contains(string(body), probe.ExpectBody) {
+		return timings, fmt.Errorf("%w: %q", ErrHTTPBody, probe.ExpectBody)
+	}
+	return timings, nil
+}
+
+// expectedStatus accepts any 2xx/3xx response unless specific codes are configured.
+func expectedStatus(expected []int, code int) bool {
+	if len(expected) == 0 {
+		return code >= 200 && code < 400
+	}
+	for _, want := range expected {
+		if code == want {
+			return true
+		}
+	}
+	return false
+}
+
+// defaultHTTPURL builds the URL probed when a target doesn't set one.
+func defaultHTTPURL(host string, port int) string {
+	scheme := "http"
+	if port == 443 {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://%s/", scheme, net.JoinHostPort(host, fmt.Sprint(port)))
+}
@@ -0,0 +1,132 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newProbeServer() *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "all systems nominal")
+	})
+	mux.HandleFunc("/broken", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "upstream exploded", http.StatusBadGateway)
+	})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(500 * time.Millisecond)
+		fmt.Fprint(w, "eventually")
+	})
+	return httptest.NewServer(mux)
+}
+
+// TestHttpPingSuccess tests a healthy GET and its phase timings
+func TestHttpPingSuccess(t *testing.T) {
+	srv := newProbeServer()
+	defer srv.Close()
+
+	timings, err := HttpPing(HTTPProbeConfig{URL: srv.URL + "/ok", Method: "GET", ExpectBody: "nominal"}, "v4", 5*time.Second)
+	if err != nil {
+		t.Fatalf("Expected success, got %v", err)
+	}
+	if timings.StatusCode != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", timings.StatusCode)
+	}
+	if timings.Connect == noPhase {
+		t.Error("Expected connect phase to be measured")
+	}
+	if timings.TTFB == noPhase || timings.TTFB > timings.Total {
+		t.Errorf("Expected TTFB within total, got ttfb=%v total=%v", timings.TTFB, timings.Total)
+	}
+	if timings.DNS != noPhase {
+		t.Errorf("Expected no DNS phase for an IP literal, got %v", timings.DNS)
+	}
+	if timings.TLS != noPhase {
+		t.Errorf("Expected no TLS phase for plain http, got %v", timings.TLS)
+	}
+}
+
+// TestHttpPingFailures tests that unexpected responses are reported as failures
+func TestHttpPingFailures(t *testing.T) {
+	srv := newProbeServer()
+	defer srv.Close()
+
+	tests := []struct {
+		name       string
+		probe      HTTPProbeConfig
+		wantErr    error
+		wantStatus string
+	}{
+		{
+			name:       "Server error status",
+			probe:      HTTPProbeConfig{URL: srv.URL + "/broken", Method: "GET"},
+			wantErr:    ErrHTTPStatus,
+			wantStatus: "bad_status",
+		},
+		{
+			name:       "Status not in expected list",
+			probe:      HTTPProbeConfig{URL: srv.URL + "/ok", Method: "GET", ExpectStatus: []int{204}},
+			wantErr:    ErrHTTPStatus,
+			wantStatus: "bad_status",
+		},
+		{
+			name:       "Body substring missing",
+			probe:      HTTPProbeConfig{URL: srv.URL + "/ok", Method: "GET", ExpectBody: "maintenance"},
+			wantErr:    ErrHTTPBody,
+			wantStatus: "bad_body",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			timings, err := HttpPing(tt.probe, "v4", 5*time.Second)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Expected %v, got %v", tt.wantErr, err)
+			}
+			if status := statusForError(err); status != tt.wantStatus {
+				t.Errorf("Expected status %q, got %q", tt.wantStatus, status)
+			}
+			if timings.StatusCode == 0 {
+				t.Error("Expected the status code to be recorded even on failure")
+			}
+		})
+	}
+}
+
+// TestHttpPingHead tests HEAD requests and the request timeout
+func TestHttpPingHead(t *testing.T) {
+	srv := newProbeServer()
+	defer srv.Close()
+
+	if _, err := HttpPing(HTTPProbeConfig{URL: srv.URL + "/ok", Method: "HEAD"}, "v4", 5*time.Second); err != nil {
+		t.Errorf("Expected HEAD to succeed, got %v", err)
+	}
+
+	_, err := HttpPing(HTTPProbeConfig{URL: srv.URL + "/slow", Method: "GET"}, "v4", 100*time.Millisecond)
+	if err == nil {
+		t.Fatal("Expected a timeout for a slow response")
+	}
+	if status := statusForError(err); status != "timeout" {
+		t.Errorf("Expected status timeout, got %q", status)
+	}
+}
+
+// TestHttpPingTLS tests that the TLS handshake phase is measured
+func TestHttpPingTLS(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	timings, err := HttpPing(HTTPProbeConfig{URL: srv.URL, Method: "GET", InsecureSkipVerify: true}, "v4", 5*time.Second)
+	if err != nil {
+		t.Fatalf("Expected success, got %v", err)
+	}
+	if timings.TLS == noPhase {
+		t.Error("Expected TLS handshake phase to be measured")
+	}
+}
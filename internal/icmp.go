@@ -0,0 +1,248 @@
+// internal/icmp.go
+// @version icmp
+// @description ICMP/ICMPv6 echo probes over unprivileged datagram sockets with a raw-socket fallback
+
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+// ICMP failure reasons. IcmpPing wraps these so callers can use errors.Is
+// to tell a lost reply apart from an explicit rejection by the network.
+var (
+	ErrICMPPermission  = errors.New("icmp: permission denied (need net.ipv4.ping_group_range or CAP_NET_RAW)")
+	ErrICMPUnreachable = errors.New("icmp: destination unreachable")
+	ErrICMPTTLExceeded = errors.New("icmp: ttl exceeded in transit")
+	ErrICMPTimeout     = errors.New("icmp: timed out waiting for echo reply")
+)
+
+const icmpPayloadLen = 32
+
+// icmpSeq is shared by all probes so concurrent pings never reuse a sequence number.
+var icmpSeq atomic.Uint32
+
+// icmpProto describes the per-family differences between ICMP and ICMPv6.
+type icmpProto struct {
+	family       string // "v4" or "v6"
+	resolve      string // network passed to net.ResolveIPAddr
+	rawNetwork   string // network passed to icmp.ListenPacket for the raw fallback
+	rawAddress   string
+	protocol     int // IANA protocol number, used by icmp.ParseMessage
+	echoRequest  icmp.Type
+	echoReply    icmp.Type
+	echoType     int // echoRequest as it appears on the wire
+	unreachable  int
+	timeExceeded int
+}
+
+var (
+	icmpV4 = icmpProto{
+		family:       "v4",
+		resolve:      "ip4",
+		rawNetwork:   "ip4:icmp",
+		rawAddress:   "0.0.0.0",
+		protocol:     1,
+		echoRequest:  ipv4.ICMPTypeEcho,
+		echoReply:    ipv4.ICMPTypeEchoReply,
+		echoType:     int(ipv4.ICMPTypeEcho),
+		unreachable:  int(ipv4.ICMPTypeDestinationUnreachable),
+		timeExceeded: int(ipv4.ICMPTypeTimeExceeded),
+	}
+	icmpV6 = icmpProto{
+		family:       "v6",
+		resolve:      "ip6",
+		rawNetwork:   "ip6:ipv6-icmp",
+		rawAddress:   "::",
+		protocol:     58,
+		echoRequest:  ipv6.ICMPTypeEchoRequest,
+		echoReply:    ipv6.ICMPTypeEchoReply,
+		echoType:     int(ipv6.ICMPTypeEchoRequest),
+		unreachable:  int(ipv6.ICMPTypeDestinationUnreachable),
+		timeExceeded: int(ipv6.ICMPTypeTimeExceeded),
+	}
+)
+
+// icmpSocket is the small surface IcmpPing needs from either socket flavour.
+type icmpSocket interface {
+	// send writes a marshalled ICMP message to the target.
+	send(msg []byte) error
+	// recv reads the next ICMP message (without IP header) and the address it came from.
+	recv(buf []byte) (int, net.Addr, error)
+	// id is the echo identifier replies will carry.
+	id() int
+	SetReadDeadline(t time.Time) error
+	Close() error
+}
+
+// IcmpPing sends a single ICMP echo request to target over the given address
+// family ("v4" or "v6") and waits up to timeout for the matching reply. It
+// first tries an unprivileged datagram socket (Linux: net.ipv4.ping_group_range)
+// and falls back to a raw socket, which needs CAP_NET_RAW or root.
+func IcmpPing(target string, family string, timeout time.Duration) (time.Duration, error) {
+	proto := icmpV4
+	if family == "v6" {
+		proto = icmpV6
+	}
+
+	addr, err := net.ResolveIPAddr(proto.resolve, target)
+	if err != nil {
+		return 0, err
+	}
+
+	sock, err := openICMPSocket(proto, addr)
+	if err != nil {
+		return 0, err
+	}
+	defer sock.Close()
+
+	seq := int(icmpSeq.Add(1) & 0xffff)
+	payload := make([]byte, icmpPayloadLen)
+	copy(payload, "pingmonke")
+
+	// The kernel fills in the ICMPv6 checksum, so no pseudo-header is needed.
+	msg := icmp.Message{
+		Type: proto.echoRequest,
+		Body: &icmp.Echo{ID: sock.id(), Seq: seq, Data: payload},
+	}
+	wire, err := msg.Marshal(nil)
+	if err != nil {
+		return 0, err
+	}
+
+	deadline := time.Now().Add(timeout)
+	if err := sock.SetReadDeadline(deadline); err != nil {
+		return 0, err
+	}
+
+	start := time.Now()
+	if err := sock.send(wire); err != nil {
+		return 0, classifyICMPSocketError(err)
+	}
+
+	buf := make([]byte, 1500)
+	for {
+		n, peer, err := sock.recv(buf)
+		if err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				return 0, fmt.Errorf("%w after %v", ErrICMPTimeout, timeout)
+			}
+			return 0, classifyICMPSocketError(err)
+		}
+
+		reply, err := icmp.ParseMessage(proto.protocol, buf[:n])
+		if err != nil {
+			continue
+		}
+
+		switch body := reply.Body.(type) {
+		case *icmp.Echo:
+			if reply.Type != proto.echoReply || body.ID != sock.id() || body.Seq != seq {
+				continue
+			}
+			return time.Since(start), nil
+		case *icmp.DstUnreach:
+			if proto.matchesEcho(body.Data, sock.id(), seq) {
+				return 0, fmt.Errorf("%w: %s reported code %d", ErrICMPUnreachable, peer, reply.Code)
+			}
+		case *icmp.TimeExceeded:
+			if proto.matchesEcho(body.Data, sock.id(), seq) {
+				return 0, fmt.Errorf("%w: reported by %s", ErrICMPTTLExceeded, peer)
+			}
+		}
+	}
+}
+
+// openICMPSocket prefers the unprivileged datagram socket and only falls back
+// to a raw socket when the kernel refuses the former.
+func openICMPSocket(proto icmpProto, dst *net.IPAddr) (icmpSocket, error) {
+	sock, dgramErr := openICMPDatagram(proto, dst)
+	if dgramErr == nil {
+		return sock, nil
+	}
+
+	conn, rawErr := icmp.ListenPacket(proto.rawNetwork, proto.rawAddress)
+	if rawErr != nil {
+		if os.IsPermission(rawErr) || os.IsPermission(dgramErr) {
+			return nil, ErrICMPPermission
+		}
+		return nil, fmt.Errorf("icmp: datagram socket: %v; raw socket: %v", dgramErr, rawErr)
+	}
+	return &rawICMPSocket{conn: conn, dst: dst, ident: rawICMPIdent()}, nil
+}
+
+// rawICMPSocket sees every ICMP packet delivered to the host, so it filters
+// replies by identifier and sequence and can inspect error messages directly.
+type rawICMPSocket struct {
+	conn  *icmp.PacketConn
+	dst   *net.IPAddr
+	ident int
+}
+
+func (s *rawICMPSocket) send(msg []byte) error {
+	_, err := s.conn.WriteTo(msg, s.dst)
+	return err
+}
+
+func (s *rawICMPSocket) recv(buf []byte) (int, net.Addr, error) {
+	return s.conn.ReadFrom(buf)
+}
+
+func (s *rawICMPSocket) id() int                           { return s.ident }
+func (s *rawICMPSocket) SetReadDeadline(t time.Time) error { return s.conn.SetReadDeadline(t) }
+func (s *rawICMPSocket) Close() error                      { return s.conn.Close() }
+
+// rawICMPIdent derives an echo identifier for raw sockets. The kernel assigns
+// identifiers for datagram sockets, but raw sockets must pick their own.
+func rawICMPIdent() int {
+	return (os.Getpid() ^ int(icmpSeq.Add(1)<<8)) & 0xffff
+}
+
+// matchesEcho reports whether the quoted datagram inside an ICMP error message
+// is the echo request we sent: the original IP header followed by the first
+// 8 bytes of our echo request.
+func (p icmpProto) matchesEcho(quoted []byte, id, seq int) bool {
+	var hdrLen int
+	if p.family == "v6" {
+		hdrLen = ipv6.HeaderLen // extension headers are not expected on echo requests
+	} else {
+		if len(quoted) < ipv4.HeaderLen {
+			return false
+		}
+		hdrLen = int(quoted[0]&0x0f) * 4
+	}
+	if len(quoted) < hdrLen+8 {
+		return false
+	}
+	echo := quoted[hdrLen:]
+	if int(echo[0]) != p.echoType {
+		return false
+	}
+	gotID := int(echo[4])<<8 | int(echo[5])
+	gotSeq := int(echo[6])<<8 | int(echo[7])
+	return gotID == id && gotSeq == seq
+}
+
+// classifyICMPSocketError maps socket-level failures onto the ErrICMP* reasons.
+func classifyICMPSocketError(err error) error {
+	switch {
+	case errors.Is(err, ErrICMPUnreachable), errors.Is(err, ErrICMPTTLExceeded), errors.Is(err, ErrICMPPermission):
+		return err
+	case errors.Is(err, syscall.EACCES), errors.Is(err, syscall.EPERM):
+		return fmt.Errorf("%w: %v", ErrICMPPermission, err)
+	case errors.Is(err, syscall.EHOSTUNREACH), errors.Is(err, syscall.ENETUNREACH):
+		return fmt.Errorf("%w: %v", ErrICMPUnreachable, err)
+	}
+	return err
+}
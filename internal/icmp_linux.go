@@ -0,0 +1,197 @@
+//go:build linux
+
+// internal/icmp_linux.go
+// @version icmp
+// @description Unprivileged ICMP datagram sockets (ping_group_range) with error-queue decoding
+
+package internal
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+	"time"
+)
+
+// SO_EE_ORIGIN_ICMP and SO_EE_ORIGIN_ICMP6 from <linux/errqueue.h>.
+const (
+	soEEOriginICMP  = 2
+	soEEOriginICMP6 = 3
+)
+
+// datagramICMPSocket is a connected SOCK_DGRAM/IPPROTO_ICMP socket. The kernel
+// owns the echo identifier (the socket's local "port") and only delivers
+// replies addressed to it, so no filtering of foreign traffic is needed.
+// ICMP errors are not delivered as messages; with IP_RECVERR set they surface
+// as a socket error plus an entry on the error queue, which recv decodes.
+type datagramICMPSocket struct {
+	conn  *net.UDPConn
+	proto icmpProto
+	ident int
+}
+
+// openICMPDatagram creates the unprivileged socket. It fails with EACCES when
+// the process's group is outside net.ipv4.ping_group_range (which also
+// governs ICMPv6 datagram sockets).
+func openICMPDatagram(proto icmpProto, dst *net.IPAddr) (icmpSocket, error) {
+	domain, protocol := syscall.AF_INET, syscall.IPPROTO_ICMP
+	level, recvErr := syscall.IPPROTO_IP, syscall.IP_RECVERR
+	var sa syscall.Sockaddr
+	if proto.family == "v6" {
+		domain, protocol = syscall.AF_INET6, syscall.IPPROTO_ICMPV6
+		level, recvErr = syscall.IPPROTO_IPV6, syscall.IPV6_RECVERR
+		sa6 := &syscall.SockaddrInet6{}
+		copy(sa6.Addr[:], dst.IP.To16())
+		if dst.Zone != "" {
+			if ifi, err := net.InterfaceByName(dst.Zone); err == nil {
+				sa6.ZoneId = uint32(ifi.Index)
+			}
+		}
+		sa = sa6
+	} else {
+		sa4 := &syscall.SockaddrInet4{}
+		copy(sa4.Addr[:], dst.IP.To4())
+		sa = sa4
+	}
+
+	fd, err := syscall.Socket(domain, syscall.SOCK_DGRAM|syscall.SOCK_CLOEXEC, protocol)
+	if err != nil {
+		return nil, os.NewSyscallError("socket", err)
+	}
+
+	if err := syscall.SetsockoptInt(fd, level, recvErr, 1); err != nil {
+		syscall.Close(fd)
+		return nil, os.NewSyscallError("setsockopt", err)
+	}
+
+	if err := syscall.Connect(fd, sa); err != nil {
+		syscall.Close(fd)
+		return nil, os.NewSyscallError("connect", err)
+	}
+
+	// net.FileConn dups the descriptor, so the original is closed either way.
+	f := os.NewFile(uintptr(fd), "icmp")
+	c, err := net.FileConn(f)
+	f.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	conn, ok := c.(*net.UDPConn)
+	if !ok {
+		c.Close()
+		return nil, fmt.Errorf("icmp: unexpected connection type %T", c)
+	}
+
+	local, _ := conn.LocalAddr().(*net.UDPAddr)
+	if local == nil {
+		conn.Close()
+		return nil, errors.New("icmp: datagram socket has no local identifier")
+	}
+
+	return &datagramICMPSocket{conn: conn, proto: proto, ident: local.Port}, nil
+}
+
+func (s *datagramICMPSocket) send(msg []byte) error {
+	_, err := s.conn.Write(msg)
+	return err
+}
+
+func (s *datagramICMPSocket) recv(buf []byte) (int, net.Addr, error) {
+	n, err := s.conn.Read(buf)
+	if err != nil {
+		var errno syscall.Errno
+		if errors.As(err, &errno) {
+			if icmpErr := s.readErrorQueue(); icmpErr != nil {
+				return 0, nil, icmpErr
+			}
+		}
+		return 0, nil, err
+	}
+	return n, s.conn.RemoteAddr(), nil
+}
+
+func (s *datagramICMPSocket) id() int                           { return s.ident }
+func (s *datagramICMPSocket) SetReadDeadline(t time.Time) error { return s.conn.SetReadDeadline(t) }
+func (s *datagramICMPSocket) Close() error                      { return s.conn.Close() }
+
+// readErrorQueue pulls one entry off the socket's error queue and converts the
+// embedded ICMP type into one of the ErrICMP* reasons. It returns nil when the
+// queue is empty or holds something other than an ICMP error.
+func (s *datagramICMPSocket) readErrorQueue() error {
+	raw, err := s.conn.SyscallConn()
+	if err != nil {
+		return nil
+	}
+
+	var (
+		oob     = make([]byte, 512)
+		oobn    int
+		rerr    error
+		scratch = make([]byte, 1280)
+	)
+	raw.Read(func(fd uintptr) bool {
+		_, oobn, _, _, rerr = syscall.Recvmsg(int(fd), scratch, oob, syscall.MSG_ERRQUEUE|syscall.MSG_DONTWAIT)
+		return true
+	})
+	if rerr != nil || oobn == 0 {
+		return nil
+	}
+
+	msgs, err := syscall.ParseSocketControlMessage(oob[:oobn])
+	if err != nil {
+		return nil
+	}
+
+	level, recvErr, origin := syscall.IPPROTO_IP, syscall.IP_RECVERR, byte(soEEOriginICMP)
+	if s.proto.family == "v6" {
+		level, recvErr, origin = syscall.IPPROTO_IPV6, syscall.IPV6_RECVERR, soEEOriginICMP6
+	}
+
+	for _, m := range msgs {
+		if m.Header.Level != int32(level) || m.Header.Type != int32(recvErr) {
+			continue
+		}
+		// struct sock_extended_err: errno u32, origin u8, type u8, code u8, pad u8, info u32, data u32
+		if len(m.Data) < 8 || m.Data[4] != origin {
+			continue
+		}
+		errno := syscall.Errno(binary.NativeEndian.Uint32(m.Data[0:4]))
+		icmpType, icmpCode := int(m.Data[5]), m.Data[6]
+		reporter := icmpOffender(m.Data)
+
+		switch icmpType {
+		case s.proto.timeExceeded:
+			return fmt.Errorf("%w: reported by %s", ErrICMPTTLExceeded, reporter)
+		case s.proto.unreachable:
+			return fmt.Errorf("%w: %s reported code %d", ErrICMPUnreachable, reporter, icmpCode)
+		default:
+			return fmt.Errorf("icmp: type %d from %s: %w", icmpType, reporter, errno)
+		}
+	}
+	return nil
+}
+
+// icmpOffender returns the address of the router that generated the error.
+// The kernel appends it as a sockaddr_in/sockaddr_in6 right after
+// sock_extended_err.
+func icmpOffender(data []byte) string {
+	const eeLen = 16
+	if len(data) < eeLen+2 {
+		return "unknown"
+	}
+	switch binary.NativeEndian.Uint16(data[eeLen : eeLen+2]) {
+	case syscall.AF_INET:
+		if len(data) >= eeLen+8 {
+			return net.IP(data[eeLen+4 : eeLen+8]).String()
+		}
+	case syscall.AF_INET6:
+		if len(data) >= eeLen+24 {
+			return net.IP(data[eeLen+8 : eeLen+24]).String()
+		}
+	}
+	return "unknown"
+}
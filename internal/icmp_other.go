@@ -0,0 +1,52 @@
+//go:build !linux
+
+// internal/icmp_other.go
+// @version icmp
+// @description Unprivileged ICMP datagram sockets on non-Linux platforms
+
+package internal
+
+import (
+	"net"
+	"time"
+
+	"golang.org/x/net/icmp"
+)
+
+// datagramICMPSocket wraps x/net's "udp4"/"udp6" ICMP endpoints (macOS supports these
+// without privileges). Unlike Linux, ICMP errors are not reported back on
+// this socket, so unreachable hosts surface as timeouts here.
+type datagramICMPSocket struct {
+	conn  *icmp.PacketConn
+	dst   *net.UDPAddr
+	ident int
+}
+
+func openICMPDatagram(proto icmpProto, dst *net.IPAddr) (icmpSocket, error) {
+	network, address := "udp4", "0.0.0.0"
+	if proto.family == "v6" {
+		network, address = "udp6", "::"
+	}
+	conn, err := icmp.ListenPacket(network, address)
+	if err != nil {
+		return nil, err
+	}
+	ident := rawICMPIdent()
+	if local, ok := conn.LocalAddr().(*net.UDPAddr); ok && local.Port != 0 {
+		ident = local.Port
+	}
+	return &datagramICMPSocket{conn: conn, dst: &net.UDPAddr{IP: dst.IP, Zone: dst.Zone}, ident: ident}, nil
+}
+
+func (s *datagramICMPSocket) send(msg []byte) error {
+	_, err := s.conn.WriteTo(msg, s.dst)
+	return err
+}
+
+func (s *datagramICMPSocket) recv(buf []byte) (int, net.Addr, error) {
+	return s.conn.ReadFrom(buf)
+}
+
+func (s *datagramICMPSocket) id() int                           { return s.ident }
+func (s *datagramICMPSocket) SetReadDeadline(t time.Time) error { return s.conn.SetReadDeadline(t) }
+func (s *datagramICMPSocket) Close() error                      { return s.conn.Close() }
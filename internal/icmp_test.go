@@ -0,0 +1,100 @@
+package internal
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+// TestIcmpPingLoopback sends a real echo request to 127.0.0.1
+func TestIcmpPingLoopback(t *testing.T) {
+	latency, err := IcmpPing("127.0.0.1", "v4", 2*time.Second)
+	if errors.Is(err, ErrICMPPermission) {
+		t.Skip("ICMP sockets not permitted here (ping_group_range/CAP_NET_RAW):", err)
+	}
+	if err != nil {
+		t.Fatalf("Expected echo reply from loopback, got error: %v", err)
+	}
+	if latency <= 0 || latency > 2*time.Second {
+		t.Errorf("Expected latency within (0, 2s], got %v", latency)
+	}
+}
+
+// TestIcmpPingSequential makes sure back-to-back pings each match their own reply
+func TestIcmpPingSequential(t *testing.T) {
+	for i := 0; i < 3; i++ {
+		_, err := IcmpPing("127.0.0.1", "v4", 2*time.Second)
+		if errors.Is(err, ErrICMPPermission) {
+			t.Skip("ICMP sockets not permitted here:", err)
+		}
+		if err != nil {
+			t.Fatalf("Ping %d failed: %v", i, err)
+		}
+	}
+}
+
+// TestIcmpPingLoopbackV6 sends a real ICMPv6 echo request to ::1
+func TestIcmpPingLoopbackV6(t *testing.T) {
+	_, err := IcmpPing("::1", "v6", 2*time.Second)
+	if errors.Is(err, ErrICMPPermission) {
+		t.Skip("ICMPv6 sockets not permitted here:", err)
+	}
+	var netErr *net.OpError
+	if errors.As(err, &netErr) {
+		t.Skip("IPv6 not available here:", err)
+	}
+	if err != nil {
+		t.Fatalf("Expected echo reply from ::1, got error: %v", err)
+	}
+}
+
+// TestMatchesEcho tests matching of the quoted datagram in ICMP error messages
+func TestMatchesEcho(t *testing.T) {
+	// Minimal IPv4 header (IHL=5) followed by an echo request header id=0x1234 seq=7
+	quoted := make([]byte, 28)
+	quoted[0] = 0x45
+	quoted[20] = 8 // echo request
+	quoted[24], quoted[25] = 0x12, 0x34
+	quoted[26], quoted[27] = 0x00, 0x07
+
+	tests := []struct {
+		name    string
+		data    []byte
+		id, seq int
+		want    bool
+	}{
+		{name: "Matching id and seq", data: quoted, id: 0x1234, seq: 7, want: true},
+		{name: "Wrong seq", data: quoted, id: 0x1234, seq: 8, want: false},
+		{name: "Wrong id", data: quoted, id: 0x4321, seq: 7, want: false},
+		{name: "Truncated", data: quoted[:24], id: 0x1234, seq: 7, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := icmpV4.matchesEcho(tt.data, tt.id, tt.seq); got != tt.want {
+				t.Errorf("Expected matchesEcho to be %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+// TestStatusForError tests mapping of probe errors onto status strings
+func TestStatusForError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{ErrICMPUnreachable, "unreachable"},
+		{ErrICMPTTLExceeded, "ttl_exceeded"},
+		{ErrICMPPermission, "no_permission"},
+		{ErrICMPTimeout, "timeout"},
+		{errors.New("dial tcp: i/o timeout"), "timeout"},
+	}
+
+	for _, tt := range tests {
+		if got := statusForError(tt.err); got != tt.want {
+			t.Errorf("statusForError(%v) = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+// internal/jsonl.go
+// @version jsonl
+// @description JSON form of a probe result, used by JSON Lines logs and the network sink
+
+package internal
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// probeRecordSchema versions the JSON form of a probe result. Fields may be
+// added without bumping it; renames and removals bump it.
+const probeRecordSchema = 1
+
+// probeRecord is a probe result as one JSON object. Unlike a CSV row it
+// carries the target, probe type and error.
+type probeRecord struct {
+	Schema     int    `json:"schema"`
+	Target     string `json:"target"`
+	Host       string `json:"host,omitempty"`
+	Probe      string `json:"probe,omitempty"`
+	StartTime  string `json:"start_time"`
+	EndTime    string `json:"end_time"`
+	LatencyMs  int64  `json:"latency_ms"`
+	Status     string `json:"status"`
+	Family     string `json:"family,omitempty"`
+	DNSMs      *int64 `json:"dns_ms,omitempty"`
+	ConnectMs  *int64 `json:"connect_ms,omitempty"`
+	TLSMs      *int64 `json:"tls_ms,omitempty"`
+	TTFBMs     *int64 `json:"ttfb_ms,omitempty"`
+	HTTPStatus int    `json:"http_status,omitempty"`
+	Rcode      string `json:"rcode,omitempty"`
+	IP         string `json:"ip,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+func newProbeRecord(target TargetConfig, result ProbeResult) probeRecord {
+	rec := probeRecord{
+		Schema:     probeRecordSchema,
+		Target:     target.Label(),
+		Host:       target.Host,
+		Probe:      target.Probe,
+		StartTime:  result.StartTime.Format(time.RFC3339Nano),
+		EndTime:    result.EndTime.Format(time.RFC3339Nano),
+		LatencyMs:  result.Latency.Milliseconds(),
+		Status:     result.Status,
+		Family:     result.Family,
+		DNSMs:      phaseMs(result.DNS),
+		ConnectMs:  phaseMs(result.Connect),
+		TLSMs:      phaseMs(result.TLS),
+		TTFBMs:     phaseMs(result.TTFB),
+		HTTPStatus: result.HTTPStatus,
+		Rcode:      result.Rcode,
+		IP:         result.IP,
+	}
+	if result.Err != nil {
+		rec.Error = result.Err.Error()
+	}
+	return rec
+}
+
+// phaseMs is a phase timing in milliseconds, or nil if it wasn't measured.
+func phaseMs(d time.Duration) *int64 {
+	if d < 0 {
+		return nil
+	}
+	ms := d.Milliseconds()
+	return &ms
+}
+
+// row converts the record to the columns of pingCSVHeader, with timestamps
+// in the record's own zone like a CSV log.
+func (rec probeRecord) row() ([]string, error) {
+	start, err := time.Parse(time.RFC3339Nano, rec.StartTime)
+	if err != nil {
+		return nil, fmt.Errorf("start_time: %w", err)
+	}
+	end, err := time.Parse(time.RFC3339Nano, rec.EndTime)
+	if err != nil {
+		return nil, fmt.Errorf("end_time: %w", err)
+	}
+	return []string{
+		formatTimestamp(start),
+		formatTimestamp(end),
+		strconv.FormatInt(rec.LatencyMs, 10),
+		rec.Status,
+		rec.Family,
+		formatPhaseMs(rec.DNSMs),
+		formatPhaseMs(rec.ConnectMs),
+		formatPhaseMs(rec.TLSMs),
+		formatPhaseMs(rec.TTFBMs),
+		formatHTTPStatus(rec.HTTPStatus),
+		rec.Rcode,
+		rec.IP,
+	}, nil
+}
+
+func formatPhaseMs(ms *int64) string {
+	if ms == nil {
+		return ""
+	}
+	return strconv.FormatInt(*ms, 10)
+}
@@ -0,0 +1,80 @@
+package internal
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestProbeRecordRow tests that a JSON record reads back as the CSV row of the same result
+func TestProbeRecordRow(t *testing.T) {
+	loc := time.FixedZone("CST", -6*3600)
+	at := time.Date(2026, 1, 7, 14, 0, 0, 0, loc)
+	result := ProbeResult{
+		StartTime: at, EndTime: at.Add(42 * time.Millisecond), Latency: 42 * time.Millisecond,
+		Status: "ok", Family: "v6", DNS: 3 * time.Millisecond, Connect: 12 * time.Millisecond,
+		TLS: 20 * time.Millisecond, TTFB: 7 * time.Millisecond, HTTPStatus: 200, IP: "2001:db8::1",
+		Err: errors.New("ignored in CSV"),
+	}
+
+	data, err := json.Marshal(newProbeRecord(TargetConfig{Name: "web", Probe: "http"}, result))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rec probeRecord
+	if err := json.Unmarshal(data, &rec); err != nil {
+		t.Fatal(err)
+	}
+	row, err := rec.row()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := strings.Join(row, ","), strings.Join(pingRow(result), ","); got != want {
+		t.Errorf("Expected row %s, got %s", want, got)
+	}
+}
+
+// TestReadPingRowsJSONL tests reading a JSON Lines log, including a line still being written
+func TestReadPingRowsJSONL(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "2026-01-07-gateway-pings.jsonl")
+	content := `{"schema":1,"target":"gateway","start_time":"2026-01-07T14:00:00Z","end_time":"2026-01-07T14:00:00.01Z","latency_ms":10,"status":"ok","family":"v4"}
+{"schema":1,"target":"gateway","start_time":"2026-01-07T14:00:15Z","end_time":"2026-01-07T14:00:30Z","latency_ms":0,"status":"timeout","family":"v4","error":"i/o timeout"}
+
+{"schema":1,"target":"gateway","start_time":"2026-01-07T14:0`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	lines, err := ReadPingFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 complete lines, got %+v", lines)
+	}
+	if lines[1].StartTime != "2026-01-07 14:00:15.000" || lines[1].Status != "timeout" || lines[1].Family != "v4" {
+		t.Errorf("Unexpected line %+v", lines[1])
+	}
+	if total, ok, _, timeout, avg := GetSummaryStats(path); total != 2 || ok != 1 || timeout != 1 || avg != 10 {
+		t.Errorf("Unexpected stats total=%d ok=%d timeout=%d avg=%v", total, ok, timeout, avg)
+	}
+}
+
+// TestIsPingLogFile tests telling ping logs from summaries
+func TestIsPingLogFile(t *testing.T) {
+	for name, want := range map[string]bool{
+		"2026-01-07-gateway-pings.csv":         true,
+		"2026-01-07-gateway-pings.jsonl":       true,
+		"2026-01-07-gateway-pings.csv.gz":      true,
+		"2026-01-07-gateway-pings-summary.csv": false,
+		"notes.txt":                            false,
+	} {
+		if got := IsPingLogFile(name); got != want {
+			t.Errorf("IsPingLogFile(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
@@ -8,22 +8,33 @@ import (
 	"encoding/csv"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 	"time"
 )
 
-// prepareLogFile creates a new log file for the current period.
-func prepareLogFile(periodStart time.Time, cfg Config) string {
+// pingCSVHeader lists the columns of a ping log. Readers rely on the first
+// four columns keeping their positions; new columns are only ever appended.
+var pingCSVHeader = []string{
+	"Ping Init", "Ping Rec", "Ping Time (ms)", "Status", "AF",
+	"DNS (ms)", "Connect (ms)", "TLS (ms)", "TTFB (ms)", "HTTP Status", "Rcode", "IP",
+}
+
+// Log file formats, which are also their file extensions
+const (
+	logFormatCSV   = "csv"
+	logFormatJSONL = "jsonl"
+)
+
+// prepareLogFile creates a new log file in format for the current period and target.
+func prepareLogFile(periodStart time.Time, cfg Config, target TargetConfig, format string) string {
 	logDir := cfg.LogDir
 	if logDir == "" {
 		logDir = defaultLogDir() // fallback if env/config missing
 	}
 	logDir = ExpandHome(logDir) // expand ~ to home directory
-	var filename string
-	if cfg.DebugMode {
-		filename = fmt.Sprintf("%s/%s-pings.csv", logDir, periodStart.Format("15:04:05.000"))
-	} else {
-		filename = fmt.Sprintf("%s/%s-pings.csv", logDir, periodStart.Format("2006-01-02"))
-	}
+	stamp := periodStart.Format(cfg.periodSpec().layout)
+	filename := fmt.Sprintf("%s/%s%s", logDir, stamp, pingFileSuffix(target.Name, format))
 
 	// Ensure directory exists
 	err := os.MkdirAll(logDir, 0755)
@@ -39,7 +50,7 @@ func prepareLogFile(periodStart time.Time, cfg Config) string {
 		return filename
 	}
 
-	// File doesn't exist or is empty, create it with header
+	// File doesn't exist or is empty, create it (with a header for CSV)
 	file, err := os.Create(filename)
 	if err != nil {
 		fmt.Println("[Logging] Error creating log file:", err)
@@ -47,33 +58,93 @@ func prepareLogFile(periodStart time.Time, cfg Config) string {
 	}
 	defer file.Close()
 
-	writer := csv.NewWriter(file)
-	writer.Write([]string{"Ping Init", "Ping Rec", "Ping Time (ms)", "Status"})
-	writer.Flush()
+	if format == logFormatCSV {
+		writer := csv.NewWriter(file)
+		writer.Write(pingCSVHeader)
+		writer.Flush()
+	}
 
 	fmt.Println("[Logging] Log file ready:", filename)
 	return filename
 }
 
-// writeToCSV appends a ping result to the main log file.
-func writeToCSV(file string, start, end time.Time, latency time.Duration, status string) {
-	// Write to main CSV
-	f, err := os.OpenFile(file, os.O_APPEND|os.O_WRONLY, 0644)
-	if err != nil {
-		fmt.Println("[Logging] Error opening log file:", err)
-		return
+// pingRow formats a ping result as the columns of pingCSVHeader.
+func pingRow(result ProbeResult) []string {
+	return []string{
+		formatTimestamp(result.StartTime),
+		formatTimestamp(result.EndTime),
+		fmt.Sprintf("%d", result.Latency.Milliseconds()),
+		result.Status,
+		result.Family,
+		formatPhase(result.DNS),
+		formatPhase(result.Connect),
+		formatPhase(result.TLS),
+		formatPhase(result.TTFB),
+		formatHTTPStatus(result.HTTPStatus),
+		result.Rcode,
+		result.IP,
+	}
+}
+
+// formatPhase writes a phase timing in milliseconds, or blank if it wasn't measured.
+func formatPhase(d time.Duration) string {
+	if d < 0 {
+		return ""
+	}
+	return fmt.Sprintf("%d", d.Milliseconds())
+}
+
+// formatHTTPStatus writes the HTTP status code, or blank for other probe types.
+func formatHTTPStatus(code int) string {
+	if code == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%d", code)
+}
+
+// pingFileSuffix returns the part of a log file name that follows the period
+// stamp. The unnamed legacy target keeps the original "-pings.csv" names.
+func pingFileSuffix(targetName, format string) string {
+	if targetName == "" {
+		return "-pings." + format
 	}
-	defer f.Close()
-
-	writer := csv.NewWriter(f)
-	row := []string{
-		formatTimestamp(start),
-		formatTimestamp(end),
-		fmt.Sprintf("%d", latency.Milliseconds()),
-		status,
+	return "-" + targetName + "-pings." + format
+}
+
+// IsPingLogFile reports whether name is a ping log in either format, as
+// opposed to a summary or some other file in the log directory. Logs gzipped
+// by retention count too.
+func IsPingLogFile(name string) bool {
+	name = strings.TrimSuffix(name, gzipExt)
+	return strings.HasSuffix(name, "-pings.csv") || strings.HasSuffix(name, "-pings.jsonl")
+}
+
+// LogFileTarget extracts the target name from a log file name such as
+// "2026-01-07-gateway-pings.csv" or "2026-01-07T14-gateway-pings.jsonl".
+// Files of the unnamed target return "".
+func LogFileTarget(path string) string {
+	_, target := splitLogFileName(path)
+	return target
+}
+
+// splitLogFileName splits a log file name into its period stamp and target.
+func splitLogFileName(path string) (stamp, target string) {
+	name := strings.TrimSuffix(filepath.Base(path), gzipExt)
+	name = strings.TrimSuffix(strings.TrimSuffix(name, "-pings.csv"), "-pings.jsonl")
+	for _, layout := range periodStampLayouts {
+		if len(name) < len(layout) {
+			continue
+		}
+		if _, err := time.Parse(layout, name[:len(layout)]); err != nil {
+			continue
+		}
+		rest := name[len(layout):]
+		if rest != "" && rest[0] != '-' {
+			continue // longer stamp than this layout
+		}
+		return name[:len(layout)], strings.TrimPrefix(rest, "-")
 	}
-	writer.Write(row)
-	writer.Flush()
+	return "", ""
 }
 
 // formatTimestamp converts a time to the standardized format: YYYY-MM-DD HH:MM:SS.mmm
@@ -0,0 +1,142 @@
+// internal/metrics.go
+// @version metrics
+// @description Prometheus /metrics exporter over the live probe state, in the text exposition format
+
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MetricsHandler serves the live state in the Prometheus text format.
+func MetricsHandler(state *LiveState) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var b strings.Builder
+		state.writeMetrics(&b)
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		io.WriteString(w, b.String())
+	})
+}
+
+// StartMetricsServer serves /metrics on addr until ctx is cancelled. It
+// returns once the listener is bound, so address errors surface at startup.
+func StartMetricsServer(ctx context.Context, addr string, state *LiveState) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", MetricsHandler(state))
+	bound, err := startHTTPServer(ctx, addr, mux, "Metrics")
+	if err != nil {
+		return err
+	}
+	fmt.Printf("[Metrics] Serving Prometheus metrics on http://%s/metrics\n", bound)
+	return nil
+}
+
+// startHTTPServer binds addr and serves handler in the background until ctx
+// is cancelled. Serve errors are printed under tag.
+func startHTTPServer(ctx context.Context, addr string, handler http.Handler, tag string) (net.Addr, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	srv := &http.Server{Handler: handler, ReadHeaderTimeout: 10 * time.Second}
+
+	go func() {
+		if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fmt.Printf("[%s] Server error: %v\n", tag, err)
+		}
+	}()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+	}()
+	return ln.Addr(), nil
+}
+
+// writeMetrics renders every metric family, with targets in config order and
+// label values sorted, so scrapes are stable.
+func (s *LiveState) writeMetrics(w io.Writer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fmt.Fprintln(w, "# HELP pingmonke_probe_latency_seconds Latency of successful probes.")
+	fmt.Fprintln(w, "# TYPE pingmonke_probe_latency_seconds histogram")
+	for _, st := range s.targets {
+		for _, family := range sortedKeys(st.latency) {
+			h := st.latency[family]
+			labels := fmt.Sprintf("target=%s,family=%s", quoteLabel(st.target.Label()), quoteLabel(family))
+			var cumulative uint64
+			for i, bound := range latencyBuckets {
+				cumulative += h.counts[i]
+				fmt.Fprintf(w, "pingmonke_probe_latency_seconds_bucket{%s,le=%q} %d\n", labels, formatFloat(bound), cumulative)
+			}
+			fmt.Fprintf(w, "pingmonke_probe_latency_seconds_bucket{%s,le=\"+Inf\"} %d\n", labels, h.count)
+			fmt.Fprintf(w, "pingmonke_probe_latency_seconds_sum{%s} %s\n", labels, formatFloat(h.sum.Seconds()))
+			fmt.Fprintf(w, "pingmonke_probe_latency_seconds_count{%s} %d\n", labels, h.count)
+		}
+	}
+
+	fmt.Fprintln(w, "# HELP pingmonke_probes_total Probes completed, by result status.")
+	fmt.Fprintln(w, "# TYPE pingmonke_probes_total counter")
+	for _, st := range s.targets {
+		keys := make([]statusKey, 0, len(st.counts))
+		for key := range st.counts {
+			keys = append(keys, key)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			if keys[i].family != keys[j].family {
+				return keys[i].family < keys[j].family
+			}
+			return keys[i].status < keys[j].status
+		})
+		for _, key := range keys {
+			fmt.Fprintf(w, "pingmonke_probes_total{target=%s,family=%s,status=%s} %d\n",
+				quoteLabel(st.target.Label()), quoteLabel(key.family), quoteLabel(key.status), st.counts[key])
+		}
+	}
+
+	fmt.Fprintln(w, "# HELP pingmonke_event_active Whether a network event is in progress (1) or not (0).")
+	fmt.Fprintln(w, "# TYPE pingmonke_event_active gauge")
+	for _, st := range s.targets {
+		active := 0
+		if st.event.IsActive {
+			active = 1
+		}
+		fmt.Fprintf(w, "pingmonke_event_active{target=%s} %d\n", quoteLabel(st.target.Label()), active)
+	}
+
+	fmt.Fprintln(w, "# HELP pingmonke_event_duration_seconds Duration of the active event, or of the most recent one.")
+	fmt.Fprintln(w, "# TYPE pingmonke_event_duration_seconds gauge")
+	for _, st := range s.targets {
+		fmt.Fprintf(w, "pingmonke_event_duration_seconds{target=%s} %s\n", quoteLabel(st.target.Label()), formatFloat(st.event.Duration.Seconds()))
+	}
+}
+
+// quoteLabel escapes a label value as the exposition format requires.
+func quoteLabel(v string) string {
+	v = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(v)
+	return `"` + v + `"`
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
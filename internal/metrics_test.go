@@ -0,0 +1,113 @@
+package internal
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// recordPing feeds one result into state as spawnPing would
+func recordPing(state *LiveState, target TargetConfig, at time.Time, family, status string, latency time.Duration) {
+	state.Record(target, ProbeResult{
+		StartTime: at,
+		EndTime:   at.Add(latency),
+		Latency:   latency,
+		Status:    status,
+		Family:    family,
+		DNS:       noPhase,
+		Connect:   noPhase,
+		TLS:       noPhase,
+		TTFB:      noPhase,
+	})
+}
+
+// TestMetricsHandler tests scraping the exporter after a few probes
+func TestMetricsHandler(t *testing.T) {
+	gateway := TargetConfig{Name: "gateway", Thresholds: DefaultThresholds}
+	web := TargetConfig{Name: "web", Thresholds: DefaultThresholds}
+	state := NewLiveState([]TargetConfig{gateway, web})
+
+	base := time.Date(2026, 1, 7, 14, 0, 0, 0, time.UTC)
+	recordPing(state, gateway, base, "v4", "ok", 3*time.Millisecond)
+	recordPing(state, gateway, base.Add(15*time.Second), "v4", "ok", 40*time.Millisecond)
+	recordPing(state, gateway, base.Add(30*time.Second), "v4", "timeout", 0)
+
+	rec := httptest.NewRecorder()
+	MetricsHandler(state).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("Unexpected content type %q", ct)
+	}
+
+	body := rec.Body.String()
+	for _, want := range []string{
+		"# TYPE pingmonke_probe_latency_seconds histogram",
+		`pingmonke_probe_latency_seconds_bucket{target="gateway",family="v4",le="0.005"} 1`,
+		`pingmonke_probe_latency_seconds_bucket{target="gateway",family="v4",le="0.05"} 2`,
+		`pingmonke_probe_latency_seconds_bucket{target="gateway",family="v4",le="+Inf"} 2`,
+		`pingmonke_probe_latency_seconds_sum{target="gateway",family="v4"} 0.043`,
+		`pingmonke_probe_latency_seconds_count{target="gateway",family="v4"} 2`,
+		"# TYPE pingmonke_probes_total counter",
+		`pingmonke_probes_total{target="gateway",family="v4",status="ok"} 2`,
+		`pingmonke_probes_total{target="gateway",family="v4",status="timeout"} 1`,
+		`pingmonke_event_active{target="gateway"} 0`,
+		`pingmonke_event_active{target="web"} 0`,
+		`pingmonke_event_duration_seconds{target="web"} 0`,
+	} {
+		if !strings.Contains(body, want+"\n") {
+			t.Errorf("Expected scrape to contain %q, got:\n%s", want, body)
+		}
+	}
+}
+
+// TestLiveStateEvent tests that recorded results drive the event gauges
+func TestLiveStateEvent(t *testing.T) {
+	target := TargetConfig{Name: "isp", Thresholds: DefaultThresholds}
+	state := NewLiveState([]TargetConfig{target})
+
+	base := time.Date(2026, 1, 7, 14, 0, 0, 0, time.UTC)
+	recordPing(state, target, base, "v4", "ok", 10*time.Millisecond)
+	recordPing(state, target, base.Add(15*time.Second), "v4", "timeout", 0)
+	recordPing(state, target, base.Add(30*time.Second), "v4", "timeout", 0)
+	recordPing(state, target, base.Add(45*time.Second), "v4", "delayed", 300*time.Millisecond)
+
+	var b strings.Builder
+	state.writeMetrics(&b)
+	if !strings.Contains(b.String(), `pingmonke_event_active{target="isp"} 1`) {
+		t.Errorf("Expected an active event, got:\n%s", b.String())
+	}
+	if !strings.Contains(b.String(), `pingmonke_event_duration_seconds{target="isp"} 30`) {
+		t.Errorf("Expected a 30s event, got:\n%s", b.String())
+	}
+}
+
+// TestLiveStateHistoryLimit tests that only the most recent pings are kept
+func TestLiveStateHistoryLimit(t *testing.T) {
+	target := TargetConfig{Name: "gateway", Thresholds: DefaultThresholds}
+	state := NewLiveState([]TargetConfig{target})
+
+	base := time.Date(2026, 1, 7, 14, 0, 0, 0, time.UTC)
+	for i := 0; i < liveHistory+10; i++ {
+		recordPing(state, target, base.Add(time.Duration(i)*time.Second), "v4", "ok", time.Millisecond)
+	}
+	st := state.byName["gateway"]
+	if len(st.recent) != liveHistory {
+		t.Fatalf("Expected %d recent pings, got %d", liveHistory, len(st.recent))
+	}
+	if want := formatTimestamp(base.Add(10 * time.Second)); st.recent[0].StartTime != want {
+		t.Errorf("Expected oldest ping at %s, got %s", want, st.recent[0].StartTime)
+	}
+	if got := st.counts[statusKey{"v4", "ok"}]; got != uint64(liveHistory+10) {
+		t.Errorf("Expected counters to cover every ping, got %d", got)
+	}
+}
+
+func TestQuoteLabel(t *testing.T) {
+	if got := quoteLabel("a\"b\\c\nd"); got != `"a\"b\\c\nd"` {
+		t.Errorf("Unexpected escaping: %s", got)
+	}
+}
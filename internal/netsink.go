@@ -0,0 +1,97 @@
+// internal/netsink.go
+// @version sinks
+// @description Network result sink: probe results as JSON lines over TCP or UDP
+
+package internal
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"time"
+)
+
+// netRedialDelay is how long a network sink waits before reconnecting after
+// a failure; results in the meantime are dropped.
+const netRedialDelay = 10 * time.Second
+
+// netSink streams results to a collector as newline-delimited JSON. Over UDP
+// each result is its own datagram.
+type netSink struct {
+	network string
+	address string
+	conn    net.Conn
+	w       *bufio.Writer
+	retryAt time.Time
+}
+
+func newNetSink(network, address string) *netSink {
+	return &netSink{network: network, address: address}
+}
+
+func (s *netSink) Name() string { return s.network + "://" + s.address }
+
+func (s *netSink) OpenPeriod(time.Time, []TargetConfig) error { return nil }
+
+func (s *netSink) Write(target TargetConfig, result ProbeResult) error {
+	if s.conn == nil {
+		if time.Now().Before(s.retryAt) {
+			return fmt.Errorf("not connected, retrying at %s", s.retryAt.Format("15:04:05"))
+		}
+		if err := s.dial(); err != nil {
+			return err
+		}
+	}
+
+	line, err := json.Marshal(newProbeRecord(target, result))
+	if err != nil {
+		return err
+	}
+	s.w.Write(append(line, '\n'))
+	if s.network == "udp" {
+		return s.Flush()
+	}
+	return nil
+}
+
+func (s *netSink) Flush() error {
+	if s.conn == nil {
+		return nil
+	}
+	s.conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+	if err := s.w.Flush(); err != nil {
+		s.drop()
+		return err
+	}
+	return nil
+}
+
+func (s *netSink) ClosePeriod() error { return s.Flush() }
+
+func (s *netSink) Close() error {
+	err := s.Flush()
+	if s.conn != nil {
+		s.conn.Close()
+		s.conn = nil
+	}
+	return err
+}
+
+func (s *netSink) dial() error {
+	conn, err := net.DialTimeout(s.network, s.address, 5*time.Second)
+	if err != nil {
+		s.retryAt = time.Now().Add(netRedialDelay)
+		return err
+	}
+	s.conn = conn
+	s.w = bufio.NewWriterSize(conn, 64<<10)
+	return nil
+}
+
+// drop closes a broken connection; the next write after netRedialDelay redials.
+func (s *netSink) drop() {
+	s.conn.Close()
+	s.conn = nil
+	s.retryAt = time.Now().Add(netRedialDelay)
+}
@@ -0,0 +1,186 @@
+// internal/period.go
+// @version periods
+// @description Period length settings: fixed durations or cron-like boundaries, and their file name stamps
+
+package internal
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// periodSpec describes where periods start, as the wall-clock minutes, hours
+// and weekdays of their boundaries (the fields of a cron line).
+type periodSpec struct {
+	minutes  [60]bool
+	hours    [24]bool
+	weekdays [7]bool // Sunday = 0
+	layout   string  // file name stamp of a period start
+}
+
+// periodStampLayouts lists every stamp a log file name can start with,
+// longest first so a shorter layout never matches a longer stamp's prefix.
+var periodStampLayouts = []string{"2006-01-02T1504", "2006-01-02T15", "2006-01-02", "15:04:05.000"}
+
+var (
+	dailyPeriod = mustParsePeriod("24h")
+	debugPeriod = func() periodSpec {
+		p := mustParsePeriod("1m")
+		p.layout = "15:04:05.000" // debug files keep their original names
+		return p
+	}()
+)
+
+// maxPeriodScan bounds the search for a boundary: a week plus a DST shift.
+const maxPeriodScan = 8 * 24 * 60
+
+// parsePeriod reads the period setting: hourly, daily, weekly, a duration that
+// divides a day evenly (e.g. 1h, 6h, 24h), 168h, or a cron-like spec
+// "minute hour * * weekday" such as "0 */6 * * *".
+func parsePeriod(s string) (periodSpec, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "daily":
+		s = "24h"
+	case "hourly":
+		s = "1h"
+	case "weekly":
+		s = "168h"
+	}
+
+	if d, err := time.ParseDuration(s); err == nil {
+		switch {
+		case d == 168*time.Hour:
+			s = "0 0 * * 1" // weeks start on Monday
+		case d >= time.Minute && d < time.Hour && d%time.Minute == 0 && time.Hour%d == 0:
+			s = fmt.Sprintf("*/%d * * * *", int(d.Minutes()))
+		case d >= time.Hour && d%time.Hour == 0 && (24*time.Hour)%d == 0:
+			s = fmt.Sprintf("0 */%d * * *", int(d.Hours()))
+		default:
+			return periodSpec{}, fmt.Errorf("period %v must divide a day evenly or be 168h", d)
+		}
+	}
+
+	fields := strings.Fields(s)
+	if len(fields) != 5 {
+		return periodSpec{}, fmt.Errorf("period %q is neither a duration nor a 5-field cron spec", s)
+	}
+	if fields[2] != "*" || fields[3] != "*" {
+		return periodSpec{}, fmt.Errorf("period %q: day-of-month and month fields must be *", s)
+	}
+
+	var p periodSpec
+	if err := parseCronField(fields[0], p.minutes[:], 0); err != nil {
+		return p, fmt.Errorf("period %q minute: %w", s, err)
+	}
+	if err := parseCronField(fields[1], p.hours[:], 0); err != nil {
+		return p, fmt.Errorf("period %q hour: %w", s, err)
+	}
+	weekdays := make([]bool, 8) // cron allows 7 for Sunday
+	if err := parseCronField(fields[4], weekdays, 0); err != nil {
+		return p, fmt.Errorf("period %q weekday: %w", s, err)
+	}
+	copy(p.weekdays[:], weekdays)
+	p.weekdays[0] = p.weekdays[0] || weekdays[7]
+
+	p.layout = "2006-01-02T1504"
+	if only(p.minutes[:], 0) {
+		p.layout = "2006-01-02T15"
+		if only(p.hours[:], 0) {
+			p.layout = "2006-01-02"
+		}
+	}
+	return p, nil
+}
+
+// parseCronField marks the values a cron field selects: *, */n, a, a-b,
+// a-b/n, or a comma-separated list of those.
+func parseCronField(field string, set []bool, min int) error {
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepPart)
+			if err != nil || n <= 0 {
+				return fmt.Errorf("bad step %q", stepPart)
+			}
+			step = n
+		}
+
+		lo, hi := min, len(set)-1
+		if rangePart != "*" {
+			from, to, isRange := strings.Cut(rangePart, "-")
+			var err error
+			if lo, err = strconv.Atoi(from); err != nil {
+				return fmt.Errorf("bad value %q", from)
+			}
+			hi = lo
+			if isRange {
+				if hi, err = strconv.Atoi(to); err != nil {
+					return fmt.Errorf("bad value %q", to)
+				}
+			} else if hasStep {
+				hi = len(set) - 1
+			}
+		}
+		if lo < min || hi >= len(set) || lo > hi {
+			return fmt.Errorf("%q is out of range %d-%d", part, min, len(set)-1)
+		}
+		for v := lo; v <= hi; v += step {
+			set[v] = true
+		}
+	}
+	return nil
+}
+
+func mustParsePeriod(s string) periodSpec {
+	p, err := parsePeriod(s)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+// only reports whether v is the single value selected in set.
+func only(set []bool, v int) bool {
+	for i, on := range set {
+		if on != (i == v) {
+			return false
+		}
+	}
+	return true
+}
+
+// matches reports whether a period starts at the wall-clock minute of t.
+func (p periodSpec) matches(t time.Time) bool {
+	return p.minutes[t.Minute()] && p.hours[t.Hour()] && p.weekdays[t.Weekday()]
+}
+
+// bounds returns the period containing now, found by walking minute by
+// minute to the surrounding boundaries in loc. Because boundaries are wall
+// clock times, periods stretch or shrink across DST changes.
+func (p periodSpec) bounds(now time.Time, loc *time.Location) (time.Time, time.Time) {
+	now = now.In(loc)
+	start := time.Date(now.Year(), now.Month(), now.Day(), now.Hour(), now.Minute(), 0, 0, loc)
+	for i := 0; i < maxPeriodScan && !p.matches(start); i++ {
+		start = start.Add(-time.Minute)
+	}
+	end := start.Add(time.Minute)
+	for i := 0; i < maxPeriodScan && !p.matches(end); i++ {
+		end = end.Add(time.Minute)
+	}
+	return start, end
+}
+
+// periodSpec returns the period in effect: one-minute periods in debug mode,
+// otherwise the parsed period setting.
+func (cfg Config) periodSpec() periodSpec {
+	switch {
+	case cfg.DebugMode:
+		return debugPeriod
+	case cfg.period.layout == "":
+		return dailyPeriod
+	}
+	return cfg.period
+}
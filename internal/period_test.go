@@ -0,0 +1,98 @@
+package internal
+
+import (
+	"testing"
+	"time"
+)
+
+// TestParsePeriod tests durations, aliases and cron specs, and their file name stamps
+func TestParsePeriod(t *testing.T) {
+	tests := []struct {
+		period string
+		layout string
+	}{
+		{"24h", "2006-01-02"},
+		{"daily", "2006-01-02"},
+		{"", "2006-01-02"},
+		{"168h", "2006-01-02"},
+		{"weekly", "2006-01-02"},
+		{"1h", "2006-01-02T15"},
+		{"hourly", "2006-01-02T15"},
+		{"6h", "2006-01-02T15"},
+		{"15m", "2006-01-02T1504"},
+		{"0 */6 * * *", "2006-01-02T15"},
+		{"0 0 * * 0", "2006-01-02"},
+		{"30 8,20 * * 1-5", "2006-01-02T1504"},
+	}
+	for _, tt := range tests {
+		p, err := parsePeriod(tt.period)
+		if err != nil {
+			t.Errorf("parsePeriod(%q) failed: %v", tt.period, err)
+			continue
+		}
+		if p.layout != tt.layout {
+			t.Errorf("parsePeriod(%q) layout = %q, want %q", tt.period, p.layout, tt.layout)
+		}
+	}
+
+	for _, bad := range []string{"5h", "90m", "48h", "1s", "0 0 1 * *", "61 * * * *", "0 0 * *", "*/0 * * * *", "fortnightly"} {
+		if _, err := parsePeriod(bad); err == nil {
+			t.Errorf("Expected parsePeriod(%q) to fail", bad)
+		}
+	}
+}
+
+// TestPeriodBounds tests the period containing a moment for several settings
+func TestPeriodBounds(t *testing.T) {
+	cst := time.FixedZone("CST", -6*60*60)
+	now := time.Date(2026, 1, 7, 14, 25, 10, 0, cst) // a Wednesday
+
+	tests := []struct {
+		period     string
+		start, end time.Time
+		stamp      string
+	}{
+		{"1h", time.Date(2026, 1, 7, 14, 0, 0, 0, cst), time.Date(2026, 1, 7, 15, 0, 0, 0, cst), "2026-01-07T14"},
+		{"6h", time.Date(2026, 1, 7, 12, 0, 0, 0, cst), time.Date(2026, 1, 7, 18, 0, 0, 0, cst), "2026-01-07T12"},
+		{"24h", time.Date(2026, 1, 7, 0, 0, 0, 0, cst), time.Date(2026, 1, 8, 0, 0, 0, 0, cst), "2026-01-07"},
+		{"168h", time.Date(2026, 1, 5, 0, 0, 0, 0, cst), time.Date(2026, 1, 12, 0, 0, 0, 0, cst), "2026-01-05"},
+		{"15m", time.Date(2026, 1, 7, 14, 15, 0, 0, cst), time.Date(2026, 1, 7, 14, 30, 0, 0, cst), "2026-01-07T1415"},
+		{"0 8,20 * * *", time.Date(2026, 1, 7, 8, 0, 0, 0, cst), time.Date(2026, 1, 7, 20, 0, 0, 0, cst), "2026-01-07T08"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.period, func(t *testing.T) {
+			p := mustParsePeriod(tt.period)
+			start, end := p.bounds(now, cst)
+			if !start.Equal(tt.start) || !end.Equal(tt.end) {
+				t.Errorf("Expected %v to %v, got %v to %v", tt.start, tt.end, start, end)
+			}
+			if got := start.Format(p.layout); got != tt.stamp {
+				t.Errorf("Expected stamp %s, got %s", tt.stamp, got)
+			}
+		})
+	}
+}
+
+// TestPeriodBoundsOnBoundary tests that a boundary instant starts a new period
+func TestPeriodBoundsOnBoundary(t *testing.T) {
+	boundary := time.Date(2026, 1, 7, 12, 0, 0, 0, time.UTC)
+	start, end := mustParsePeriod("6h").bounds(boundary, time.UTC)
+	if !start.Equal(boundary) || !end.Equal(boundary.Add(6*time.Hour)) {
+		t.Errorf("Expected period starting at %v, got %v to %v", boundary, start, end)
+	}
+}
+
+// TestPrepareLogFilePeriodStamp tests that file names follow the period setting
+func TestPrepareLogFilePeriodStamp(t *testing.T) {
+	cfg := Config{LogDir: t.TempDir(), Period: "1h"}
+	SetDefaults(&cfg)
+
+	start := time.Date(2026, 1, 7, 14, 0, 0, 0, time.UTC)
+	file := prepareLogFile(start, cfg, TargetConfig{Name: "gateway"}, logFormatCSV)
+	if want := cfg.LogDir + "/2026-01-07T14-gateway-pings.csv"; file != want {
+		t.Errorf("Expected %s, got %s", want, file)
+	}
+	if got := LogFileTarget(file); got != "gateway" {
+		t.Errorf("Expected target gateway, got %q", got)
+	}
+}
@@ -1,56 +1,250 @@
 package internal
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net"
+	"net/netip"
+	"strconv"
 	"sync"
 	"time"
 )
 
-func spawnPing(target string, port int, useICMP bool, logFile string, verbose bool, wg *sync.WaitGroup) {
+// ProbeResult is the outcome of a single probe, as written to one CSV row.
+// Phase timings are noPhase unless the probe type measures them.
+type ProbeResult struct {
+	StartTime  time.Time
+	EndTime    time.Time
+	Latency    time.Duration
+	Status     string
+	Family     string // "v4" or "v6"
+	DNS        time.Duration
+	Connect    time.Duration
+	TLS        time.Duration
+	TTFB       time.Duration
+	HTTPStatus int    // 0 unless the probe was an HTTP request that got a response
+	Rcode      string // DNS response code, blank for other probe types
+	IP         string // address the probe connected to, when known
+	Err        error
+}
+
+// spawnPing probes target once and hands the result to the sinks in its slot.
+// If ctx is cancelled first, the probe is abandoned and the slot skipped, so
+// a shutdown doesn't record a cut-off probe as a timeout.
+func spawnPing(ctx context.Context, cfg Config, target TargetConfig, family string, sinks *resultSinks, slot resultSlot, wg *sync.WaitGroup) {
 	defer wg.Done()
 
-	startTime := time.Now()
-	var latency time.Duration
-	var status string
-	var err error
+	done := make(chan ProbeResult, 1)
+	go func() {
+		done <- runProbe(target, family)
+	}()
 
-	if useICMP {
-		latency, err = IcmpPing(target)
-	} else {
-		latency, err = TcpPing(target, port, 15*time.Second)
+	var result ProbeResult
+	select {
+	case result = <-done:
+	case <-ctx.Done():
+		fmt.Printf("[Ping] Abandoned in-flight probe of %s (%s) at shutdown\n", target.Label(), family)
+		sinks.Skip(slot)
+		return
 	}
 
-	if err != nil {
-		status = "timeout"
-	} else {
-		status = classifyStatus(latency)
+	// Rows are stamped in the configured zone, like the file they go into
+	result.StartTime, result.EndTime = result.StartTime.In(cfg.location()), result.EndTime.In(cfg.location())
+	sinks.Write(slot, result)
+
+	if cfg.Verbose {
+		fmt.Printf("[Ping] %s Finished %s (%s %s:%d %s) - %s (%dms)\n", result.StartTime.Format("15:04:05.000"), target.Label(), target.Probe, target.Host, target.Port, family, result.Status, result.Latency.Milliseconds())
+		if result.Err != nil {
+			fmt.Printf("[Ping] %s %s error: %v\n", result.StartTime.Format("15:04:05.000"), target.Label(), result.Err)
+		}
+	}
+}
+
+// runProbe runs the target's probe type over one address family and
+// classifies the outcome.
+func runProbe(target TargetConfig, family string) ProbeResult {
+	result := ProbeResult{
+		StartTime: time.Now(),
+		Family:    family,
+		DNS:       noPhase,
+		Connect:   noPhase,
+		TLS:       noPhase,
+		TTFB:      noPhase,
 	}
 
-	writeToCSV(logFile, startTime, time.Now(), latency, status)
+	switch target.Probe {
+	case "icmp":
+		result.Latency, result.Err = IcmpPing(target.Host, family, 15*time.Second)
+	case "http":
+		var timings HTTPTimings
+		timings, result.Err = HttpPing(target.HTTP, family, 15*time.Second)
+		result.Latency = timings.Total
+		result.DNS, result.Connect, result.TLS, result.TTFB = timings.DNS, timings.Connect, timings.TLS, timings.TTFB
+		result.HTTPStatus = timings.StatusCode
+		result.IP = timings.IP
+	case "dns":
+		var answer DNSResult
+		answer, result.Err = DnsPing(target.DNS, 15*time.Second)
+		result.Latency = answer.Latency
+		result.DNS = answer.Latency
+		result.Rcode = answer.Rcode
+		result.Family = addressFamily(answer.Server, family)
+	default:
+		var timings TCPTimings
+		timings, result.Err = TcpPing(target.Host, target.Port, family, 15*time.Second)
+		if result.Err == nil {
+			result.Latency = timings.Connect
+		}
+		result.DNS, result.Connect, result.IP = timings.DNS, timings.Connect, timings.IP
+	}
 
-	if verbose {
-		fmt.Printf("[Ping] %s Finished %s:%d - %s (%dms)\n", startTime.Format("15:04:05.000"), target, port, status, latency.Milliseconds())
+	if result.Err != nil {
+		result.Status = statusForError(result.Err)
+	} else {
+		result.Status = classifyStatus(result.Latency, target.Thresholds)
 	}
+	result.EndTime = time.Now()
+	return result
 }
 
-func TcpPing(target string, port int, timeout time.Duration) (time.Duration, error) {
+// TCPTimings separates name resolution from the TCP handshake, so a slow
+// resolver doesn't show up as network latency.
+type TCPTimings struct {
+	DNS     time.Duration // noPhase when target is an IP literal
+	Connect time.Duration
+	IP      string // address actually dialed
+}
+
+// TcpPing resolves target over the given address family ("v4" or "v6"), then
+// measures how long a TCP handshake to the first resolved address takes.
+func TcpPing(target string, port int, family string, timeout time.Duration) (TCPTimings, error) {
+	timings := TCPTimings{DNS: noPhase, Connect: noPhase}
+	network, resolveNetwork := "tcp4", "ip4"
+	if family == "v6" {
+		network, resolveNetwork = "tcp6", "ip6"
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	ip, err := netip.ParseAddr(target)
+	if err != nil {
+		start := time.Now()
+		addrs, err := net.DefaultResolver.LookupNetIP(ctx, resolveNetwork, target)
+		timings.DNS = time.Since(start)
+		if err != nil {
+			return timings, resolveError(err)
+		}
+		ip = addrs[0]
+	}
+	timings.IP = ip.Unmap().String()
+
+	var dialer net.Dialer
 	start := time.Now()
-	conn, err := net.DialTimeout("tcp", fmt.Sprintf("%s:%d", target, port), timeout)
+	conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(timings.IP, strconv.Itoa(port)))
 	if err != nil {
-		return 0, err
+		return timings, err
 	}
+	timings.Connect = time.Since(start)
 	conn.Close()
-	return time.Since(start), nil
+	return timings, nil
 }
 
-func IcmpPing(target string) (time.Duration, error) {
-	return 42 * time.Millisecond, nil // placeholder
+// resolveError maps a failed lookup onto the DNS statuses. Timeouts are left
+// alone so they are still counted as timeouts.
+func resolveError(err error) error {
+	var dnsErr *net.DNSError
+	if !errors.As(err, &dnsErr) || dnsErr.IsTimeout {
+		return err
+	}
+	if dnsErr.IsNotFound {
+		return fmt.Errorf("%w: %v", ErrDNSNXDomain, err)
+	}
+	return fmt.Errorf("%w: %v", ErrDNSServFail, err)
 }
 
-func classifyStatus(latency time.Duration) string {
-	if latency < 100*time.Millisecond {
+// addressFamily reports whether a "host:port" address is IPv4 or IPv6,
+// returning fallback when it isn't an IP literal.
+func addressFamily(hostport string, fallback string) string {
+	host, _, err := net.SplitHostPort(hostport)
+	if err != nil {
+		host = hostport
+	}
+	ip := net.ParseIP(host)
+	switch {
+	case ip == nil:
+		return fallback
+	case ip.To4() != nil:
+		return "v4"
+	}
+	return "v6"
+}
+
+// probeFamilies expands the address_family setting into the families probed on every tick.
+func probeFamilies(addressFamily string) []string {
+	switch addressFamily {
+	case "v6":
+		return []string{"v6"}
+	case "both":
+		return []string{"v4", "v6"}
+	}
+	return []string{"v4"}
+}
+
+// classifyStatus places a successful probe into the first latency tier it is
+// under. Tiers left at 0 are skipped. Anything slower than the last tier is
+// "severe", or "delayed" when no delayed tier is configured.
+func classifyStatus(latency time.Duration, th Thresholds) string {
+	switch {
+	case latency < th.OK:
 		return "ok"
+	case th.Degraded > 0 && latency < th.Degraded:
+		return "degraded"
+	case th.Delayed > 0 && latency < th.Delayed:
+		return "delayed"
+	case th.Delayed > 0:
+		return "severe"
 	}
 	return "delayed"
 }
+
+// statusForError turns a probe error into the Status column value. Anything
+// that isn't an explicit ICMP rejection, HTTP check failure or DNS error
+// response is still recorded as a timeout.
+func statusForError(err error) string {
+	switch {
+	case errors.Is(err, ErrDNSNXDomain):
+		return "nxdomain"
+	case errors.Is(err, ErrDNSServFail):
+		return "servfail"
+	case errors.Is(err, ErrDNSRefused):
+		return "refused"
+	case errors.Is(err, ErrDNSNoAnswer):
+		return "no_answer"
+	case errors.Is(err, ErrDNSRcode):
+		return "dns_error"
+	case errors.Is(err, ErrHTTPStatus):
+		return "bad_status"
+	case errors.Is(err, ErrHTTPBody):
+		return "bad_body"
+	case errors.Is(err, ErrICMPPermission):
+		return "no_permission"
+	case errors.Is(err, ErrICMPUnreachable):
+		return "unreachable"
+	case errors.Is(err, ErrICMPTTLExceeded):
+		return "ttl_exceeded"
+	}
+	return "timeout"
+}
+
+// isFailureStatus reports whether a status means the probe failed outright,
+// rather than succeeding at some latency.
+func isFailureStatus(status string) bool {
+	switch status {
+	case "timeout", "unreachable", "ttl_exceeded", "no_permission", "bad_status", "bad_body",
+		"nxdomain", "servfail", "refused", "no_answer", "dns_error":
+		return true
+	}
+	return false
+}
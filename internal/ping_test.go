@@ -0,0 +1,122 @@
+package internal
+
+import (
+	"errors"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func startTCPListener(t *testing.T, network, address string) int {
+	t.Helper()
+	ln, err := net.Listen(network, address)
+	if err != nil {
+		t.Skipf("Cannot listen on %s %s: %v", network, address, err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	return ln.Addr().(*net.TCPAddr).Port
+}
+
+// TestTcpPingIPLiteral tests that IP literals skip resolution entirely
+func TestTcpPingIPLiteral(t *testing.T) {
+	port := startTCPListener(t, "tcp4", "127.0.0.1:0")
+
+	timings, err := TcpPing("127.0.0.1", port, "v4", 2*time.Second)
+	if err != nil {
+		t.Fatalf("Expected connect to succeed, got %v", err)
+	}
+	if timings.DNS != noPhase {
+		t.Errorf("Expected no DNS phase for an IP literal, got %v", timings.DNS)
+	}
+	if timings.Connect < 0 {
+		t.Errorf("Expected a measured connect time, got %v", timings.Connect)
+	}
+	if timings.IP != "127.0.0.1" {
+		t.Errorf("Expected IP 127.0.0.1, got %q", timings.IP)
+	}
+}
+
+// TestTcpPingResolvesFirst tests that hostnames are resolved separately from the connect
+func TestTcpPingResolvesFirst(t *testing.T) {
+	port := startTCPListener(t, "tcp4", "127.0.0.1:0")
+
+	timings, err := TcpPing("localhost", port, "v4", 2*time.Second)
+	if err != nil {
+		t.Fatalf("Expected connect to succeed, got %v", err)
+	}
+	if timings.DNS < 0 {
+		t.Errorf("Expected a measured DNS phase, got %v", timings.DNS)
+	}
+	if ip := net.ParseIP(timings.IP); ip == nil || !ip.IsLoopback() || ip.To4() == nil {
+		t.Errorf("Expected an IPv4 loopback address, got %q", timings.IP)
+	}
+}
+
+// TestTcpPingUnresolvable tests that resolution failures get a DNS status instead of timeout
+func TestTcpPingUnresolvable(t *testing.T) {
+	_, err := TcpPing("pingmonke-does-not-exist.invalid", 80, "v4", 2*time.Second)
+	if err == nil {
+		t.Fatal("Expected an error for an unresolvable host")
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		t.Skip("Resolver timed out in this environment:", err)
+	}
+	if status := statusForError(err); status != "nxdomain" && status != "servfail" {
+		t.Errorf("Expected a DNS status, got %q (%v)", status, err)
+	}
+}
+
+// TestAddressFamily tests detecting the family of a resolver address
+func TestAddressFamily(t *testing.T) {
+	tests := map[string]string{
+		"192.0.2.53:53":                           "v4",
+		"[2001:db8::53]:53":                       "v6",
+		"resolver.lan:53":                         "v4",
+		net.JoinHostPort("::1", strconv.Itoa(53)): "v6",
+	}
+	for addr, want := range tests {
+		if got := addressFamily(addr, "v4"); got != want {
+			t.Errorf("addressFamily(%q) = %q, want %q", addr, got, want)
+		}
+	}
+}
+
+// TestClassifyStatus tests placing latencies into the configured tiers
+func TestClassifyStatus(t *testing.T) {
+	tiers := Thresholds{OK: 30 * time.Millisecond, Degraded: 80 * time.Millisecond, Delayed: 250 * time.Millisecond}
+	tests := []struct {
+		name    string
+		th      Thresholds
+		latency time.Duration
+		want    string
+	}{
+		{"Default below cutoff", DefaultThresholds, 99 * time.Millisecond, "ok"},
+		{"Default at cutoff", DefaultThresholds, 100 * time.Millisecond, "delayed"},
+		{"Default far above cutoff", DefaultThresholds, 5 * time.Second, "delayed"},
+		{"Tiered ok", tiers, 29 * time.Millisecond, "ok"},
+		{"Tiered degraded", tiers, 30 * time.Millisecond, "degraded"},
+		{"Tiered delayed", tiers, 80 * time.Millisecond, "delayed"},
+		{"Tiered severe", tiers, 250 * time.Millisecond, "severe"},
+		{"No degraded tier", Thresholds{OK: 30 * time.Millisecond, Delayed: 250 * time.Millisecond}, 50 * time.Millisecond, "delayed"},
+		{"No delayed tier", Thresholds{OK: 30 * time.Millisecond, Degraded: 80 * time.Millisecond}, 90 * time.Millisecond, "delayed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := classifyStatus(tt.latency, tt.th); got != tt.want {
+				t.Errorf("classifyStatus(%v) = %q, want %q", tt.latency, got, tt.want)
+			}
+		})
+	}
+}
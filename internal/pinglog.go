@@ -0,0 +1,241 @@
+// internal/pinglog.go
+// @version parser
+// @description Shared, validated reader for ping logs (CSV or JSON Lines, gzipped or not)
+
+package internal
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// PingLogError reports a row of a ping log that was skipped as malformed.
+type PingLogError struct {
+	Path string
+	Line int // 1-based
+	Err  error
+}
+
+func (e *PingLogError) Error() string {
+	if e.Path == "" {
+		return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+	}
+	return fmt.Sprintf("%s:%d: %v", e.Path, e.Line, e.Err)
+}
+
+func (e *PingLogError) Unwrap() error { return e.Err }
+
+// pingLogRow is one validated row of a ping log.
+type pingLogRow struct {
+	line   int      // 1-based line number
+	fields []string // the columns of pingCSVHeader (JSON Lines records are converted)
+	record PingRecord
+}
+
+// pingLog is a parsed ping log. Malformed rows are skipped and listed; a
+// final line with no newline was still being written (or cut off by a
+// crash) and is left out without being counted as malformed.
+type pingLog struct {
+	rows      []pingLogRow
+	malformed []*PingLogError
+	truncated bool
+}
+
+// maxPingLogLine bounds a single line, so a corrupt file can't exhaust memory.
+const maxPingLogLine = 1 << 20
+
+// readPingLog reads and validates the ping log at path. Only I/O errors are
+// returned; bad rows end up in malformed.
+func readPingLog(path string) (*pingLog, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	name, gzipped := strings.CutSuffix(path, gzipExt)
+	if gzipped {
+		zr, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		defer zr.Close()
+		r = zr
+	}
+
+	log, err := parsePingLog(r, filepath.Ext(name) == "."+logFormatJSONL)
+	for _, e := range log.malformed {
+		e.Path = path
+	}
+	if err != nil {
+		// A gzip stream cut short is a truncated file: keep what was read
+		if gzipped && errors.Is(err, io.ErrUnexpectedEOF) {
+			log.truncated = true
+			return log, nil
+		}
+		return log, fmt.Errorf("%s: %w", path, err)
+	}
+	return log, nil
+}
+
+// parsePingLog reads a ping log line by line. A CSV log's first line is its
+// header; blank lines are ignored.
+func parsePingLog(r io.Reader, jsonl bool) (*pingLog, error) {
+	log := &pingLog{}
+	br := bufio.NewReader(r)
+	for n := 1; ; n++ {
+		line, err := readLogLine(br)
+		if err == errLineTooLong {
+			log.malformed = append(log.malformed, &PingLogError{Line: n, Err: err})
+			continue
+		}
+		if err != nil && err != io.EOF {
+			return log, err
+		}
+		complete := err == nil
+		if line == "" && !complete {
+			return log, nil
+		}
+		if !complete {
+			// The writer always ends rows with a newline, so this one is partial
+			log.truncated = true
+			return log, nil
+		}
+
+		line = strings.TrimRight(line, "\r\n")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		fields, rowErr := splitLogLine(line, jsonl)
+		if rowErr == nil && n == 1 && !jsonl && fields[0] == pingCSVHeader[0] {
+			continue // header
+		}
+		var record PingRecord
+		if rowErr == nil {
+			record, rowErr = parsePingFields(fields)
+		}
+		if rowErr != nil {
+			log.malformed = append(log.malformed, &PingLogError{Line: n, Err: rowErr})
+			continue
+		}
+		log.rows = append(log.rows, pingLogRow{line: n, fields: fields, record: record})
+	}
+}
+
+var errLineTooLong = fmt.Errorf("line longer than %d bytes", maxPingLogLine)
+
+// readLogLine reads up to and including the next newline, returning io.EOF
+// with whatever was left if there is none. An overlong line is skipped and
+// reported as errLineTooLong.
+func readLogLine(br *bufio.Reader) (string, error) {
+	var sb strings.Builder
+	tooLong := false
+	for {
+		chunk, err := br.ReadSlice('\n')
+		if !tooLong {
+			sb.Write(chunk)
+			tooLong = sb.Len() > maxPingLogLine
+		}
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		if tooLong && err == nil {
+			return "", errLineTooLong
+		}
+		if tooLong {
+			return "", err // cut off at the end of the file anyway
+		}
+		return sb.String(), err
+	}
+}
+
+// splitLogLine splits one line into the columns of pingCSVHeader.
+func splitLogLine(line string, jsonl bool) ([]string, error) {
+	if jsonl {
+		var rec probeRecord
+		if err := json.Unmarshal([]byte(line), &rec); err != nil {
+			return nil, fmt.Errorf("invalid JSON: %w", err)
+		}
+		if rec.Schema != probeRecordSchema {
+			return nil, fmt.Errorf("unsupported schema %d (expected %d)", rec.Schema, probeRecordSchema)
+		}
+		return rec.row()
+	}
+	reader := csv.NewReader(strings.NewReader(line))
+	reader.FieldsPerRecord = -1 // files started before the AF column was added have 4 columns
+	fields, err := reader.Read()
+	if err != nil {
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			err = parseErr.Err
+		}
+		return nil, fmt.Errorf("invalid CSV: %w", err)
+	}
+	return fields, nil
+}
+
+// parsePingFields validates a row's columns and converts them to a record.
+func parsePingFields(fields []string) (PingRecord, error) {
+	if len(fields) < 4 {
+		return PingRecord{}, fmt.Errorf("expected at least 4 columns, got %d", len(fields))
+	}
+	start, err := parsePingTime(fields[0])
+	if err != nil {
+		return PingRecord{}, fmt.Errorf("invalid start time %q", fields[0])
+	}
+	end, err := parsePingTime(fields[1])
+	if err != nil {
+		return PingRecord{}, fmt.Errorf("invalid end time %q", fields[1])
+	}
+	latency, err := strconv.ParseInt(fields[2], 10, 64)
+	if err != nil || latency < 0 {
+		return PingRecord{}, fmt.Errorf("invalid latency %q", fields[2])
+	}
+	status := fields[3]
+	if status == "" {
+		return PingRecord{}, errors.New("missing status")
+	}
+	family := ""
+	if len(fields) > 4 {
+		family = fields[4]
+		if family != "" && family != "v4" && family != "v6" {
+			return PingRecord{}, fmt.Errorf("invalid address family %q", family)
+		}
+	}
+	return PingRecord{StartTime: start, EndTime: end, Latency: latency, Status: status, Family: family}, nil
+}
+
+// records returns the log's rows as PingRecords.
+func (l *pingLog) records() []PingRecord {
+	pings := make([]PingRecord, len(l.rows))
+	for i, row := range l.rows {
+		pings[i] = row.record
+	}
+	return pings
+}
+
+// malformedSummary describes the skipped rows, listing the first few.
+func (l *pingLog) malformedSummary() string {
+	if len(l.malformed) == 0 {
+		return ""
+	}
+	var parts []string
+	for i, e := range l.malformed {
+		if i == 3 {
+			parts = append(parts, fmt.Sprintf("and %d more", len(l.malformed)-i))
+			break
+		}
+		parts = append(parts, e.Error())
+	}
+	return fmt.Sprintf("Skipped %d malformed rows: %s", len(l.malformed), strings.Join(parts, "; "))
+}
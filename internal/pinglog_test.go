@@ -0,0 +1,171 @@
+package internal
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestParsePingLog tests validation of CSV rows, with line numbers for the malformed ones
+func TestParsePingLog(t *testing.T) {
+	content := strings.Join([]string{
+		"Ping Init,Ping Rec,Ping Time (ms),Status,AF",
+		"2026-01-07 14:00:00.000,2026-01-07 14:00:00.010,10,ok,v4",
+		"2026-01-07T14:00:15Z,2026-01-07T14:00:30Z,0,timeout,v4", // older RFC3339 rows
+		"",
+		"2026-01-07 14:00:30.000,2026-01-07 14:00:30.010,10",
+		"yesterday,2026-01-07 14:00:45.010,10,ok,v4",
+		"2026-01-07 14:01:00.000,2026-01-07 14:01:00.010,-5,ok,v4",
+		`2026-01-07 14:01:15.000,"2026-01-07 14:01:15.010,10,ok,v4`,
+		"2026-01-07 14:01:30.000,2026-01-07 14:01:30.010,10,ok,v5",
+		"2026-01-07 14:01:45.000,2026-01-07 14:01:45.010,12,ok", // legacy 4-column row
+		"2026-01-07 14:02:00.000,2026-01-07 14:02:00.010,1",     // cut off by a crash
+	}, "\n")
+
+	log, err := parsePingLog(strings.NewReader(content), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(log.rows) != 3 || log.rows[1].record.Status != "timeout" || log.rows[2].line != 10 || log.rows[2].record.Family != "" {
+		t.Errorf("Unexpected rows %+v", log.rows)
+	}
+	if got := formatTimestamp(log.rows[1].record.StartTime); got != "2026-01-07 14:00:15.000" {
+		t.Errorf("Expected the RFC3339 start time to parse, got %s", got)
+	}
+	if !log.truncated {
+		t.Error("Expected the unterminated last line to be reported as truncated")
+	}
+
+	wantLines := []int{5, 6, 7, 8, 9}
+	if len(log.malformed) != len(wantLines) {
+		t.Fatalf("Expected malformed lines %v, got %v", wantLines, log.malformed)
+	}
+	for i, e := range log.malformed {
+		if e.Line != wantLines[i] {
+			t.Errorf("Expected malformed line %d, got %v", wantLines[i], e)
+		}
+	}
+	if msg := log.malformedSummary(); !strings.HasPrefix(msg, "Skipped 5 malformed rows: line 5: expected at least 4 columns") || !strings.HasSuffix(msg, "and 2 more") {
+		t.Errorf("Unexpected summary %q", msg)
+	}
+}
+
+// TestParsePingLogJSONL tests that JSON Lines logs go through the same validation
+func TestParsePingLogJSONL(t *testing.T) {
+	content := `{"schema":1,"start_time":"2026-01-07T14:00:00Z","end_time":"2026-01-07T14:00:00.01Z","latency_ms":10,"status":"ok","family":"v4"}
+not json
+{"schema":1,"start_time":"2026-01-07T14:00:30Z","end_time":"2026-01-07T14:00:30Z","latency_ms":0,"status":"","family":"v4"}
+{"schema":1,"start_time":"2026-01-07T14:00:45Z","end_time":"2026-01-07T14:00:45Z","latency_ms":0,"status":"timeout","family":"v4"}
+{"schema":2,"start_time":"2026-01-07T14:01:00Z","end_time":"2026-01-07T14:01:00Z","latency_ms":12,"status":"ok","family":"v4"}
+{"start_time":"2026-01-07T14:01:15Z","end_time":"2026-01-07T14:01:15Z","latency_ms":12,"status":"ok","family":"v4"}
+`
+	log, err := parsePingLog(strings.NewReader(content), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(log.rows) != 2 || log.rows[1].line != 4 || log.truncated {
+		t.Errorf("Unexpected rows %+v (truncated %v)", log.rows, log.truncated)
+	}
+	// Unknown and missing schema versions are rejected rather than misread
+	if len(log.malformed) != 4 || log.malformed[0].Line != 2 || log.malformed[1].Line != 3 ||
+		log.malformed[2].Line != 5 || log.malformed[3].Line != 6 {
+		t.Errorf("Expected lines 2, 3, 5 and 6 malformed, got %v", log.malformed)
+	}
+	if !strings.Contains(log.malformed[2].Err.Error(), "schema 2") {
+		t.Errorf("Expected the schema named, got %v", log.malformed[2].Err)
+	}
+}
+
+// TestGenerateSummaryTimes tests that summaries carry the logged times rather than zero times
+func TestGenerateSummaryTimes(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "2026-01-07-isp-pings.csv")
+	content := "Ping Init,Ping Rec,Ping Time (ms),Status,AF\n" +
+		"2026-01-07 14:00:00.000,2026-01-07 14:00:00.010,10,ok,v4\n" +
+		"2026-01-07 14:00:15.000,2026-01-07 14:00:16.000,0,timeout,v4\n" +
+		"2026-01-07 14:00:30.000,2026-01-07 14:00:31.000,0,timeout,v4\n" +
+		"garbage\n" +
+		"2026-01-07 14:00:45.000,2026-01-07 14:00:45.010,10,ok,v4\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	msg := generateSummaryWithLogging(path, Config{}, DefaultThresholds, true)
+	if !strings.Contains(msg, "Skipped 1 malformed rows") || !strings.Contains(msg, "2026-01-07-isp-pings.csv:5:") {
+		t.Errorf("Expected the malformed row to be reported with its line, got %q", msg)
+	}
+	summary, err := os.ReadFile(filepath.Join(dir, "2026-01-07-isp-pings-summary.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(summary), "Start,2026-01-07 14:00:15.000") || strings.Contains(string(summary), "0001-01-01") {
+		t.Errorf("Expected the event to start at 14:00:15, got\n%s", summary)
+	}
+}
+
+// TestGenerateSummaryEmptyFile tests summarizing empty and header-only logs
+func TestGenerateSummaryEmptyFile(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"2026-01-07-empty-pings.csv":   "",
+		"2026-01-07-header-pings.csv":  "Ping Init,Ping Rec,Ping Time (ms),Status,AF\n",
+		"2026-01-07-empty-pings.jsonl": "",
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		generateSummary(path, Config{}, DefaultThresholds)
+		summary, err := os.ReadFile(strings.TrimSuffix(path, filepath.Ext(path)) + "-summary.csv")
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !strings.Contains(string(summary), "\n0,0,0,0,0,0,0,0,") {
+			t.Errorf("%s: expected an all-zero summary, got\n%s", name, summary)
+		}
+		if lines, err := ReadPingFile(path); err != nil || len(lines) != 0 {
+			t.Errorf("%s: expected no lines, got %v %v", name, lines, err)
+		}
+	}
+}
+
+// FuzzParsePingLog tests that arbitrary input never panics and only yields valid rows
+func FuzzParsePingLog(f *testing.F) {
+	f.Add([]byte("Ping Init,Ping Rec,Ping Time (ms),Status,AF\n2026-01-07 14:00:00.000,2026-01-07 14:00:00.010,10,ok,v4\n"), false)
+	f.Add([]byte("2026-01-07T14:00:15Z,2026-01-07T14:00:30Z,0,timeout\n\"unterminated,\n"), false)
+	f.Add([]byte("2026-01-07 14:00:00.000,2026-01-07 14:00:00.010,10,ok,v4,1,2,3,4,200,NOERROR,1.1.1.1\n2026-01-07 14:00"), false)
+	f.Add([]byte(`{"schema":1,"start_time":"2026-01-07T14:00:00Z","end_time":"2026-01-07T14:00:00Z","latency_ms":10,"status":"ok","dns_ms":3}`+"\n{\"schema\":"), true)
+	f.Add([]byte("\r\n\n\x00,\xff\n"), true)
+
+	f.Fuzz(func(t *testing.T, data []byte, jsonl bool) {
+		log, err := parsePingLog(bytes.NewReader(data), jsonl)
+		if err != nil {
+			t.Fatalf("Unexpected error reading from memory: %v", err)
+		}
+		lines := bytes.Count(data, []byte("\n")) + 1
+		last := 0
+		for _, row := range log.rows {
+			if row.line <= last || row.line > lines {
+				t.Fatalf("Row line %d out of order or range (previous %d, %d lines)", row.line, last, lines)
+			}
+			last = row.line
+			record, err := parsePingFields(row.fields)
+			if err != nil || record != row.record {
+				t.Fatalf("Row %v doesn't validate: %v", row.fields, err)
+			}
+			if record.Latency < 0 || record.Status == "" {
+				t.Fatalf("Invalid record accepted: %+v", record)
+			}
+		}
+		for _, e := range log.malformed {
+			if e.Line < 1 || e.Line > lines || e.Err == nil {
+				t.Fatalf("Bad malformed report %+v", e)
+			}
+		}
+		if len(log.rows)+len(log.malformed) > lines {
+			t.Fatalf("%d rows and %d malformed from %d lines", len(log.rows), len(log.malformed), lines)
+		}
+	})
+}
@@ -0,0 +1,137 @@
+// internal/query.go
+// @version sqlite
+// @description pingmonke query: probes and events from the history database as a table, CSV or JSON Lines
+
+package internal
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+	"time"
+)
+
+// QueryOptions selects what pingmonke query prints.
+type QueryOptions struct {
+	Events      bool   // events instead of probes
+	Format      string // table (default), csv or jsonl
+	Target      string
+	Statuses    []string      // probes only
+	MinDuration time.Duration // events only
+	From, To    time.Time
+	Limit       int
+}
+
+// queryEvent is an event as pingmonke query prints it in JSON Lines.
+type queryEvent struct {
+	Target          string  `json:"target"`
+	Period          string  `json:"period"`
+	Family          string  `json:"family"`
+	StartTime       string  `json:"start_time"`
+	EndTime         string  `json:"end_time"`
+	DurationSeconds float64 `json:"duration_seconds"`
+	BadPings        int     `json:"bad_pings"`
+	TotalPings      int     `json:"total_pings"`
+}
+
+// RunQuery runs the query opts describes against h, writing the results to w.
+func RunQuery(w io.Writer, h *HistoryDB, opts QueryOptions) error {
+	switch opts.Format {
+	case "", "table", "csv", "jsonl":
+	default:
+		return fmt.Errorf("unknown format %q, use table, csv or jsonl", opts.Format)
+	}
+	if opts.Events {
+		events, err := h.QueryEvents(EventQuery{Target: opts.Target, From: opts.From, To: opts.To, MinDuration: opts.MinDuration, Limit: opts.Limit})
+		if err != nil {
+			return err
+		}
+		return writeEvents(w, events, opts.Format)
+	}
+	probes, err := h.QueryProbes(ProbeQuery{Target: opts.Target, Statuses: opts.Statuses, From: opts.From, To: opts.To, Limit: opts.Limit})
+	if err != nil {
+		return err
+	}
+	return writeProbes(w, probes, opts.Format)
+}
+
+func writeProbes(w io.Writer, probes []HistoryProbe, format string) error {
+	switch format {
+	case "jsonl":
+		enc := json.NewEncoder(w)
+		for _, p := range probes {
+			if err := enc.Encode(newProbeRecord(TargetConfig{Name: p.Target, Host: p.Host, Probe: p.Probe}, p.Result)); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "csv":
+		cw := csv.NewWriter(w)
+		cw.Write(append([]string{"Target"}, pingCSVHeader...))
+		for _, p := range probes {
+			cw.Write(append([]string{p.Target}, pingRow(p.Result)...))
+		}
+		cw.Flush()
+		return cw.Error()
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "START\tTARGET\tSTATUS\tLATENCY\tAF\tERROR")
+	for _, p := range probes {
+		r := p.Result
+		errText := ""
+		if r.Err != nil {
+			errText = r.Err.Error()
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%dms\t%s\t%s\n", formatTimestamp(r.StartTime), p.Target, r.Status, r.Latency.Milliseconds(), r.Family, errText)
+	}
+	fmt.Fprintf(tw, "%d probes\n", len(probes))
+	return tw.Flush()
+}
+
+func writeEvents(w io.Writer, events []HistoryEvent, format string) error {
+	switch format {
+	case "jsonl":
+		enc := json.NewEncoder(w)
+		for _, e := range events {
+			err := enc.Encode(queryEvent{
+				Target:          e.Target,
+				Period:          e.Period,
+				Family:          e.Family,
+				StartTime:       e.StartTime.Format(time.RFC3339Nano),
+				EndTime:         e.EndTime.Format(time.RFC3339Nano),
+				DurationSeconds: e.EndTime.Sub(e.StartTime).Seconds(),
+				BadPings:        e.BadPings,
+				TotalPings:      e.TotalPings,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	case "csv":
+		cw := csv.NewWriter(w)
+		cw.Write([]string{"Target", "Period", "AF", "Start", "End", "Duration (s)", "Bad Pings", "Total Pings"})
+		for _, e := range events {
+			cw.Write([]string{
+				e.Target, e.Period, e.Family, formatTimestamp(e.StartTime), formatTimestamp(e.EndTime),
+				strconv.FormatFloat(e.EndTime.Sub(e.StartTime).Seconds(), 'f', 3, 64),
+				strconv.Itoa(e.BadPings), strconv.Itoa(e.TotalPings),
+			})
+		}
+		cw.Flush()
+		return cw.Error()
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "START\tEND\tDURATION\tTARGET\tAF\tBAD PINGS")
+	for _, e := range events {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%d/%d\n", formatTimestamp(e.StartTime), formatTimestamp(e.EndTime),
+			e.EndTime.Sub(e.StartTime).Round(time.Second), e.Target, e.Family, e.BadPings, e.TotalPings)
+	}
+	fmt.Fprintf(tw, "%d events\n", len(events))
+	return tw.Flush()
+}
@@ -0,0 +1,492 @@
+// internal/report.go
+// @version report
+// @description pingmonke report: uptime, loss, latency and events over a range of period files, by day and hour of day
+
+package internal
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+	"time"
+)
+
+// ReportOptions selects what pingmonke report covers.
+type ReportOptions struct {
+	From, To time.Time // To is exclusive
+	Target   string    // only this target; every target when empty
+	Format   string    // text (default), csv or html
+}
+
+// Report consolidates the period files of a range of days.
+type Report struct {
+	From, To time.Time
+	Targets  []TargetReport
+}
+
+// TargetReport is one target's share of a report.
+type TargetReport struct {
+	Target  TargetConfig
+	Files   int
+	Overall ReportBucket
+	Days    []ReportBucket   // one per calendar day of the range
+	Hours   [24]ReportBucket // by hour of day, over every day of the range
+	Events  []Event          // indices refer to pings
+	Gaps    []ReportGap      // stretches of the range pingmonke wasn't running
+
+	pings []PingRecord // every ping in the range, oldest first
+}
+
+// ReportBucket aggregates the pings and events of a slice of the range.
+// Time pingmonke wasn't running counts toward Span but not Monitored, so
+// it is never mistaken for uptime.
+type ReportBucket struct {
+	Label     string
+	Span      time.Duration // time the bucket covers, up to now
+	Monitored time.Duration // of which pingmonke was running
+	Down      time.Duration // monitored time spent in events
+	Events    int           // events that started in the bucket
+	Stats     PingStats
+}
+
+// ReportGap is a stretch of time with no pings.
+type ReportGap struct {
+	Start, End time.Time
+}
+
+// Uptime is the percentage of monitored time outside events, or -1 if none
+// of the bucket was monitored.
+func (b ReportBucket) Uptime() float64 {
+	if b.Monitored <= 0 {
+		return -1
+	}
+	return 100 * float64(b.Monitored-min(b.Down, b.Monitored)) / float64(b.Monitored)
+}
+
+// Coverage is the percentage of the bucket pingmonke was running for.
+func (b ReportBucket) Coverage() float64 {
+	if b.Span <= 0 {
+		return 0
+	}
+	return 100 * float64(b.Monitored) / float64(b.Span)
+}
+
+// reportGapIntervals is how many probe intervals may pass between pings
+// before the silence counts as pingmonke not running.
+const reportGapIntervals = 2
+
+// timeSpan is a half-open stretch of time.
+type timeSpan struct {
+	start, end time.Time
+}
+
+// RunReport reads the period files of the range opts describes and writes
+// the consolidated report to w.
+func RunReport(w io.Writer, cfg Config, opts ReportOptions) error {
+	switch opts.Format {
+	case "", "text", "csv", "html":
+	default:
+		return fmt.Errorf("unknown format %q, use text, csv or html", opts.Format)
+	}
+	report, err := buildReport(cfg, opts, time.Now())
+	if err != nil {
+		return err
+	}
+	switch opts.Format {
+	case "csv":
+		return writeReportCSV(w, report)
+	case "html":
+		return writeReportHTML(w, report)
+	}
+	return writeReportText(w, report)
+}
+
+// buildReport gathers every target's pings in the range from the log
+// directory and aggregates them as of now.
+func buildReport(cfg Config, opts ReportOptions, now time.Time) (*Report, error) {
+	if !opts.From.Before(opts.To) {
+		return nil, fmt.Errorf("the report range is empty: %s to %s", opts.From.Format(time.DateOnly), opts.To.Format(time.DateOnly))
+	}
+	loc := cfg.location()
+	report := &Report{From: opts.From.In(loc), To: opts.To.In(loc)}
+
+	var targets []TargetConfig
+	for _, target := range cfg.Targets {
+		if opts.Target == "" || opts.Target == target.Name || opts.Target == target.Label() {
+			targets = append(targets, target)
+		}
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("no target %q in the config", opts.Target)
+	}
+
+	files, err := reportFiles(cfg, report.From, report.To)
+	if err != nil {
+		return nil, err
+	}
+	for _, target := range targets {
+		report.Targets = append(report.Targets, buildTargetReport(cfg, target, files[target.Name], report.From, report.To, now))
+	}
+	return report, nil
+}
+
+// reportFiles finds the ping logs, by target name and oldest first, of the
+// periods that may hold pings between from and to. A period is at most a
+// week long, so it can't start more than 8 days (a week plus a DST shift)
+// before from.
+func reportFiles(cfg Config, from, to time.Time) (map[string][]string, error) {
+	paths, err := filepath.Glob(filepath.Join(ExpandHome(cfg.LogDir), "*-pings.*"))
+	if err != nil {
+		return nil, err
+	}
+	earliest := from.AddDate(0, 0, -8)
+	byTarget := map[string][]string{}
+	for _, path := range paths {
+		if !IsPingLogFile(path) {
+			continue
+		}
+		stamp, name := splitLogFileName(path)
+		start, ok := parsePeriodStamp(stamp, cfg.location())
+		if !ok || !start.Before(to) || start.Before(earliest) {
+			continue
+		}
+		byTarget[name] = append(byTarget[name], path)
+	}
+	for _, files := range byTarget {
+		sort.Slice(files, func(i, j int) bool {
+			si, _ := splitLogFileName(files[i])
+			sj, _ := splitLogFileName(files[j])
+			return si < sj
+		})
+	}
+	return byTarget, nil
+}
+
+// parsePeriodStamp reads the start of a period from its file name stamp.
+// Debug files, stamped with a time of day only, have no date and are skipped.
+func parsePeriodStamp(stamp string, loc *time.Location) (time.Time, bool) {
+	for _, layout := range periodStampLayouts {
+		if len(layout) != len(stamp) || layout == debugPeriod.layout {
+			continue
+		}
+		if t, err := time.ParseInLocation(layout, stamp, loc); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// buildTargetReport aggregates one target's files over [from, to).
+func buildTargetReport(cfg Config, target TargetConfig, files []string, from, to, now time.Time) TargetReport {
+	loc := cfg.location()
+	tr := TargetReport{Target: target}
+	detector := cfg.EventDetector(target.Thresholds)
+	for _, file := range files {
+		records, err := readPingRecords(file)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[Report] Error reading %s: %v\n", file, err)
+			if len(records) == 0 {
+				continue
+			}
+		}
+		var pings []PingRecord
+		for _, p := range records {
+			p.StartTime, p.EndTime = inZone(p.StartTime, loc), inZone(p.EndTime, loc)
+			if !p.StartTime.Before(from) && p.StartTime.Before(to) {
+				pings = append(pings, p)
+			}
+		}
+		if len(pings) == 0 {
+			continue
+		}
+		tr.Files++
+		// Events are detected per period file, as in its summary
+		offset := len(tr.pings)
+		for _, e := range detector.Detect(pings) {
+			e.StartIndex += offset
+			e.EndIndex += offset
+			tr.Events = append(tr.Events, e)
+		}
+		tr.pings = append(tr.pings, pings...)
+	}
+
+	end := earlier(to, now)
+	interval := target.interval(cfg)
+	if interval <= 0 {
+		interval = cfg.Interval
+	}
+	monitored := monitoredSpans(tr.pings, interval, from, end)
+	tr.Gaps = spanGaps(monitored, from, end)
+	var down []timeSpan
+	for _, e := range tr.Events {
+		down = append(down, timeSpan{e.StartTime, e.EndTime})
+	}
+
+	bucket := func(label string, start, stop time.Time) ReportBucket {
+		if stop.After(end) {
+			stop = end
+		}
+		b := ReportBucket{Label: label}
+		if start.Before(stop) {
+			b.Span = stop.Sub(start)
+			b.Monitored = spansOverlap(monitored, start, stop)
+			b.Down = spansOverlap(down, start, stop)
+		}
+		return b
+	}
+
+	tr.Overall = bucket("", from, to)
+	tr.Overall.Events = len(tr.Events)
+	tr.Overall.Stats = computeStats(tr.pings, target.Thresholds)
+
+	for h := range tr.Hours {
+		tr.Hours[h].Label = fmt.Sprintf("%02d:00", h)
+	}
+	dayPings := map[string][]PingRecord{}
+	var hourPings [24][]PingRecord
+	for _, p := range tr.pings {
+		day := p.StartTime.Format(time.DateOnly)
+		dayPings[day] = append(dayPings[day], p)
+		hourPings[p.StartTime.Hour()] = append(hourPings[p.StartTime.Hour()], p)
+	}
+	dayEvents := map[string]int{}
+	for _, e := range tr.Events {
+		dayEvents[e.StartTime.Format(time.DateOnly)]++
+		tr.Hours[e.StartTime.Hour()].Events++
+	}
+
+	for day := from; day.Before(to); day = time.Date(day.Year(), day.Month(), day.Day()+1, 0, 0, 0, 0, loc) {
+		label := day.Format(time.DateOnly)
+		next := time.Date(day.Year(), day.Month(), day.Day()+1, 0, 0, 0, 0, loc)
+		d := bucket(label, day, earlier(next, to))
+		d.Events = dayEvents[label]
+		d.Stats = computeStats(dayPings[label], target.Thresholds)
+		tr.Days = append(tr.Days, d)
+
+		// Hours are walked on the wall clock, so DST days have 23 or 25
+		for h := range tr.Hours {
+			start := time.Date(day.Year(), day.Month(), day.Day(), h, 0, 0, 0, loc)
+			if start.Hour() != h || start.Before(from) || !start.Before(to) {
+				continue
+			}
+			hb := bucket("", start, earlier(start.Add(time.Hour), to))
+			tr.Hours[h].Span += hb.Span
+			tr.Hours[h].Monitored += hb.Monitored
+			tr.Hours[h].Down += hb.Down
+		}
+	}
+	for h := range tr.Hours {
+		tr.Hours[h].Stats = computeStats(hourPings[h], target.Thresholds)
+	}
+	return tr
+}
+
+// earlier returns the earlier of a and b.
+func earlier(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
+
+// monitoredSpans returns the stretches of [from, to) pingmonke was running
+// for: pings no more than reportGapIntervals intervals apart are joined, and
+// each ping covers one interval from when it was sent.
+func monitoredSpans(pings []PingRecord, interval time.Duration, from, to time.Time) []timeSpan {
+	var spans []timeSpan
+	for _, p := range pings {
+		if n := len(spans); n > 0 && p.StartTime.Sub(spans[n-1].end) <= (reportGapIntervals-1)*interval {
+			spans[n-1].end = later(spans[n-1].end, p.StartTime.Add(interval))
+			continue
+		}
+		spans = append(spans, timeSpan{p.StartTime, p.StartTime.Add(interval)})
+	}
+	var clipped []timeSpan
+	for _, s := range spans {
+		s.start, s.end = later(s.start, from), earlier(s.end, to)
+		if s.start.Before(s.end) {
+			clipped = append(clipped, s)
+		}
+	}
+	return clipped
+}
+
+// spanGaps returns the stretches of [from, to) not covered by spans, which
+// are sorted and don't overlap.
+func spanGaps(spans []timeSpan, from, to time.Time) []ReportGap {
+	var gaps []ReportGap
+	at := from
+	for _, s := range spans {
+		if s.start.After(at) {
+			gaps = append(gaps, ReportGap{at, s.start})
+		}
+		at = later(at, s.end)
+	}
+	if at.Before(to) {
+		gaps = append(gaps, ReportGap{at, to})
+	}
+	return gaps
+}
+
+// spansOverlap returns how much of [start, end) spans cover.
+func spansOverlap(spans []timeSpan, start, end time.Time) time.Duration {
+	var total time.Duration
+	for _, s := range spans {
+		if lo, hi := later(s.start, start), earlier(s.end, end); lo.Before(hi) {
+			total += hi.Sub(lo)
+		}
+	}
+	return total
+}
+
+// later returns the later of a and b.
+func later(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+// reportBucketHeader lists the CSV columns written from a ReportBucket.
+var reportBucketHeader = append([]string{
+	"Span (s)", "Monitored (s)", "Coverage (%)", "Down (s)", "Uptime (%)", "Events", "Pings", "Lost",
+}, statsHeader...)
+
+// row formats the bucket as the columns of reportBucketHeader.
+func (b ReportBucket) row() []string {
+	return append([]string{
+		strconv.FormatFloat(b.Span.Seconds(), 'f', 0, 64),
+		strconv.FormatFloat(b.Monitored.Seconds(), 'f', 0, 64),
+		fmt.Sprintf("%.2f", b.Coverage()),
+		strconv.FormatFloat(b.Down.Seconds(), 'f', 0, 64),
+		formatUptime(b),
+		strconv.Itoa(b.Events),
+		strconv.Itoa(b.Stats.Total),
+		strconv.Itoa(b.Stats.Lost),
+	}, b.Stats.row()...)
+}
+
+// formatUptime formats the bucket's uptime, marking buckets pingmonke
+// wasn't running for at all.
+func formatUptime(b ReportBucket) string {
+	if b.Uptime() < 0 {
+		return "not monitored"
+	}
+	return fmt.Sprintf("%.2f", b.Uptime())
+}
+
+// uptimeText formats the bucket's uptime as a percentage for the text report.
+func uptimeText(b ReportBucket) string {
+	if b.Uptime() < 0 {
+		return "not monitored"
+	}
+	return fmt.Sprintf("%.2f%%", b.Uptime())
+}
+
+// formatReportDuration formats long durations in days, hours and minutes.
+func formatReportDuration(d time.Duration) string {
+	if d < 24*time.Hour {
+		return d.Round(time.Second).String()
+	}
+	d = d.Round(time.Minute)
+	return fmt.Sprintf("%dd%dh%dm", d/(24*time.Hour), d%(24*time.Hour)/time.Hour, d%time.Hour/time.Minute)
+}
+
+// reportRange describes the days a report covers, To being exclusive.
+func (r *Report) reportRange() string {
+	return fmt.Sprintf("%s to %s (%s)", r.From.Format(time.DateOnly), r.To.Add(-time.Nanosecond).Format(time.DateOnly), r.From.Location())
+}
+
+func writeReportCSV(w io.Writer, r *Report) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"Report", r.reportRange()})
+	for _, tr := range r.Targets {
+		cw.Write([]string{})
+		cw.Write([]string{"Target", tr.Target.Label(), tr.Target.Host})
+		cw.Write(append([]string{"Period Files"}, reportBucketHeader...))
+		cw.Write(append([]string{strconv.Itoa(tr.Files)}, tr.Overall.row()...))
+
+		cw.Write([]string{})
+		cw.Write(append([]string{"Day"}, reportBucketHeader...))
+		for _, b := range tr.Days {
+			cw.Write(append([]string{b.Label}, b.row()...))
+		}
+
+		cw.Write([]string{})
+		cw.Write(append([]string{"Hour"}, reportBucketHeader...))
+		for _, b := range tr.Hours {
+			cw.Write(append([]string{b.Label}, b.row()...))
+		}
+
+		cw.Write([]string{})
+		cw.Write([]string{"Event Start", "End", "Duration (s)", "AF", "Class", "Severity", "Bad Pings", "Total Pings", "Statuses"})
+		for _, e := range tr.Events {
+			cw.Write([]string{
+				formatTimestamp(e.StartTime), formatTimestamp(e.EndTime),
+				strconv.FormatFloat(e.EndTime.Sub(e.StartTime).Seconds(), 'f', 3, 64),
+				e.Family, e.Class, fmt.Sprintf("%.1f", e.Severity),
+				strconv.Itoa(e.mix.bad()), strconv.Itoa(e.mix.total),
+				eventStatusBreakdown(tr.pings[e.StartIndex:e.EndIndex+1], tr.Target.Thresholds),
+			})
+		}
+
+		cw.Write([]string{})
+		cw.Write([]string{"Gap Start", "End", "Duration (s)"})
+		for _, g := range tr.Gaps {
+			cw.Write([]string{formatTimestamp(g.Start), formatTimestamp(g.End), strconv.FormatFloat(g.End.Sub(g.Start).Seconds(), 'f', 0, 64)})
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func writeReportText(w io.Writer, r *Report) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "pingmonke report, %s\n", r.reportRange())
+	for _, tr := range r.Targets {
+		o := tr.Overall
+		fmt.Fprintf(tw, "\n== %s (%s) ==\n", tr.Target.Label(), tr.Target.Host)
+		fmt.Fprintf(tw, "Period files:\t%d\n", tr.Files)
+		fmt.Fprintf(tw, "Monitored:\t%s of %s (%.2f%%), %d gaps\n", formatReportDuration(o.Monitored), formatReportDuration(o.Span), o.Coverage(), len(tr.Gaps))
+		fmt.Fprintf(tw, "Uptime:\t%s of monitored time, %s down in %d events\n", uptimeText(o), formatReportDuration(o.Down), o.Events)
+		fmt.Fprintf(tw, "Pings:\t%d, loss %.2f%% (longest streak %d), availability %.2f%%\n", o.Stats.Total, o.Stats.LossPct, o.Stats.LossStreak, o.Stats.Available)
+		fmt.Fprintf(tw, "Latency:\tp50 %dms, p90 %dms, p95 %dms, p99 %dms, max %dms, jitter %.1fms\n",
+			o.Stats.P50, o.Stats.P90, o.Stats.P95, o.Stats.P99, o.Stats.Max, o.Stats.Jitter)
+
+		writeBuckets := func(title string, buckets []ReportBucket) {
+			fmt.Fprintf(tw, "\n%s\tMONITORED\tUPTIME\tPINGS\tLOSS\tP50\tP95\tP99\tEVENTS\tDOWN\n", title)
+			for _, b := range buckets {
+				if b.Span == 0 {
+					continue // not reached yet
+				}
+				if b.Monitored == 0 {
+					fmt.Fprintf(tw, "%s\t0.0%%\tnot monitored\t\t\t\t\t\t\t\n", b.Label)
+					continue
+				}
+				fmt.Fprintf(tw, "%s\t%.1f%%\t%s\t%d\t%.2f%%\t%dms\t%dms\t%dms\t%d\t%s\n", b.Label, b.Coverage(), uptimeText(b),
+					b.Stats.Total, b.Stats.LossPct, b.Stats.P50, b.Stats.P95, b.Stats.P99, b.Events, formatReportDuration(b.Down))
+			}
+		}
+		writeBuckets("DAY", tr.Days)
+		writeBuckets("HOUR", tr.Hours[:])
+
+		fmt.Fprintf(tw, "\nEVENTS\tEND\tDURATION\tAF\tCLASS\tSEVERITY\tBAD PINGS\n")
+		for _, e := range tr.Events {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%.1f\t%d/%d\n", formatTimestamp(e.StartTime), formatTimestamp(e.EndTime),
+				formatReportDuration(e.EndTime.Sub(e.StartTime)), e.Family, e.Class, e.Severity, e.mix.bad(), e.mix.total)
+		}
+		fmt.Fprintf(tw, "%d events\n", len(tr.Events))
+
+		fmt.Fprintf(tw, "\nNOT MONITORED\tEND\tDURATION\n")
+		for _, g := range tr.Gaps {
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", formatTimestamp(g.Start), formatTimestamp(g.End), formatReportDuration(g.End.Sub(g.Start)))
+		}
+		fmt.Fprintf(tw, "%d gaps, not counted as uptime\n", len(tr.Gaps))
+	}
+	return tw.Flush()
+}
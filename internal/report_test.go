@@ -0,0 +1,134 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeReportLog writes a period file of pings every 15 seconds from start
+// until end, timing out from outage for outageFor.
+func writeReportLog(t *testing.T, path string, start, end, outage time.Time, outageFor time.Duration) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := csv.NewWriter(f)
+	w.Write(pingCSVHeader)
+	for at := start; at.Before(end); at = at.Add(15 * time.Second) {
+		latency, status := int64(20), "ok"
+		if !at.Before(outage) && at.Before(outage.Add(outageFor)) {
+			latency, status = 0, "timeout"
+		}
+		w.Write([]string{formatTimestamp(at), formatTimestamp(at.Add(time.Duration(latency) * time.Millisecond)), strconv.FormatInt(latency, 10), status, "v4"})
+	}
+	w.Flush()
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestReport tests aggregating several days of period files, with a day
+// pingmonke wasn't running
+func TestReport(t *testing.T) {
+	dir := t.TempDir()
+	target := TargetConfig{Name: "isp", Host: "192.0.2.1", Interval: 15 * time.Second, Thresholds: DefaultThresholds}
+	cfg := Config{LogDir: dir, Location: time.UTC, Targets: []TargetConfig{target}}
+	day := func(d, h int) time.Time { return time.Date(2026, 9, d, h, 0, 0, 0, time.UTC) }
+
+	// The first day stops at noon, with a ten minute outage at six; the
+	// second is missing; the third is complete and compressed by retention
+	writeReportLog(t, filepath.Join(dir, "2026-09-01-isp-pings.csv"), day(1, 0), day(1, 12), day(1, 6), 10*time.Minute)
+	third := filepath.Join(dir, "2026-09-03-isp-pings.csv")
+	writeReportLog(t, third, day(3, 0), day(4, 0), time.Time{}, 0)
+	if err := gzipFile(third, day(4, 0)); err != nil {
+		t.Fatal(err)
+	}
+	// Outside the range
+	writeReportLog(t, filepath.Join(dir, "2026-09-04-isp-pings.csv"), day(4, 0), day(4, 1), day(4, 0), time.Hour)
+
+	report, err := buildReport(cfg, ReportOptions{From: day(1, 0), To: day(4, 0)}, day(10, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := report.Targets[0]
+	if tr.Files != 2 || len(tr.Days) != 3 {
+		t.Fatalf("Expected 2 files over 3 days, got %d over %d", tr.Files, len(tr.Days))
+	}
+	if len(tr.Events) != 1 || !tr.Events[0].StartTime.Equal(day(1, 6)) || tr.Events[0].Class != EventOutage {
+		t.Fatalf("Expected the outage at six, got %+v", tr.Events)
+	}
+
+	o := tr.Overall
+	if o.Span != 72*time.Hour || o.Monitored != 36*time.Hour || o.Down != 10*time.Minute || o.Events != 1 {
+		t.Errorf("Unexpected overall %+v", o)
+	}
+	if want := 100 * float64(36*time.Hour-10*time.Minute) / float64(36*time.Hour); o.Uptime() != want {
+		t.Errorf("Expected uptime %.4f over the monitored time only, got %.4f", want, o.Uptime())
+	}
+	if o.Stats.Lost != 40 || o.Stats.Total != 36*240 {
+		t.Errorf("Expected 40 lost of %d pings, got %+v", 36*240, o.Stats)
+	}
+
+	if d := tr.Days[0]; d.Label != "2026-09-01" || d.Monitored != 12*time.Hour || d.Coverage() != 50 || d.Events != 1 {
+		t.Errorf("Unexpected first day %+v", d)
+	}
+	if d := tr.Days[1]; d.Monitored != 0 || d.Uptime() != -1 || d.Stats.Total != 0 {
+		t.Errorf("Expected the second day not monitored, got %+v", d)
+	}
+	if d := tr.Days[2]; d.Coverage() != 100 || d.Uptime() != 100 {
+		t.Errorf("Unexpected third day %+v", d)
+	}
+
+	if h := tr.Hours[6]; h.Span != 3*time.Hour || h.Monitored != 2*time.Hour || h.Down != 10*time.Minute || h.Events != 1 {
+		t.Errorf("Unexpected six o'clock %+v", h)
+	}
+	if h := tr.Hours[13]; h.Monitored != time.Hour || h.Stats.Total != 240 {
+		t.Errorf("Unexpected one o'clock %+v", h)
+	}
+
+	if len(tr.Gaps) != 1 || !tr.Gaps[0].Start.Equal(day(1, 12)) || !tr.Gaps[0].End.Equal(day(3, 0)) {
+		t.Errorf("Expected one gap from noon on the first day, got %+v", tr.Gaps)
+	}
+
+	// A range that hasn't finished yet only counts time up to now
+	report, _ = buildReport(cfg, ReportOptions{From: day(3, 0), To: day(5, 0)}, day(3, 18))
+	if o := report.Targets[0].Overall; o.Span != 18*time.Hour || o.Coverage() != 100 {
+		t.Errorf("Expected the report to stop at now, got %+v", o)
+	}
+
+	var text, table bytes.Buffer
+	report, _ = buildReport(cfg, ReportOptions{From: day(1, 0), To: day(4, 0)}, day(10, 0))
+	if err := writeReportText(&text, report); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"2026-09-02  0.0%", "not monitored", "2026-09-01 12:00:00.000", "outage", "1 gaps, not counted as uptime"} {
+		if !strings.Contains(text.String(), want) {
+			t.Errorf("Expected %q in the report:\n%s", want, text.String())
+		}
+	}
+	if err := writeReportCSV(&table, report); err != nil {
+		t.Fatal(err)
+	}
+	r := csv.NewReader(strings.NewReader(table.String()))
+	r.FieldsPerRecord = -1
+	rows, err := r.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, row := range rows {
+		if row[0] == "2026-09-02" && row[5] == "not monitored" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Expected the missing day marked in the CSV, got %v", rows)
+	}
+}
@@ -0,0 +1,158 @@
+// internal/retention.go
+// @version retention
+// @description Log retention: gzip period files after compress_after_days and delete them after keep_days
+
+package internal
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// gzipExt is appended to period files compressed by retention.
+const gzipExt = ".gz"
+
+// logRetention prunes the log directory in the background, one pass at a
+// time, so a slow disk never delays the next period's probes.
+type logRetention struct {
+	cfg     Config
+	running atomic.Bool
+}
+
+func newLogRetention(cfg Config) *logRetention {
+	return &logRetention{cfg: cfg}
+}
+
+// run starts a pass over the log directory, leaving the period starting at
+// current alone. It does nothing if retention is off or a pass is still going.
+func (r *logRetention) run(current time.Time) {
+	if r.cfg.KeepDays == 0 && r.cfg.CompressAfterDays == 0 {
+		return
+	}
+	if !r.running.CompareAndSwap(false, true) {
+		return
+	}
+	go func() {
+		defer r.running.Store(false)
+		compressed, deleted, err := pruneLogDir(r.cfg, current, time.Now())
+		if compressed > 0 || deleted > 0 {
+			fmt.Printf("[Retention] Compressed %d and deleted %d old log files\n", compressed, deleted)
+		}
+		if err != nil {
+			fmt.Println("[Retention] Error:", err)
+		}
+	}()
+}
+
+// isPeriodFile reports whether name is a ping log or summary, gzipped or not.
+func isPeriodFile(name string) bool {
+	name = strings.TrimSuffix(name, gzipExt)
+	return IsPingLogFile(name) || strings.HasSuffix(name, "-pings-summary.csv") || strings.HasSuffix(name, "-pings-summary.html")
+}
+
+// pruneLogDir deletes period files last written more than keep_days before
+// now and gzips those older than compress_after_days. Files of the period
+// starting at current are never touched.
+func pruneLogDir(cfg Config, current, now time.Time) (compressed, deleted int, err error) {
+	dir := ExpandHome(cfg.LogDir)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return 0, 0, err
+	}
+	currentStamp := current.Format(cfg.periodSpec().layout)
+	keep := time.Duration(cfg.KeepDays) * 24 * time.Hour
+	compressAfter := time.Duration(cfg.CompressAfterDays) * 24 * time.Hour
+
+	var errs error
+	for _, entry := range entries {
+		name := entry.Name()
+		path := filepath.Join(dir, name)
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		if tmp, ok := strings.CutSuffix(name, gzipExt+".tmp"); ok && isPeriodFile(tmp) {
+			os.Remove(path) // left by a pass that was interrupted
+			continue
+		}
+		if !isPeriodFile(name) {
+			continue
+		}
+		if stamp, _ := splitLogFileName(name); stamp == currentStamp {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue // removed since ReadDir
+		}
+
+		age := now.Sub(info.ModTime())
+		switch {
+		case keep > 0 && age > keep:
+			if err := os.Remove(path); err != nil {
+				errs = errors.Join(errs, err)
+				continue
+			}
+			deleted++
+		case compressAfter > 0 && age > compressAfter && !strings.HasSuffix(name, gzipExt):
+			if err := gzipFile(path, info.ModTime()); err != nil {
+				errs = errors.Join(errs, err)
+				continue
+			}
+			compressed++
+		}
+	}
+	return compressed, deleted, errs
+}
+
+// gzipFile replaces path with path.gz, keeping its modification time so its
+// age still counts towards keep_days. The original is only removed once the
+// compressed copy is complete.
+func gzipFile(path string, modTime time.Time) (err error) {
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	tmp := path + gzipExt + ".tmp"
+	out, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			out.Close()
+			os.Remove(tmp)
+		}
+	}()
+
+	zw := gzip.NewWriter(out)
+	zw.Name, zw.ModTime = filepath.Base(path), modTime
+	if _, err = io.Copy(zw, in); err != nil {
+		return err
+	}
+	if err = zw.Close(); err != nil {
+		return err
+	}
+	if err = out.Sync(); err != nil {
+		return err
+	}
+	if err = out.Close(); err != nil {
+		return err
+	}
+	if err = os.Chtimes(tmp, modTime, modTime); err != nil {
+		return err
+	}
+	if err = os.Rename(tmp, path+gzipExt); err != nil {
+		return err
+	}
+	in.Close()
+	return os.Remove(path)
+}
@@ -0,0 +1,117 @@
+package internal
+
+import (
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeAgedFile writes a ping log with statuses, last modified age before now
+func writeAgedFile(t *testing.T, path string, now time.Time, age time.Duration, statuses ...string) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := csv.NewWriter(f)
+	w.Write(pingCSVHeader)
+	base := time.Date(2026, 1, 7, 14, 0, 0, 0, time.UTC)
+	for i, status := range statuses {
+		at := base.Add(time.Duration(i) * 15 * time.Second)
+		w.Write([]string{formatTimestamp(at), formatTimestamp(at), "10", status, "v4"})
+	}
+	w.Flush()
+	f.Close()
+	if err := os.Chtimes(path, now.Add(-age), now.Add(-age)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestPruneLogDir tests compressing and deleting period files by age
+func TestPruneLogDir(t *testing.T) {
+	dir := t.TempDir()
+	cfg := Config{LogDir: dir, KeepDays: 30, CompressAfterDays: 7}
+	SetDefaults(&cfg)
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+
+	writeAgedFile(t, filepath.Join(dir, "2026-01-07-isp-pings.csv"), now, 40*day, "ok")
+	writeAgedFile(t, filepath.Join(dir, "2026-01-07-isp-pings-summary.csv"), now, 40*day)
+	writeAgedFile(t, filepath.Join(dir, "2026-02-01-isp-pings.csv.gz"), now, 31*day)
+	writeAgedFile(t, filepath.Join(dir, "2026-02-10-isp-pings.csv"), now, 10*day, "ok", "timeout")
+	writeAgedFile(t, filepath.Join(dir, "2026-02-10-isp-pings-summary.csv"), now, 10*day)
+	writeAgedFile(t, filepath.Join(dir, "2026-02-28-isp-pings.csv"), now, 2*day, "ok")
+	writeAgedFile(t, filepath.Join(dir, "2026-02-20-isp-pings.csv.gz.tmp"), now, 0)
+	writeAgedFile(t, filepath.Join(dir, "notes.csv"), now, 100*day)
+	// The current period is never touched, however old its file looks
+	writeAgedFile(t, filepath.Join(dir, "2026-03-01-isp-pings.csv"), now, 50*day, "ok")
+
+	compressed, deleted, err := pruneLogDir(cfg, time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if compressed != 2 || deleted != 3 {
+		t.Errorf("Expected 2 compressed and 3 deleted, got %d and %d", compressed, deleted)
+	}
+
+	var names []string
+	entries, _ := os.ReadDir(dir)
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	want := []string{
+		"2026-02-10-isp-pings-summary.csv.gz", "2026-02-10-isp-pings.csv.gz", "2026-02-28-isp-pings.csv",
+		"2026-03-01-isp-pings.csv", "notes.csv",
+	}
+	if len(names) != len(want) {
+		t.Fatalf("Expected %v, got %v", want, names)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Fatalf("Expected %v, got %v", want, names)
+		}
+	}
+
+	// Compressed files keep their age, so they are still deleted on time
+	gz := filepath.Join(dir, "2026-02-10-isp-pings.csv.gz")
+	if info, err := os.Stat(gz); err != nil || !info.ModTime().Equal(now.Add(-10*day)) {
+		t.Errorf("Expected the original modification time, got %v %v", info.ModTime(), err)
+	}
+	if _, deleted, _ := pruneLogDir(cfg, now, now.Add(21*day)); deleted != 2 {
+		t.Errorf("Expected both compressed files deleted after keep_days, got %d", deleted)
+	}
+}
+
+// TestReadGzippedLog tests that tailmonke and the summaries read compressed logs
+func TestReadGzippedLog(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "2026-01-07-isp-pings.csv")
+	now := time.Now()
+	writeAgedFile(t, path, now, time.Hour, "ok", "timeout", "timeout", "ok", "ok", "ok", "ok", "ok", "ok")
+	if err := gzipFile(path, now.Add(-time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	path += gzipExt
+	if _, err := os.Stat(path); err != nil {
+		t.Fatal(err)
+	}
+
+	lines, err := ReadPingFile(path)
+	if err != nil || len(lines) != 9 || lines[1].Status != "timeout" {
+		t.Fatalf("Unexpected lines %+v %v", lines, err)
+	}
+	if LogFileTarget(path) != "isp" {
+		t.Errorf("Expected target isp, got %q", LogFileTarget(path))
+	}
+	pings, err := readPingRecords(path)
+	if err != nil || len(NewEventDetector(DefaultEventRules, DefaultThresholds).Detect(pings)) != 1 {
+		t.Errorf("Expected 1 event from the compressed log, got %v", err)
+	}
+
+	generateSummary(path, Config{}, DefaultThresholds)
+	if _, err := os.Stat(filepath.Join(dir, "2026-01-07-isp-pings-summary.csv")); err != nil {
+		t.Errorf("Expected the summary next to the compressed log: %v", err)
+	}
+}
@@ -1,62 +1,151 @@
 package internal
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"sync"
 	"time"
 )
 
-func StartScheduler(cfg Config) {
-	// Set interval based on debug mode
-	interval := cfg.Interval
-	if cfg.DebugMode {
-		interval = cfg.DebugInterval
-	}
+// ErrShutdownTimeout is returned by StartScheduler when probes were still in
+// flight at the shutdown deadline and had to be abandoned.
+var ErrShutdownTimeout = errors.New("shutdown deadline passed with probes still in flight")
+
+// StartScheduler probes every target until ctx is cancelled, handing each
+// result to the configured sinks and recording it in state (which may be
+// nil). On cancellation it stops scheduling, gives in-flight probes up to
+// cfg.ShutdownTimeout to finish, and closes the partial period on the sinks
+// (writing its summaries) before returning.
+func StartScheduler(ctx context.Context, cfg Config, state *LiveState) error {
+	targets := cfg.Targets
+
+	// Probes outlive ctx so they can finish during shutdown; they are only
+	// cut off once the shutdown deadline passes.
+	probeCtx, abandonProbes := context.WithCancel(context.WithoutCancel(ctx))
+	defer abandonProbes()
+
+	sinks := openSinks(cfg, state)
+	defer sinks.Close()
+	retention := newLogRetention(cfg)
 
 	for {
 		periodStart, periodEnd := calculatePeriod(cfg)
-		logFile := prepareLogFile(periodStart, cfg)
 
-		fmt.Printf("[Scheduler] New period: %v to %v\n", periodStart, periodEnd)
+		// Each target gets its own log file and its own schedule within the period
+		sinks.OpenPeriod(periodStart, targets)
+		retention.run(periodStart) // compress and prune earlier periods' files
+		nextPing := make([]time.Time, len(targets))
+		for i, target := range targets {
+			nextPing[i] = alignToSchedule(periodStart, target.interval(cfg))
+		}
+
+		fmt.Printf("[Scheduler] New period: %v to %v (%d targets)\n", periodStart, periodEnd, len(targets))
 
 		var wg sync.WaitGroup
 
-		for time.Now().Before(periodEnd) {
-			nextPingTime := alignToSchedule(periodStart, interval)
-			spawnTime := maxTime(time.Now(), nextPingTime)
+		for ctx.Err() == nil {
+			// Pick the target whose next ping is due first
+			due := 0
+			for i := range nextPing {
+				if nextPing[i].Before(nextPing[due]) {
+					due = i
+				}
+			}
+			if nextPing[due].After(periodEnd) {
+				break
+			}
 
-			sleepUntil(spawnTime)
-			wg.Add(1)
-			go spawnPing(cfg.Target, cfg.Port, cfg.UseICMP, logFile, cfg.Verbose, &wg)
+			if !sleepUntil(ctx, maxTime(time.Now(), nextPing[due])) {
+				break
+			}
+			target := targets[due]
+			for _, family := range probeFamilies(target.AddressFamily) {
+				wg.Add(1)
+				go spawnPing(probeCtx, cfg, target, family, sinks, sinks.Reserve(due), &wg)
+			}
+			nextPing[due] = alignToSchedule(periodStart, target.interval(cfg))
 		}
 
-		// Wait for all pings in this period to complete
-		wg.Wait()
-		generateSummary(logFile, cfg)
+		if stop, err := endPeriod(ctx, cfg, &wg, sinks, abandonProbes); stop {
+			return err
+		}
 
 		// Sleep until next period to avoid busy loop at rollover
-		sleepUntil(periodEnd.Add(100 * time.Millisecond))
+		if !sleepUntil(ctx, periodEnd.Add(100*time.Millisecond)) {
+			fmt.Println("[Shutdown] Stopped between periods")
+			return nil
+		}
 	}
 }
 
-func calculatePeriod(cfg Config) (time.Time, time.Time) {
-	now := time.Now()
-	if cfg.DebugMode {
-		start := now.Truncate(time.Minute)
-		return start, start.Add(60 * time.Second)
+// endPeriod waits for the period's probes and closes it on the sinks. If a
+// shutdown arrives first, the probes get cfg.ShutdownTimeout to finish before
+// they are abandoned, and endPeriod reports that the scheduler should stop,
+// with ErrShutdownTimeout if any probes were abandoned.
+func endPeriod(ctx context.Context, cfg Config, wg *sync.WaitGroup, sinks *resultSinks, abandonProbes func()) (stop bool, err error) {
+	select {
+	case <-waitChan(wg):
+	case <-ctx.Done():
+	}
+
+	if ctx.Err() != nil {
+		fmt.Printf("[Shutdown] Stopped scheduling, waiting up to %v for in-flight probes\n", cfg.ShutdownTimeout)
+		if !waitTimeout(wg, cfg.ShutdownTimeout) {
+			abandonProbes()
+			wg.Wait()
+			err = ErrShutdownTimeout
+		}
+		sinks.ClosePeriod()
+		fmt.Println("[Shutdown] Closed the partial period")
+		return true, err
 	}
-	start := time.Now().Truncate(24 * time.Hour)
-	return start, start.Add(24 * time.Hour)
+
+	sinks.ClosePeriod()
+	return false, nil
+}
+
+func calculatePeriod(cfg Config) (time.Time, time.Time) {
+	return cfg.periodSpec().bounds(time.Now(), cfg.location())
 }
 
 func alignToSchedule(base time.Time, interval time.Duration) time.Time {
-	elapsed := time.Since(base)
-	n := int(elapsed.Seconds()) / int(interval.Seconds())
-	return base.Add(time.Duration(n+1) * interval)
+	n := time.Since(base) / interval
+	return base.Add((n + 1) * interval)
+}
+
+// sleepUntil waits until t, returning false if ctx is cancelled first.
+func sleepUntil(ctx context.Context, t time.Time) bool {
+	timer := time.NewTimer(time.Until(t))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// waitChan returns a channel that is closed once wg is done.
+func waitChan(wg *sync.WaitGroup) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	return done
 }
 
-func sleepUntil(t time.Time) {
-	time.Sleep(time.Until(t))
+// waitTimeout waits for wg, returning false if it takes longer than d.
+func waitTimeout(wg *sync.WaitGroup, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-waitChan(wg):
+		return true
+	case <-timer.C:
+		return false
+	}
 }
 
 func maxTime(a, b time.Time) time.Time {
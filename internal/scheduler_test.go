@@ -1,6 +1,15 @@
 package internal
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -27,7 +36,9 @@ func TestCalculatePeriodDebugMode(t *testing.T) {
 
 // TestCalculatePeriodNormalMode tests period calculation in normal mode
 func TestCalculatePeriodNormalMode(t *testing.T) {
-	cfg := Config{DebugMode: false}
+	// Periods follow the calendar day of the configured zone; UTC days are
+	// always 24 hours and start where Truncate puts them
+	cfg := Config{DebugMode: false, Location: time.UTC}
 	// In normal mode, periods should be 24 hours
 	periodStart, periodEnd := calculatePeriod(cfg)
 
@@ -104,6 +115,18 @@ func TestAlignToSchedule(t *testing.T) {
 	}
 }
 
+// TestAlignToScheduleNow tests the next ping time from the current time
+func TestAlignToScheduleNow(t *testing.T) {
+	base := time.Now().Add(-2500 * time.Millisecond)
+	if got := alignToSchedule(base, time.Second); !got.Equal(base.Add(3 * time.Second)) {
+		t.Errorf("Expected the next ping 3s after base, got %v", got.Sub(base))
+	}
+	// Sub-second intervals don't round down to zero
+	if got := alignToSchedule(base, 400*time.Millisecond); !got.Equal(base.Add(2800 * time.Millisecond)) {
+		t.Errorf("Expected the next ping 2.8s after base, got %v", got.Sub(base))
+	}
+}
+
 // TestMaxTime tests the maxTime utility function
 func TestMaxTime(t *testing.T) {
 	testCases := []struct {
@@ -181,6 +204,216 @@ func TestScheduleIntervalCalculations(t *testing.T) {
 	}
 }
 
+// newShutdownConfig returns a debug-mode config probing url over HTTP every second
+func newShutdownConfig(t *testing.T, url string) Config {
+	t.Helper()
+	cfg := Config{
+		LogDir:          t.TempDir(),
+		DebugMode:       true,
+		DebugInterval:   time.Second,
+		ShutdownTimeout: 300 * time.Millisecond,
+		AddressFamily:   "v4",
+		Targets:         []TargetConfig{{Name: "web", Probe: "http", HTTP: HTTPProbeConfig{URL: url}}},
+	}
+	SetDefaults(&cfg)
+	return cfg
+}
+
+// TestStartSchedulerShutdown tests that cancellation flushes a summary for the partial period
+func TestStartSchedulerShutdown(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "ok")
+	}))
+	defer srv.Close()
+	cfg := newShutdownConfig(t, srv.URL)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2500*time.Millisecond)
+	defer cancel()
+	if err := StartScheduler(ctx, cfg, nil); err != nil {
+		t.Fatalf("Expected clean shutdown, got %v", err)
+	}
+
+	summaries, _ := filepath.Glob(filepath.Join(cfg.LogDir, "*-web-pings-summary.csv"))
+	if len(summaries) == 0 {
+		t.Fatal("Expected a summary for the partial period")
+	}
+	data, err := os.ReadFile(summaries[len(summaries)-1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(data), "Total,") {
+		t.Errorf("Unexpected summary contents: %q", data)
+	}
+}
+
+// TestStartSchedulerShutdownDeadline tests that stuck probes are abandoned at the deadline
+func TestStartSchedulerShutdownDeadline(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer srv.Close()
+	defer close(release)
+	cfg := newShutdownConfig(t, srv.URL)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1500*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err := StartScheduler(ctx, cfg, nil)
+	if !errors.Is(err, ErrShutdownTimeout) {
+		t.Fatalf("Expected ErrShutdownTimeout, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 1500*time.Millisecond+time.Second {
+		t.Errorf("Shutdown took %v, expected it to stop at the deadline", elapsed)
+	}
+
+	// The abandoned probe must not be logged as a timeout
+	files, _ := filepath.Glob(filepath.Join(cfg.LogDir, "*-web-pings.csv"))
+	for _, file := range files {
+		lines, err := ReadPingFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(lines) != 0 {
+			t.Errorf("Expected no rows for abandoned probes, got %d", len(lines))
+		}
+	}
+}
+
+// TestEndPeriodShutdown tests a shutdown arriving while the period's last
+// probes are still in flight
+func TestEndPeriodShutdown(t *testing.T) {
+	fast := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "ok")
+	}))
+	defer fast.Close()
+	release := make(chan struct{})
+	stuck := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer stuck.Close()
+	defer close(release)
+
+	cfg := newShutdownConfig(t, fast.URL)
+	cfg.Targets = append(cfg.Targets, TargetConfig{Name: "stuck", Probe: "http", HTTP: HTTPProbeConfig{URL: stuck.URL}})
+	SetDefaults(&cfg)
+
+	probeCtx, abandonProbes := context.WithCancel(context.Background())
+	defer abandonProbes()
+	sinks := openSinks(cfg, nil)
+	defer sinks.Close()
+	periodStart, _ := calculatePeriod(cfg)
+	sinks.OpenPeriod(periodStart, cfg.Targets)
+
+	var wg sync.WaitGroup
+	for i, target := range cfg.Targets {
+		wg.Add(1)
+		go spawnPing(probeCtx, cfg, target, "v4", sinks, sinks.Reserve(i), &wg)
+	}
+
+	// The shutdown lands while endPeriod waits on the stuck probe
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	stop, err := endPeriod(ctx, cfg, &wg, sinks, abandonProbes)
+	if !stop || !errors.Is(err, ErrShutdownTimeout) {
+		t.Fatalf("Expected to stop with ErrShutdownTimeout, got %v, %v", stop, err)
+	}
+	if elapsed := time.Since(start); elapsed > 200*time.Millisecond+cfg.ShutdownTimeout+time.Second {
+		t.Errorf("Shutdown took %v, expected it to stop at the deadline", elapsed)
+	}
+
+	summaries, _ := filepath.Glob(filepath.Join(cfg.LogDir, "*-web-pings-summary.csv"))
+	if len(summaries) != 1 {
+		t.Fatalf("Expected the partial period's summary, got %v", summaries)
+	}
+	data, err := os.ReadFile(summaries[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Split(strings.TrimSpace(string(data)), "\n"); len(lines) < 2 || !strings.HasPrefix(lines[1], "1,1,") {
+		t.Errorf("Expected the finished probe in the summary, got %q", data)
+	}
+}
+
+// TestWaitTimeout tests waiting on a WaitGroup with a deadline
+func TestWaitTimeout(t *testing.T) {
+	var wg sync.WaitGroup
+	if !waitTimeout(&wg, 10*time.Millisecond) {
+		t.Error("Expected an idle WaitGroup to finish immediately")
+	}
+	wg.Add(1)
+	if waitTimeout(&wg, 10*time.Millisecond) {
+		t.Error("Expected waitTimeout to give up on a busy WaitGroup")
+	}
+	wg.Done()
+}
+
+// TestPeriodAtLocalDay tests that periods follow the calendar day of the configured zone
+func TestPeriodAtLocalDay(t *testing.T) {
+	cst := time.FixedZone("CST", -6*60*60)
+	// 8pm on Jan 7 in Chicago is already Jan 8 in UTC
+	now := time.Date(2026, 1, 8, 2, 0, 0, 0, time.UTC)
+
+	start, end := dailyPeriod.bounds(now, cst)
+	if want := time.Date(2026, 1, 7, 0, 0, 0, 0, cst); !start.Equal(want) {
+		t.Errorf("Expected period to start at %v, got %v", want, start)
+	}
+	if end.Sub(start) != 24*time.Hour {
+		t.Errorf("Expected a 24h period, got %v", end.Sub(start))
+	}
+	if got := start.Format("2006-01-02"); got != "2026-01-07" {
+		t.Errorf("Expected file stamp 2026-01-07, got %s", got)
+	}
+
+	start, end = debugPeriod.bounds(time.Date(2026, 1, 8, 2, 30, 45, 0, time.UTC), cst)
+	if want := time.Date(2026, 1, 7, 20, 30, 0, 0, cst); !start.Equal(want) || end.Sub(start) != time.Minute {
+		t.Errorf("Expected debug period %v + 1m, got %v to %v", want, start, end)
+	}
+}
+
+// TestPeriodAtDST tests the 23- and 25-hour days around DST changes
+func TestPeriodAtDST(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		now  time.Time
+		want time.Duration
+	}{
+		{"Spring forward", time.Date(2026, 3, 8, 12, 0, 0, 0, ny), 23 * time.Hour},
+		{"Fall back", time.Date(2026, 11, 1, 12, 0, 0, 0, ny), 25 * time.Hour},
+		{"Ordinary day", time.Date(2026, 6, 1, 12, 0, 0, 0, ny), 24 * time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end := dailyPeriod.bounds(tt.now, ny)
+			if start.Hour() != 0 || end.Hour() != 0 {
+				t.Errorf("Expected period to run midnight to midnight, got %v to %v", start, end)
+			}
+			if got := end.Sub(start); got != tt.want {
+				t.Errorf("Expected a %v period, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+// TestLoadTimezone tests resolving the timezone setting
+func TestLoadTimezone(t *testing.T) {
+	if loadTimezone("") != time.Local || loadTimezone("Local") != time.Local {
+		t.Error("Expected empty and local timezones to use the system zone")
+	}
+	if loc := loadTimezone("Europe/Berlin"); loc.String() != "Europe/Berlin" {
+		t.Errorf("Expected Europe/Berlin, got %v", loc)
+	}
+	if loadTimezone("Mars/Olympus_Mons") != time.Local {
+		t.Error("Expected unknown timezone to fall back to the system zone")
+	}
+}
+
 // BenchmarkAlignToSchedule benchmarks the alignment calculation
 func BenchmarkAlignToSchedule(b *testing.B) {
 	baseTime := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
@@ -0,0 +1,381 @@
+// internal/sink.go
+// @version sinks
+// @description Pluggable destinations for probe results, each driven by its own goroutine
+
+package internal
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ResultSink is a destination for probe results. Every sink is driven by its
+// own goroutine, so its methods are never called concurrently: OpenPeriod at
+// the start of each period, Write for each result in the order the probes
+// started, Flush every log_flush_interval, ClosePeriod once the period's
+// probes are done, and Close at shutdown. Errors are reported per sink and
+// never reach the scheduler.
+type ResultSink interface {
+	Name() string
+	OpenPeriod(start time.Time, targets []TargetConfig) error
+	Write(target TargetConfig, result ProbeResult) error
+	Flush() error
+	ClosePeriod() error
+	Close() error
+}
+
+// SinkConfig enables one result sink.
+type SinkConfig struct {
+	Type     string `yaml:"type"`     // csv, jsonl, network or sqlite
+	Address  string `yaml:"address"`  // network: host:port to send JSON lines to
+	Protocol string `yaml:"protocol"` // network: tcp (default) or udp
+	Path     string `yaml:"path"`     // sqlite: database file (default log_dir/pingmonke.db)
+}
+
+const (
+	// sinkQueue is how many results a sink may fall behind before new ones are dropped
+	sinkQueue = 1024
+	// sinkLifecycleTimeout bounds how long the scheduler waits for a sink to
+	// open or close a period
+	sinkLifecycleTimeout = 30 * time.Second
+)
+
+// normalizeSinks validates the sinks setting, defaulting to a log file in
+// log_format.
+func normalizeSinks(cfg *Config) {
+	var sinks []SinkConfig
+	for _, sc := range cfg.Sinks {
+		sc.Type = strings.ToLower(strings.TrimSpace(sc.Type))
+		switch sc.Type {
+		case logFormatCSV, logFormatJSONL, "sqlite":
+		case "network":
+			if sc.Address == "" {
+				fmt.Println("[Config] Network sink has no address, skipping it")
+				continue
+			}
+			sc.Protocol = strings.ToLower(sc.Protocol)
+			if sc.Protocol != "tcp" && sc.Protocol != "udp" {
+				if sc.Protocol != "" {
+					fmt.Printf("[Config] Unknown protocol %q for sink %s, using tcp\n", sc.Protocol, sc.Address)
+				}
+				sc.Protocol = "tcp"
+			}
+		default:
+			fmt.Printf("[Config] Unknown sink type %q, skipping it\n", sc.Type)
+			continue
+		}
+		sinks = append(sinks, sc)
+	}
+	if len(sinks) == 0 {
+		if len(cfg.Sinks) > 0 {
+			fmt.Printf("[Config] No usable sinks, logging to %s\n", cfg.LogFormat)
+		}
+		format := cfg.LogFormat
+		if format == "" {
+			format = logFormatCSV
+		}
+		sinks = []SinkConfig{{Type: format}}
+	}
+	cfg.Sinks = sinks
+}
+
+// newSink builds the sink sc describes.
+func newSink(cfg Config, sc SinkConfig) ResultSink {
+	switch sc.Type {
+	case "network":
+		return newNetSink(sc.Protocol, sc.Address)
+	case "sqlite":
+		path := sc.Path
+		if path == "" {
+			path = cfg.HistoryPath()
+		}
+		return newSQLiteSink(cfg, ExpandHome(path))
+	default:
+		return newFileSink(cfg, sc.Type)
+	}
+}
+
+// resultSinks fans probe results out to every sink and the live state.
+// Probes reserve a slot per target when they are scheduled, and results are
+// handed on in slot order, so sinks and event detection see each target's
+// results sorted by start time even when a slow probe finishes after a later
+// one.
+type resultSinks struct {
+	runners []*sinkRunner
+	state   *LiveState // may be nil
+
+	mu      sync.Mutex
+	targets []TargetConfig
+	orders  []*resultOrder // per target of the open period
+}
+
+// resultSlot is a probe's place in its target's result order.
+type resultSlot struct {
+	target int
+	seq    uint64
+}
+
+type resultOrder struct {
+	reserved uint64
+	next     uint64
+	pending  map[uint64]*ProbeResult // arrived ahead of an earlier slot; nil for a skipped one
+}
+
+// openSinks starts a runner for each configured sink, also recording results
+// in state (which may be nil). Log files in both formats share a summary
+// name, so only the first log file sink writes it.
+func openSinks(cfg Config, state *LiveState) *resultSinks {
+	s := &resultSinks{state: state}
+	summarized := false
+	for _, sc := range cfg.Sinks {
+		sink := newSink(cfg, sc)
+		if fs, ok := sink.(*fileSink); ok {
+			fs.summarize = !summarized
+			summarized = true
+		}
+		s.runners = append(s.runners, startSinkRunner(sink, cfg.LogFlushInterval))
+	}
+	return s
+}
+
+// OpenPeriod starts a period for targets on every sink.
+func (s *resultSinks) OpenPeriod(start time.Time, targets []TargetConfig) {
+	s.mu.Lock()
+	s.targets = targets
+	s.orders = make([]*resultOrder, len(targets))
+	for i := range s.orders {
+		s.orders[i] = &resultOrder{pending: map[uint64]*ProbeResult{}}
+	}
+	s.mu.Unlock()
+
+	for _, r := range s.runners {
+		r.lifecycle(sinkOp{kind: opOpen, start: start, targets: targets})
+	}
+}
+
+// Reserve returns the slot for a probe of target i about to start. Every
+// slot must be filled with Write or Skip, or later results are held back
+// until ClosePeriod.
+func (s *resultSinks) Reserve(i int) resultSlot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	order := s.orders[i]
+	order.reserved++
+	return resultSlot{target: i, seq: order.reserved - 1}
+}
+
+// Write hands the result of the probe in slot on to the sinks.
+func (s *resultSinks) Write(slot resultSlot, result ProbeResult) {
+	s.fill(slot, &result)
+}
+
+// Skip releases slot without a result.
+func (s *resultSinks) Skip(slot resultSlot) {
+	s.fill(slot, nil)
+}
+
+func (s *resultSinks) fill(slot resultSlot, result *ProbeResult) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	order := s.orders[slot.target]
+	order.pending[slot.seq] = result
+	for {
+		result, ok := order.pending[order.next]
+		if !ok {
+			break
+		}
+		delete(order.pending, order.next)
+		order.next++
+		if result != nil {
+			s.send(slot.target, *result)
+		}
+	}
+}
+
+// send queues result on every sink and records it in the live state. Called
+// with s.mu held, so results keep their order; a sink that has fallen behind
+// drops it instead of blocking.
+func (s *resultSinks) send(target int, result ProbeResult) {
+	for _, r := range s.runners {
+		r.enqueue(sinkOp{kind: opWrite, target: s.targets[target], result: result})
+	}
+	s.state.Record(s.targets[target], result)
+}
+
+// ClosePeriod hands on results still held for unfilled slots and closes the
+// period on every sink, waiting for each to finish.
+func (s *resultSinks) ClosePeriod() {
+	s.mu.Lock()
+	for i, order := range s.orders {
+		for ; len(order.pending) > 0; order.next++ {
+			if result, ok := order.pending[order.next]; ok {
+				delete(order.pending, order.next)
+				if result != nil {
+					s.send(i, *result)
+				}
+			}
+		}
+	}
+	s.mu.Unlock()
+
+	for _, r := range s.runners {
+		r.lifecycle(sinkOp{kind: opClose})
+	}
+}
+
+// Close stops every sink.
+func (s *resultSinks) Close() {
+	for _, r := range s.runners {
+		r.stop()
+	}
+}
+
+const (
+	opOpen = iota
+	opWrite
+	opClose
+)
+
+type sinkOp struct {
+	kind    int
+	start   time.Time
+	targets []TargetConfig
+	target  TargetConfig
+	result  ProbeResult
+	done    chan struct{} // closed once a lifecycle op has run
+}
+
+// sinkRunner owns one sink and calls it from a single goroutine.
+type sinkRunner struct {
+	sink    ResultSink
+	ops     chan sinkOp
+	stopped chan struct{}
+	dropped atomic.Int64
+	failed  bool   // the sink panicked and is disabled
+	lastErr string // last error printed, to avoid repeating it every ping
+}
+
+func startSinkRunner(sink ResultSink, flushEvery time.Duration) *sinkRunner {
+	r := &sinkRunner{
+		sink:    sink,
+		ops:     make(chan sinkOp, sinkQueue),
+		stopped: make(chan struct{}),
+	}
+	go r.run(flushEvery)
+	return r
+}
+
+// enqueue queues a result without blocking, counting it as dropped if the
+// sink is too far behind.
+func (r *sinkRunner) enqueue(op sinkOp) {
+	select {
+	case r.ops <- op:
+	default:
+		if r.dropped.Add(1) == 1 {
+			fmt.Printf("[Sink] %s is falling behind, dropping results\n", r.sink.Name())
+		}
+	}
+}
+
+// lifecycle runs a period open or close on the sink, giving up on a sink
+// that doesn't get to it within sinkLifecycleTimeout.
+func (r *sinkRunner) lifecycle(op sinkOp) {
+	op.done = make(chan struct{})
+	timer := time.NewTimer(sinkLifecycleTimeout)
+	defer timer.Stop()
+	select {
+	case r.ops <- op:
+	case <-timer.C:
+		fmt.Printf("[Sink] %s is not responding, skipping period change\n", r.sink.Name())
+		return
+	}
+	select {
+	case <-op.done:
+	case <-timer.C:
+		fmt.Printf("[Sink] %s is not responding, moving on without it\n", r.sink.Name())
+	}
+}
+
+// stop closes the sink once its queue has drained, waiting up to
+// sinkLifecycleTimeout.
+func (r *sinkRunner) stop() {
+	close(r.ops)
+	select {
+	case <-r.stopped:
+	case <-time.After(sinkLifecycleTimeout):
+		fmt.Printf("[Sink] %s did not stop in time\n", r.sink.Name())
+	}
+}
+
+func (r *sinkRunner) run(flushEvery time.Duration) {
+	defer close(r.stopped)
+
+	var tick <-chan time.Time
+	if flushEvery > 0 {
+		ticker := time.NewTicker(flushEvery)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case op, ok := <-r.ops:
+			if !ok {
+				r.call("flush", r.sink.Flush)
+				r.call("close", r.sink.Close)
+				return
+			}
+			r.do(op, tick == nil)
+			if op.done != nil {
+				close(op.done)
+			}
+		case <-tick:
+			r.call("flush", r.sink.Flush)
+		}
+	}
+}
+
+func (r *sinkRunner) do(op sinkOp, flushEach bool) {
+	switch op.kind {
+	case opOpen:
+		if n := r.dropped.Swap(0); n > 0 {
+			fmt.Printf("[Sink] %s dropped %d results last period\n", r.sink.Name(), n)
+		}
+		r.call("open period", func() error { return r.sink.OpenPeriod(op.start, op.targets) })
+	case opWrite:
+		r.call("write", func() error { return r.sink.Write(op.target, op.result) })
+		if flushEach {
+			r.call("flush", r.sink.Flush)
+		}
+	case opClose:
+		r.call("flush", r.sink.Flush)
+		r.call("close period", r.sink.ClosePeriod)
+	}
+}
+
+// call runs fn, reporting errors once until the sink recovers. A sink that
+// panics is disabled rather than taking the service down.
+func (r *sinkRunner) call(what string, fn func() error) {
+	if r.failed {
+		return
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			r.failed = true
+			fmt.Printf("[Sink] %s panicked during %s, disabling it: %v\n", r.sink.Name(), what, p)
+		}
+	}()
+
+	err := fn()
+	switch {
+	case err == nil && r.lastErr != "":
+		fmt.Printf("[Sink] %s recovered\n", r.sink.Name())
+		r.lastErr = ""
+	case err != nil && err.Error() != r.lastErr:
+		fmt.Printf("[Sink] %s %s error: %v\n", r.sink.Name(), what, err)
+		r.lastErr = err.Error()
+	}
+}
@@ -0,0 +1,406 @@
+package internal
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeSink records what it is given, optionally blocking or failing
+type fakeSink struct {
+	mu      sync.Mutex
+	calls   []string
+	block   chan struct{} // Write waits on it when set
+	failing bool
+	panics  bool
+	flushes atomic.Int64
+}
+
+func (s *fakeSink) Name() string { return "fake" }
+
+func (s *fakeSink) log(call string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls = append(s.calls, call)
+}
+
+func (s *fakeSink) got() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.calls...)
+}
+
+func (s *fakeSink) OpenPeriod(start time.Time, targets []TargetConfig) error {
+	s.log("open")
+	return nil
+}
+
+func (s *fakeSink) Write(target TargetConfig, result ProbeResult) error {
+	if s.block != nil {
+		<-s.block
+	}
+	if s.panics {
+		panic("boom")
+	}
+	s.log(target.Label() + " " + result.StartTime.Format("15:04:05") + " " + result.Status)
+	if s.failing {
+		return errors.New("disk full")
+	}
+	return nil
+}
+
+func (s *fakeSink) Flush() error       { s.flushes.Add(1); return nil }
+func (s *fakeSink) ClosePeriod() error { s.log("close"); return nil }
+func (s *fakeSink) Close() error       { return nil }
+
+func newFakeSinks(sinks ...ResultSink) *resultSinks {
+	s := &resultSinks{}
+	for _, sink := range sinks {
+		s.runners = append(s.runners, startSinkRunner(sink, 0))
+	}
+	return s
+}
+
+func probeAt(at time.Time, status string) ProbeResult {
+	return ProbeResult{StartTime: at, EndTime: at, Status: status, Family: "v4", DNS: noPhase, Connect: noPhase, TLS: noPhase, TTFB: noPhase}
+}
+
+// TestResultSinksOrder tests that each target's results reach sinks in the order probes were scheduled
+func TestResultSinksOrder(t *testing.T) {
+	fake := &fakeSink{}
+	sinks := newFakeSinks(fake)
+	defer sinks.Close()
+	targets := []TargetConfig{{Name: "a"}, {Name: "b"}}
+	base := time.Date(2026, 1, 7, 14, 0, 0, 0, time.UTC)
+
+	sinks.OpenPeriod(base, targets)
+	a0, a1, a2, a3 := sinks.Reserve(0), sinks.Reserve(0), sinks.Reserve(0), sinks.Reserve(0)
+	b0 := sinks.Reserve(1)
+	// a's first probe is slow and its third is abandoned; b is unaffected
+	sinks.Write(a1, probeAt(base.Add(15*time.Second), "ok"))
+	sinks.Write(b0, probeAt(base.Add(5*time.Second), "ok"))
+	sinks.Skip(a2)
+	sinks.Write(a3, probeAt(base.Add(45*time.Second), "ok"))
+	sinks.Write(a0, probeAt(base, "timeout"))
+	sinks.ClosePeriod()
+
+	want := "open,b 14:00:05 ok,a 14:00:00 timeout,a 14:00:15 ok,a 14:00:45 ok,close"
+	if got := strings.Join(fake.got(), ","); got != want {
+		t.Errorf("Expected %s, got %s", want, got)
+	}
+}
+
+// TestResultSinksUnfilledSlot tests that results held behind a slot never filled are written at period end
+func TestResultSinksUnfilledSlot(t *testing.T) {
+	fake := &fakeSink{}
+	sinks := newFakeSinks(fake)
+	defer sinks.Close()
+	base := time.Date(2026, 1, 7, 14, 0, 0, 0, time.UTC)
+
+	sinks.OpenPeriod(base, []TargetConfig{{Name: "a"}})
+	sinks.Reserve(0) // never filled
+	sinks.Write(sinks.Reserve(0), probeAt(base, "ok"))
+	sinks.ClosePeriod()
+
+	if got := strings.Join(fake.got(), ","); got != "open,a 14:00:00 ok,close" {
+		t.Errorf("Unexpected calls %s", got)
+	}
+}
+
+// TestResultSinksLiveState tests that live state sees a target's results in
+// the order its probes started, so its events match the summary's even when
+// timed-out probes complete after later successful ones
+func TestResultSinksLiveState(t *testing.T) {
+	target := TargetConfig{Name: "isp", Thresholds: DefaultThresholds}
+	base := time.Date(2026, 1, 7, 12, 0, 0, 0, time.UTC)
+	var results []ProbeResult
+	for i := 0; i < 20; i++ {
+		status := "ok"
+		if i >= 4 && i < 10 {
+			status = "timeout"
+		}
+		results = append(results, probeAt(base.Add(time.Duration(i)*5*time.Second), status))
+	}
+	collect := func(state *LiveState) *[]string {
+		var notices []string
+		state.OnEvent(func(n EventNotice) { notices = append(notices, fmt.Sprintf("%+v", n)) })
+		return &notices
+	}
+
+	inOrder := NewLiveState([]TargetConfig{target})
+	want := collect(inOrder)
+	for _, result := range results {
+		inOrder.Record(target, result)
+	}
+
+	state := NewLiveState([]TargetConfig{target})
+	got := collect(state)
+	sinks := newFakeSinks()
+	sinks.state = state
+	sinks.OpenPeriod(base, []TargetConfig{target})
+	slots := make([]resultSlot, len(results))
+	for i := range results {
+		slots[i] = sinks.Reserve(0)
+	}
+	// Timeouts complete 15s after they were sent, behind three later pings
+	done := make([]int, len(results))
+	for i := range done {
+		done[i] = i
+	}
+	sort.SliceStable(done, func(a, b int) bool {
+		finish := func(i int) time.Duration {
+			if results[i].Status == "timeout" {
+				return time.Duration(i)*5*time.Second + 15*time.Second
+			}
+			return time.Duration(i) * 5 * time.Second
+		}
+		return finish(done[a]) < finish(done[b])
+	})
+	for _, i := range done {
+		sinks.Write(slots[i], results[i])
+	}
+	sinks.ClosePeriod()
+
+	if len(*want) != 2 {
+		t.Fatalf("Expected the outage to start and end, got %v", *want)
+	}
+	if strings.Join(*got, "\n") != strings.Join(*want, "\n") {
+		t.Errorf("Expected events\n%s\ngot\n%s", strings.Join(*want, "\n"), strings.Join(*got, "\n"))
+	}
+	recent := state.snapshot()[0].recent
+	for i := 1; i < len(recent); i++ {
+		if recent[i].StartTime < recent[i-1].StartTime {
+			t.Fatalf("Recent ping %d (%s) is out of order after %s", i, recent[i].StartTime, recent[i-1].StartTime)
+		}
+	}
+}
+
+// TestResultSinksIsolation tests that a stuck, failing or panicking sink doesn't hold up the others
+func TestResultSinksIsolation(t *testing.T) {
+	healthy := &fakeSink{}
+	stuck := &fakeSink{block: make(chan struct{})}
+	defer close(stuck.block)
+	sinks := newFakeSinks(stuck, &fakeSink{failing: true}, &fakeSink{panics: true}, healthy)
+	base := time.Date(2026, 1, 7, 14, 0, 0, 0, time.UTC)
+	sinks.OpenPeriod(base, []TargetConfig{{Name: "a"}})
+
+	// Keep pace with the healthy sink; only the stuck one should fall behind
+	deadline := time.Now().Add(10 * time.Second)
+	for i := 0; i < sinkQueue+100; i++ {
+		sinks.Write(sinks.Reserve(0), probeAt(base.Add(time.Duration(i)*time.Second), "ok"))
+		for len(healthy.got()) < i+2 {
+			if time.Now().After(deadline) {
+				t.Fatalf("Healthy sink stalled after %d results", len(healthy.got())-1)
+			}
+			time.Sleep(time.Millisecond / 10)
+		}
+	}
+	if dropped := sinks.runners[0].dropped.Load(); dropped == 0 {
+		t.Error("Expected the stuck sink to drop results once its queue filled")
+	}
+}
+
+// TestSinkRunnerFlushInterval tests that log_flush_interval batches flushes
+// onto its tick, and that 0 flushes after every result
+func TestSinkRunnerFlushInterval(t *testing.T) {
+	target := TargetConfig{Name: "a"}
+	base := time.Date(2026, 1, 7, 14, 0, 0, 0, time.UTC)
+	writeThree := func(r *sinkRunner) {
+		for i := 0; i < 3; i++ {
+			r.enqueue(sinkOp{kind: opWrite, target: target, result: probeAt(base.Add(time.Duration(i)*time.Second), "ok")})
+		}
+		r.lifecycle(sinkOp{kind: opOpen, start: base}) // runs once the writes have
+	}
+
+	each := &fakeSink{}
+	r := startSinkRunner(each, 0)
+	writeThree(r)
+	if n := each.flushes.Load(); n != 3 {
+		t.Errorf("Expected a flush per result with no interval, got %d", n)
+	}
+	r.stop()
+
+	batched := &fakeSink{}
+	r = startSinkRunner(batched, time.Hour)
+	writeThree(r)
+	if n := batched.flushes.Load(); n != 0 {
+		t.Errorf("Expected results held until the tick, got %d flushes", n)
+	}
+	r.stop()
+
+	ticking := &fakeSink{}
+	r = startSinkRunner(ticking, 20*time.Millisecond)
+	defer r.stop()
+	writeThree(r)
+	deadline := time.Now().Add(2 * time.Second)
+	for ticking.flushes.Load() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("Results were not flushed on the tick within 2s")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// TestFileSink tests writing a period through the log file sink in both formats
+func TestFileSink(t *testing.T) {
+	for _, format := range []string{logFormatCSV, logFormatJSONL} {
+		t.Run(format, func(t *testing.T) {
+			cfg := Config{LogDir: t.TempDir()}
+			SetDefaults(&cfg)
+			sink := newFileSink(cfg, format)
+			target := TargetConfig{Name: "gateway", Thresholds: DefaultThresholds}
+			start := time.Date(2026, 1, 7, 0, 0, 0, 0, time.UTC)
+
+			if err := sink.OpenPeriod(start, []TargetConfig{target}); err != nil {
+				t.Fatal(err)
+			}
+			for i, status := range []string{"ok", "timeout", "ok"} {
+				if err := sink.Write(target, probeAt(start.Add(time.Duration(i)*15*time.Second), status)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := sink.ClosePeriod(); err != nil {
+				t.Fatal(err)
+			}
+
+			path := filepath.Join(cfg.LogDir, "2026-01-07-gateway-pings."+format)
+			lines, err := ReadPingFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(lines) != 3 || lines[1].Status != "timeout" || lines[1].StartTime != "2026-01-07 00:00:15.000" {
+				t.Errorf("Unexpected rows %+v", lines)
+			}
+			if _, err := os.Stat(filepath.Join(cfg.LogDir, "2026-01-07-gateway-pings-summary.csv")); err != nil {
+				t.Errorf("Expected a summary at period close: %v", err)
+			}
+			if err := sink.Write(target, probeAt(start, "ok")); err == nil {
+				t.Error("Expected an error writing after the period closed")
+			}
+		})
+	}
+}
+
+// TestFileSinksBothFormats tests that with csv and jsonl logs enabled only the
+// first log file sink writes the shared summary
+func TestFileSinksBothFormats(t *testing.T) {
+	cfg := Config{LogDir: t.TempDir(), Sinks: []SinkConfig{{Type: logFormatJSONL}, {Type: logFormatCSV}}}
+	SetDefaults(&cfg)
+	target := TargetConfig{Name: "gateway", Thresholds: DefaultThresholds}
+	start := time.Date(2026, 1, 7, 0, 0, 0, 0, time.UTC)
+
+	sinks := openSinks(cfg, nil)
+	var summarizing []string
+	for _, r := range sinks.runners {
+		if fs, ok := r.sink.(*fileSink); ok && fs.summarize {
+			summarizing = append(summarizing, fs.format)
+		}
+	}
+	if len(summarizing) != 1 || summarizing[0] != logFormatJSONL {
+		t.Errorf("Expected only the jsonl sink to summarize, got %v", summarizing)
+	}
+
+	sinks.OpenPeriod(start, []TargetConfig{target})
+	for i, status := range []string{"ok", "timeout", "ok"} {
+		sinks.Write(sinks.Reserve(0), probeAt(start.Add(time.Duration(i)*15*time.Second), status))
+	}
+	sinks.ClosePeriod()
+	sinks.Close()
+
+	for _, format := range []string{logFormatCSV, logFormatJSONL} {
+		lines, err := ReadPingFile(filepath.Join(cfg.LogDir, "2026-01-07-gateway-pings."+format))
+		if err != nil || len(lines) != 3 {
+			t.Errorf("%s: expected 3 rows, got %d (%v)", format, len(lines), err)
+		}
+	}
+	summaries, _ := filepath.Glob(filepath.Join(cfg.LogDir, "*-summary.csv"))
+	if len(summaries) != 1 {
+		t.Fatalf("Expected one summary, got %v", summaries)
+	}
+	data, err := os.ReadFile(summaries[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "\n3,2,0,1,") {
+		t.Errorf("Expected the period's 3 pings in the summary, got\n%s", data)
+	}
+}
+
+// TestNetSink tests streaming JSON lines to a TCP collector
+func TestNetSink(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	received := make(chan probeRecord, 2)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		scanner := bufio.NewScanner(conn)
+		for scanner.Scan() {
+			var rec probeRecord
+			json.Unmarshal(scanner.Bytes(), &rec)
+			received <- rec
+		}
+	}()
+
+	sink := newNetSink("tcp", ln.Addr().String())
+	target := TargetConfig{Name: "web", Host: "example.com", Probe: "http"}
+	at := time.Date(2026, 1, 7, 14, 0, 0, 0, time.UTC)
+	result := probeAt(at, "bad_status")
+	result.HTTPStatus, result.TTFB, result.Err = 503, 40*time.Millisecond, errors.New("503 Service Unavailable")
+	if err := sink.Write(target, result); err != nil {
+		t.Fatal(err)
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case rec := <-received:
+		if rec.Schema != probeRecordSchema || rec.Target != "web" || rec.Probe != "http" || rec.Status != "bad_status" ||
+			rec.HTTPStatus != 503 || rec.Error != "503 Service Unavailable" || rec.StartTime != "2026-01-07T14:00:00Z" {
+			t.Errorf("Unexpected record %+v", rec)
+		}
+		if rec.TTFBMs == nil || *rec.TTFBMs != 40 || rec.DNSMs != nil {
+			t.Errorf("Expected only the TTFB phase, got dns=%v ttfb=%v", rec.DNSMs, rec.TTFBMs)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Collector received nothing")
+	}
+}
+
+// TestNormalizeSinks tests sink config validation
+func TestNormalizeSinks(t *testing.T) {
+	cfg := Config{Sinks: []SinkConfig{
+		{Type: "CSV"},
+		{Type: "network", Address: "collector:5170", Protocol: "sctp"},
+		{Type: "network"},
+		{Type: "kafka"},
+	}}
+	normalizeSinks(&cfg)
+	want := []SinkConfig{{Type: "csv"}, {Type: "network", Address: "collector:5170", Protocol: "tcp"}}
+	if len(cfg.Sinks) != len(want) || cfg.Sinks[0] != want[0] || cfg.Sinks[1] != want[1] {
+		t.Errorf("Expected %+v, got %+v", want, cfg.Sinks)
+	}
+
+	cfg = Config{Sinks: []SinkConfig{{Type: "kafka"}}}
+	normalizeSinks(&cfg)
+	if len(cfg.Sinks) != 1 || cfg.Sinks[0].Type != "csv" {
+		t.Errorf("Expected a fallback to CSV, got %+v", cfg.Sinks)
+	}
+}
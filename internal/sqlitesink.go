@@ -0,0 +1,137 @@
+// internal/sqlitesink.go
+// @version sqlite
+// @description SQLite result sink: every probe, and the events detected in each period, in the history database
+
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// sqliteSink stores results in the history database. Rows are buffered and
+// inserted in one transaction per flush; when a period closes its events are
+// detected from the stored probes, the same way the summaries do.
+type sqliteSink struct {
+	cfg     Config
+	path    string
+	db      *HistoryDB
+	pending []HistoryProbe
+	start   time.Time
+	targets []TargetConfig
+}
+
+func newSQLiteSink(cfg Config, path string) *sqliteSink {
+	return &sqliteSink{cfg: cfg, path: path}
+}
+
+func (s *sqliteSink) Name() string { return "sqlite:" + s.path }
+
+// open opens the database on first use, retrying on later calls if it fails.
+func (s *sqliteSink) open() error {
+	if s.db != nil {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	db, err := OpenHistory(s.path, s.cfg.location())
+	if err != nil {
+		return err
+	}
+	s.db = db
+	return nil
+}
+
+func (s *sqliteSink) OpenPeriod(start time.Time, targets []TargetConfig) error {
+	s.start, s.targets = start, targets
+	return s.open()
+}
+
+func (s *sqliteSink) Write(target TargetConfig, result ProbeResult) error {
+	s.pending = append(s.pending, HistoryProbe{Target: target.Label(), Host: target.Host, Probe: target.Probe, Result: result})
+	return nil
+}
+
+// Flush inserts the buffered rows. If the database is unavailable they are
+// kept for the next flush, up to sinkQueue of them.
+func (s *sqliteSink) Flush() error {
+	if len(s.pending) == 0 {
+		return nil
+	}
+	err := s.open()
+	if err == nil {
+		err = s.db.insertProbes(s.pending)
+	}
+	if err != nil {
+		if n := len(s.pending) - sinkQueue; n > 0 {
+			s.pending = s.pending[n:]
+		}
+		return err
+	}
+	s.pending = s.pending[:0]
+	return nil
+}
+
+// ClosePeriod stores the events each target had during the period.
+func (s *sqliteSink) ClosePeriod() error {
+	err := s.Flush()
+	if err != nil || s.db == nil || s.start.IsZero() {
+		return err
+	}
+	period := s.start.Format(s.cfg.periodSpec().layout)
+	for _, target := range s.targets {
+		err = errors.Join(err, s.storeEvents(target, period))
+	}
+	return err
+}
+
+func (s *sqliteSink) storeEvents(target TargetConfig, period string) error {
+	probes, err := s.db.QueryProbes(ProbeQuery{Target: target.Label(), From: s.start})
+	if err != nil {
+		return fmt.Errorf("%s events: %w", target.Label(), err)
+	}
+	pings := make([]PingRecord, len(probes))
+	for i, p := range probes {
+		pings[i] = PingRecord{
+			StartTime: p.Result.StartTime,
+			EndTime:   p.Result.EndTime,
+			Latency:   p.Result.Latency.Milliseconds(),
+			Status:    p.Result.Status,
+			Family:    p.Result.Family,
+		}
+	}
+
+	var events []HistoryEvent
+	for _, e := range s.cfg.EventDetector(target.Thresholds).Detect(pings) {
+		he := HistoryEvent{Target: target.Label(), Period: period, Family: e.Family, StartTime: e.StartTime, EndTime: e.EndTime}
+		for _, p := range pings[e.StartIndex : e.EndIndex+1] {
+			if e.Family == "v4" || e.Family == "v6" {
+				if p.Family != e.Family {
+					continue
+				}
+			}
+			he.TotalPings++
+			if isBadPing(p, target.Thresholds) {
+				he.BadPings++
+			}
+		}
+		events = append(events, he)
+	}
+	if err := s.db.replaceEvents(target.Label(), period, events); err != nil {
+		return fmt.Errorf("%s events: %w", target.Label(), err)
+	}
+	return nil
+}
+
+func (s *sqliteSink) Close() error {
+	err := s.Flush()
+	if s.db != nil {
+		err = errors.Join(err, s.db.Close())
+		s.db = nil
+	}
+	return err
+}
@@ -0,0 +1,253 @@
+// internal/state.go
+// @version live-state
+// @description In-memory view of recent probe results and event state per target
+
+package internal
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+// liveHistory is how many recent pings are kept per target for the API.
+const liveHistory = 1000
+
+// latencyBuckets are the upper bounds of the latency histogram, in seconds.
+var latencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 15}
+
+// LiveState receives every probe result as it is logged, so exporters can
+// report on targets without re-reading the CSV files. Each target's events
+// are followed by a StreamDetector as the results come in.
+type LiveState struct {
+	mu          sync.Mutex
+	targets     []*targetState // in config order
+	byName      map[string]*targetState
+	listeners   []func(EventNotice)
+	subscribers []*subscriber
+	rules       EventRules
+}
+
+// targetState is the live view of one target.
+type targetState struct {
+	target   TargetConfig
+	recent   []PingLine // oldest first, at most liveHistory
+	counts   map[statusKey]uint64
+	latency  map[string]*histogram // successful probes, by family
+	detector *StreamDetector
+	event    EventStatus
+}
+
+type statusKey struct {
+	family string
+	status string
+}
+
+// histogram counts observations per latencyBuckets bound; the extra last
+// count is for the +Inf bucket. Counts are not cumulative.
+type histogram struct {
+	counts []uint64
+	sum    time.Duration
+	count  uint64
+}
+
+// NewLiveState creates an empty state for the configured targets.
+func NewLiveState(targets []TargetConfig) *LiveState {
+	return NewLiveStateWithRules(targets, DefaultEventRules)
+}
+
+// NewLiveStateWithRules is NewLiveState with events detected under rules.
+func NewLiveStateWithRules(targets []TargetConfig, rules EventRules) *LiveState {
+	s := &LiveState{byName: map[string]*targetState{}, rules: rules}
+	for _, target := range targets {
+		s.add(target)
+	}
+	return s
+}
+
+func (s *LiveState) add(target TargetConfig) *targetState {
+	st := &targetState{
+		target:   target,
+		counts:   map[statusKey]uint64{},
+		latency:  map[string]*histogram{},
+		detector: NewStreamDetector(s.rules, target.Thresholds),
+	}
+	s.targets = append(s.targets, st)
+	s.byName[target.Label()] = st
+	return st
+}
+
+// OnEvent registers fn to be called whenever an event starts or ends on a
+// target. It must be called before results are recorded.
+func (s *LiveState) OnEvent(fn func(EventNotice)) {
+	s.listeners = append(s.listeners, fn)
+}
+
+// Record adds a logged probe result for target and feeds it to the target's
+// event detector, notifying listeners and subscribers of any transition.
+// Each target's results must arrive in the order its probes started.
+func (s *LiveState) Record(target TargetConfig, result ProbeResult) {
+	if s == nil {
+		return
+	}
+	notice, changed := s.record(target, result)
+	if changed {
+		for _, fn := range s.listeners {
+			fn(notice)
+		}
+	}
+}
+
+func (s *LiveState) record(target TargetConfig, result ProbeResult) (EventNotice, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st, ok := s.byName[target.Label()]
+	if !ok {
+		st = s.add(target)
+	}
+
+	row := pingRow(result)
+	st.recent = append(st.recent, PingLine{
+		StartTime: row[0],
+		EndTime:   row[1],
+		Latency:   result.Latency.Milliseconds(),
+		Status:    result.Status,
+		Family:    result.Family,
+		Raw:       row,
+	})
+	if len(st.recent) > liveHistory {
+		st.recent = append(st.recent[:0], st.recent[len(st.recent)-liveHistory:]...)
+	}
+
+	st.counts[statusKey{result.Family, result.Status}]++
+	if !isFailureStatus(result.Status) {
+		h, ok := st.latency[result.Family]
+		if !ok {
+			h = &histogram{counts: make([]uint64, len(latencyBuckets)+1)}
+			st.latency[result.Family] = h
+		}
+		h.observe(result.Latency)
+	}
+
+	t, changed := st.detector.Add(result)
+	st.event = st.detector.Status()
+	if !changed {
+		return EventNotice{}, false
+	}
+	t.Target = st.target.Label()
+	s.publish(t)
+	if t.Kind == EventUpdated {
+		return EventNotice{}, false
+	}
+	return st.notice(t), true
+}
+
+// notice describes a start or end transition for OnEvent listeners.
+func (st *targetState) notice(t EventTransition) EventNotice {
+	return EventNotice{
+		Kind:       t.Kind.String(),
+		Target:     t.Target,
+		Host:       st.target.Host,
+		StartTime:  t.Event.StartTime,
+		EndTime:    t.Event.EndTime,
+		Duration:   t.Event.Duration,
+		BadPings:   t.BadPings,
+		TotalPings: t.TotalPings,
+		Class:      t.Event.Class,
+		Severity:   t.Event.Severity,
+	}
+}
+
+// Subscribe returns a channel receiving every event transition on every
+// target, updates included, until cancel is called. Transitions are sent
+// without blocking the probes, so a subscriber that falls more than buffer
+// behind misses some.
+func (s *LiveState) Subscribe(buffer int) (transitions <-chan EventTransition, cancel func()) {
+	sub := &subscriber{ch: make(chan EventTransition, buffer)}
+	s.mu.Lock()
+	s.subscribers = append(s.subscribers, sub)
+	s.mu.Unlock()
+
+	var once sync.Once
+	return sub.ch, func() {
+		once.Do(func() {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			s.subscribers = slices.DeleteFunc(s.subscribers, func(other *subscriber) bool { return other == sub })
+			close(sub.ch)
+		})
+	}
+}
+
+// subscriber is one Subscribe channel.
+type subscriber struct {
+	ch      chan EventTransition
+	dropped bool // warned about falling behind
+}
+
+// publish sends t to every subscriber that has room for it. s.mu is held.
+func (s *LiveState) publish(t EventTransition) {
+	for _, sub := range s.subscribers {
+		select {
+		case sub.ch <- t:
+		default:
+			if !sub.dropped {
+				sub.dropped = true
+				fmt.Println("[Events] A subscriber is falling behind, dropping event transitions")
+			}
+		}
+	}
+}
+
+// targetSnapshot is a copy of one target's live view, safe to read unlocked.
+type targetSnapshot struct {
+	target TargetConfig
+	recent []PingLine
+	event  EventStatus
+}
+
+// snapshot copies the recent pings and event state of every target, in
+// config order.
+func (s *LiveState) snapshot() []targetSnapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	snaps := make([]targetSnapshot, 0, len(s.targets))
+	for _, st := range s.targets {
+		snaps = append(snaps, targetSnapshot{
+			target: st.target,
+			recent: slices.Clone(st.recent),
+			event:  st.event,
+		})
+	}
+	return snaps
+}
+
+// inLocation converts t to loc, leaving a zero time zero.
+func inLocation(t time.Time, loc *time.Location) time.Time {
+	if t.IsZero() {
+		return t
+	}
+	return t.In(loc)
+}
+
+// inZone reinterprets the wall clock of a zone-less timestamp in loc.
+func inZone(t time.Time, loc *time.Location) time.Time {
+	if t.IsZero() {
+		return t
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), loc)
+}
+
+func (h *histogram) observe(latency time.Duration) {
+	seconds := latency.Seconds()
+	i := 0
+	for i < len(latencyBuckets) && seconds > latencyBuckets[i] {
+		i++
+	}
+	h.counts[i]++
+	h.sum += latency
+	h.count++
+}
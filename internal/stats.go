@@ -0,0 +1,155 @@
+// internal/stats.go
+// @version stats
+// @description Latency percentiles, jitter and loss statistics for summaries and tailmonke
+
+package internal
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// PingStats are the latency and loss statistics of a series of pings.
+// Latency figures cover answered pings only; a ping is lost when it failed
+// outright (timeout, unreachable, nxdomain, ...).
+type PingStats struct {
+	Total      int
+	Lost       int
+	P50        int64   // ms
+	P90        int64   // ms
+	P95        int64   // ms
+	P99        int64   // ms
+	Max        int64   // ms
+	Jitter     float64 // ms, RFC 3550 interarrival jitter, averaged over address families
+	LossPct    float64
+	LossStreak int     // longest run of consecutive lost pings of one address family
+	Available  float64 // percentage of pings that were neither lost nor over the severe threshold
+}
+
+// statsHeader lists the summary columns written from PingStats, after the
+// status counts.
+var statsHeader = []string{
+	"P50 (ms)", "P90 (ms)", "P95 (ms)", "P99 (ms)", "Max (ms)", "Jitter (ms)",
+	"Loss (%)", "Longest Loss Streak", "Availability (%)",
+}
+
+// computeStats computes the statistics of pings, in the order they were sent.
+func computeStats(pings []PingRecord, th Thresholds) PingStats {
+	s := PingStats{Total: len(pings)}
+	if len(pings) == 0 {
+		return s
+	}
+
+	var latencies []int64
+	available := 0
+	streak := map[string]int{}     // current loss run per family
+	last := map[string]int64{}     // previous answered latency per family
+	jitter := map[string]float64{} // running jitter per family
+	for _, p := range pings {
+		if kind := badKind(p, th); kind != pingLost && kind != pingSevere {
+			available++
+		}
+		if isFailureStatus(p.Status) {
+			s.Lost++
+			streak[p.Family]++
+			s.LossStreak = max(s.LossStreak, streak[p.Family])
+			continue
+		}
+		streak[p.Family] = 0
+		latencies = append(latencies, p.Latency)
+
+		// RFC 3550 6.4.1: J += (|D| - J) / 16, where D is the change in
+		// transit time between consecutive packets, here the round trip
+		if prev, ok := last[p.Family]; ok {
+			d := math.Abs(float64(p.Latency - prev))
+			jitter[p.Family] += (d - jitter[p.Family]) / 16
+		}
+		last[p.Family] = p.Latency
+	}
+
+	if len(jitter) > 0 {
+		for _, j := range jitter {
+			s.Jitter += j
+		}
+		s.Jitter /= float64(len(jitter))
+	}
+	s.LossPct = 100 * float64(s.Lost) / float64(s.Total)
+	s.Available = 100 * float64(available) / float64(s.Total)
+	if len(latencies) > 0 {
+		slices.Sort(latencies)
+		s.P50 = percentile(latencies, 50)
+		s.P90 = percentile(latencies, 90)
+		s.P95 = percentile(latencies, 95)
+		s.P99 = percentile(latencies, 99)
+		s.Max = latencies[len(latencies)-1]
+	}
+	return s
+}
+
+// percentile returns the nearest-rank p-th percentile of sorted values.
+func percentile(sorted []int64, p float64) int64 {
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	return sorted[max(rank, 1)-1]
+}
+
+// StatsForLines computes the statistics of tailed lines.
+func StatsForLines(lines []PingLine, th Thresholds) PingStats {
+	pings := make([]PingRecord, len(lines))
+	for i, line := range lines {
+		pings[i] = PingRecord{Latency: line.Latency, Status: line.Status, Family: line.Family}
+	}
+	return computeStats(pings, th)
+}
+
+// row formats the statistics as the columns of statsHeader.
+func (s PingStats) row() []string {
+	return []string{
+		strconv.FormatInt(s.P50, 10),
+		strconv.FormatInt(s.P90, 10),
+		strconv.FormatInt(s.P95, 10),
+		strconv.FormatInt(s.P99, 10),
+		strconv.FormatInt(s.Max, 10),
+		fmt.Sprintf("%.1f", s.Jitter),
+		fmt.Sprintf("%.2f", s.LossPct),
+		strconv.Itoa(s.LossStreak),
+		fmt.Sprintf("%.2f", s.Available),
+	}
+}
+
+// FormatStatsLine returns the percentile, jitter and loss line shown under
+// tailmonke's summary, colored by the target's thresholds.
+func FormatStatsLine(s PingStats, th Thresholds) string {
+	if s.Total == 0 {
+		return ""
+	}
+	latencyColor := func(ms int64) string {
+		if ms <= 0 {
+			return ColorMagenta
+		}
+		return tierColor(classifyStatus(time.Duration(ms)*time.Millisecond, th))
+	}
+	lossColor := ColorGreen
+	if s.Lost > 0 {
+		lossColor = ColorRed
+	}
+	availColor := ColorGreen
+	if s.Available < 99 {
+		availColor = ColorYellow
+	}
+	if s.Available < 95 {
+		availColor = ColorRed
+	}
+
+	return fmt.Sprintf("p50 %s%dms%s | p90 %s%dms%s | p95 %s%dms%s | p99 %s%dms%s | max %s%dms%s | Jitter: %.1fms | %sLoss: %.2f%% (streak %d)%s | %sAvail: %.2f%%%s",
+		latencyColor(s.P50), s.P50, ColorReset,
+		latencyColor(s.P90), s.P90, ColorReset,
+		latencyColor(s.P95), s.P95, ColorReset,
+		latencyColor(s.P99), s.P99, ColorReset,
+		latencyColor(s.Max), s.Max, ColorReset,
+		s.Jitter,
+		lossColor, s.LossPct, s.LossStreak, ColorReset,
+		availColor, s.Available, ColorReset)
+}
@@ -0,0 +1,158 @@
+package internal
+
+import (
+	"encoding/csv"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestComputeStats tests percentiles, jitter, loss and availability
+func TestComputeStats(t *testing.T) {
+	var pings []PingRecord
+	for i := int64(1); i <= 100; i++ {
+		pings = append(pings, PingRecord{Latency: i * 10, Status: "ok", Family: "v4"})
+	}
+	// Three lost in a row, and one lost on its own
+	for _, status := range []string{"timeout", "timeout", "unreachable", "ok", "timeout"} {
+		pings = append(pings, PingRecord{Status: status, Family: "v4"})
+	}
+	pings[103].Latency = 20
+
+	s := computeStats(pings, DefaultThresholds)
+	if s.Total != 105 || s.Lost != 4 || s.LossStreak != 3 {
+		t.Errorf("Expected 105 pings, 4 lost, streak 3, got %+v", s)
+	}
+	// 101 answered: 10..1000ms and one 20ms
+	if s.P50 != 500 || s.P90 != 900 || s.P95 != 950 || s.P99 != 990 || s.Max != 1000 {
+		t.Errorf("Unexpected percentiles %+v", s)
+	}
+	if math.Abs(s.LossPct-100*4.0/105) > 1e-9 {
+		t.Errorf("Unexpected loss %v", s.LossPct)
+	}
+	// Without a severe tier, only lost pings count against availability
+	if want := 100 * 101.0 / 105; math.Abs(s.Available-want) > 1e-9 {
+		t.Errorf("Expected availability %v, got %v", want, s.Available)
+	}
+	if s.Jitter <= 0 {
+		t.Errorf("Expected some jitter, got %v", s.Jitter)
+	}
+
+	if s := computeStats(nil, DefaultThresholds); s != (PingStats{}) {
+		t.Errorf("Expected zero stats for no pings, got %+v", s)
+	}
+}
+
+// TestAvailability tests that delayed pings are available and severe ones are not
+func TestAvailability(t *testing.T) {
+	th := Thresholds{OK: 100 * time.Millisecond, Delayed: 300 * time.Millisecond}
+	var pings []PingRecord
+	for _, ms := range []int64{50, 150, 250, 400} {
+		pings = append(pings, PingRecord{Latency: ms, Status: "ok", Family: "v4"})
+	}
+	pings = append(pings, PingRecord{Status: "timeout", Family: "v4"})
+
+	// ok, delayed and delayed are up; severe and lost are down
+	if got := computeStats(pings, th).Available; got != 60 {
+		t.Errorf("Expected 60%% availability, got %v", got)
+	}
+}
+
+// TestJitter tests the RFC 3550 running estimate against a hand-worked series
+func TestJitter(t *testing.T) {
+	var pings []PingRecord
+	for _, ms := range []int64{20, 36, 20, 36} {
+		pings = append(pings, PingRecord{Latency: ms, Status: "ok", Family: "v4"})
+	}
+	// J = 16/16 = 1, then 1 + 15/16, then 1.9375 + 14.0625/16
+	want := 1.9375 + (16-1.9375)/16
+	if got := computeStats(pings, DefaultThresholds).Jitter; math.Abs(got-want) > 1e-9 {
+		t.Errorf("Expected jitter %v, got %v", want, got)
+	}
+
+	// Families are differenced separately, so alternating v4/v6 at steady latencies has no jitter
+	pings = nil
+	for i := 0; i < 10; i++ {
+		pings = append(pings, PingRecord{Latency: 10, Status: "ok", Family: "v4"}, PingRecord{Latency: 40, Status: "ok", Family: "v6"})
+	}
+	if got := computeStats(pings, DefaultThresholds).Jitter; got != 0 {
+		t.Errorf("Expected no jitter across families, got %v", got)
+	}
+
+	// Each family keeps its own estimate: v4 reaches 1, v6 stays at 0
+	pings = []PingRecord{
+		{Latency: 20, Status: "ok", Family: "v4"}, {Latency: 10, Status: "ok", Family: "v6"},
+		{Latency: 36, Status: "ok", Family: "v4"}, {Latency: 10, Status: "ok", Family: "v6"},
+	}
+	if got := computeStats(pings, DefaultThresholds).Jitter; got != 0.5 {
+		t.Errorf("Expected the families' jitter averaged to 0.5, got %v", got)
+	}
+}
+
+// TestSummaryStats tests that the statistics are written to the summary file
+func TestSummaryStats(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "2026-01-07-isp-pings.csv")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := csv.NewWriter(f)
+	w.Write(pingCSVHeader)
+	base := time.Date(2026, 1, 7, 14, 0, 0, 0, time.UTC)
+	for i, latency := range []string{"10", "20", "0", "30"} {
+		status := "ok"
+		if latency == "0" {
+			status = "timeout"
+		}
+		at := base.Add(time.Duration(i) * 15 * time.Second)
+		w.Write([]string{formatTimestamp(at), formatTimestamp(at), latency, status, "v4"})
+	}
+	w.Flush()
+	f.Close()
+
+	generateSummary(path, Config{}, DefaultThresholds)
+	sf, err := os.Open(filepath.Join(dir, "2026-01-07-isp-pings-summary.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sf.Close()
+	reader := csv.NewReader(sf)
+	reader.FieldsPerRecord = -1
+	rows, err := reader.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The original columns keep their positions; later ones are appended
+	if layout := strings.Join(rows[0][:6], ","); layout != "Total,OK,Delayed,Timeout,Events,Failed" {
+		t.Errorf("Unexpected column layout %s", layout)
+	}
+	got := map[string]string{}
+	for i, name := range rows[0] {
+		got[name] = rows[1][i]
+	}
+	for name, want := range map[string]string{
+		"Total": "4", "Timeout": "1", "P50 (ms)": "20", "Max (ms)": "30", "Jitter (ms)": "1.2",
+		"Loss (%)": "25.00", "Longest Loss Streak": "1", "Availability (%)": "75.00",
+	} {
+		if got[name] != want {
+			t.Errorf("%s: expected %s, got %q (row %s)", name, want, got[name], strings.Join(rows[1], ","))
+		}
+	}
+}
+
+// TestFormatStatsLine tests tailmonke's statistics line
+func TestFormatStatsLine(t *testing.T) {
+	if FormatStatsLine(PingStats{}, DefaultThresholds) != "" {
+		t.Error("Expected no stats line without pings")
+	}
+	line := FormatStatsLine(PingStats{Total: 10, Lost: 1, P50: 12, P90: 30, P95: 40, P99: 90, Max: 120, Jitter: 2.5, LossPct: 10, LossStreak: 1, Available: 90}, DefaultThresholds)
+	for _, want := range []string{"p50 " + ColorGreen + "12ms", "max " + ColorYellow + "120ms", "Jitter: 2.5ms", ColorRed + "Loss: 10.00% (streak 1)", ColorRed + "Avail: 90.00%"} {
+		if !strings.Contains(line, want) {
+			t.Errorf("Expected %q in %q", want, line)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+// internal/stream.go
+// @version stream
+// @description Incremental event detection over live probe results, with transitions for subscribers
+
+package internal
+
+import (
+	"time"
+)
+
+// EventTransitionKind is what happened to a target's event.
+type EventTransitionKind int
+
+const (
+	EventStarted EventTransitionKind = iota + 1
+	EventUpdated                     // another ping arrived during the event
+	EventEnded
+)
+
+func (k EventTransitionKind) String() string {
+	switch k {
+	case EventStarted:
+		return "start"
+	case EventUpdated:
+		return "update"
+	case EventEnded:
+		return "end"
+	}
+	return "unknown"
+}
+
+// EventTransition reports a change in one target's event state.
+type EventTransition struct {
+	Kind       EventTransitionKind
+	Target     string      // target label, set by LiveState
+	Event      EventStatus // the event after the transition
+	BadPings   int         // in the event, from its first bad ping to its last
+	TotalPings int
+}
+
+// StreamDetector follows one target's event state a probe result at a time,
+// under the same rules as EventDetector. Each result costs the same however
+// long the target has been running: nothing is rescanned or re-parsed.
+//
+// Dual-stack events are merged while they overlap as detected, so an event
+// on one family that is only recognized after the other family's event has
+// ended is reported as a new event, where a summary would merge the two.
+type StreamDetector struct {
+	rules  EventRules
+	th     Thresholds
+	series map[string]*streamSeries // by address family
+	status EventStatus
+	end    time.Time // latest end of the event's parts that have ended
+
+	mix eventMix // pings of the event's parts that have ended
+}
+
+// streamSeries is the detection state of one address family.
+type streamSeries struct {
+	tracker seriesTracker
+	count   int // pings seen
+}
+
+// NewStreamDetector creates a detector applying rules to results judged by th.
+func NewStreamDetector(rules EventRules, th Thresholds) *StreamDetector {
+	return &StreamDetector{rules: validEventRules(rules), th: th, series: map[string]*streamSeries{}}
+}
+
+// StreamDetector returns a configured streaming detector for a target with thresholds th.
+func (cfg Config) StreamDetector(th Thresholds) *StreamDetector {
+	return NewStreamDetector(cfg.Events, th)
+}
+
+// Status returns the state of the current, or most recent, event.
+func (d *StreamDetector) Status() EventStatus {
+	return d.status
+}
+
+// Add feeds a probe result, in the order they were sent, and returns the
+// transition it caused, if any.
+func (d *StreamDetector) Add(result ProbeResult) (EventTransition, bool) {
+	return d.add(PingRecord{
+		StartTime: result.StartTime,
+		EndTime:   result.EndTime,
+		Latency:   result.Latency.Milliseconds(),
+		Status:    result.Status,
+		Family:    result.Family,
+	})
+}
+
+func (d *StreamDetector) add(p PingRecord) (EventTransition, bool) {
+	s, ok := d.series[p.Family]
+	if !ok {
+		s = &streamSeries{tracker: seriesTracker{rules: d.rules, th: d.th}}
+		d.series[p.Family] = s
+	}
+	wasActive := d.status.IsActive
+	started, ended := s.tracker.add(s.count, p)
+	s.count++
+	d.status.LatestPingTime = p.StartTime
+
+	e := s.tracker.event
+	switch {
+	case started && !wasActive:
+		d.status = EventStatus{StartTime: e.StartTime, LatestPingTime: p.StartTime}
+		d.end = time.Time{}
+		d.mix = eventMix{}
+	case started && e.StartTime.Before(d.status.StartTime):
+		d.status.StartTime = e.StartTime // the other family went bad first
+	case ended:
+		if e.EndTime.After(d.end) {
+			d.end = e.EndTime
+		}
+		d.mix = d.mix.plus(e.mix)
+	}
+	if (s.tracker.active || ended) && s.tracker.lastBad.After(d.status.LastBadPing) {
+		d.status.LastBadPing = s.tracker.lastBad
+	}
+
+	var t EventTransition
+	mix, active := d.mix, false
+	for _, s := range d.series {
+		if s.tracker.active {
+			active = true
+			mix = mix.plus(s.tracker.event.mix)
+		}
+	}
+
+	switch {
+	case active:
+		d.status.IsActive = true
+		d.status.Duration = d.status.LatestPingTime.Sub(d.status.StartTime)
+		t.Kind = EventUpdated
+		if !wasActive {
+			t.Kind = EventStarted
+		}
+	case wasActive:
+		d.status.IsActive = false
+		d.status.EndTime = d.end
+		d.status.Duration = d.end.Sub(d.status.StartTime)
+		t.Kind = EventEnded
+	default:
+		return EventTransition{}, false
+	}
+	d.status.Class, d.status.Severity = mix.classify(d.status.Duration)
+	t.Event = d.status
+	t.BadPings, t.TotalPings = mix.bad(), mix.total
+	return t, true
+}
+
+// addLine feeds a tailed line, skipping it if its start time doesn't parse.
+func (d *StreamDetector) addLine(line PingLine) {
+	start, err := parsePingTime(line.StartTime)
+	if err != nil {
+		return
+	}
+	end, _ := parsePingTime(line.EndTime)
+	d.add(PingRecord{StartTime: start, EndTime: end, Latency: line.Latency, Status: line.Status, Family: line.Family})
+}
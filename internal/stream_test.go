@@ -0,0 +1,151 @@
+package internal
+
+import (
+	"testing"
+	"time"
+)
+
+// sameStatus compares event states by instant rather than time zone
+func sameStatus(a, b EventStatus) bool {
+	return a.IsActive == b.IsActive && a.StartTime.Equal(b.StartTime) && a.EndTime.Equal(b.EndTime) &&
+		a.LastBadPing.Equal(b.LastBadPing) && a.LatestPingTime.Equal(b.LatestPingTime) && a.Duration == b.Duration
+}
+
+// TestStreamDetectorMatchesEventDetector tests that feeding results one at a
+// time gives the same event state as detecting over the whole history
+func TestStreamDetectorMatchesEventDetector(t *testing.T) {
+	th := Thresholds{OK: 30 * time.Millisecond, Degraded: 80 * time.Millisecond}
+	for n, rules := range []EventRules{
+		DefaultEventRules,
+		{Window: 6, BadCount: 3, Within: 45 * time.Second, RecoverPings: 3, RecoverTime: 30 * time.Second},
+	} {
+		stream := NewStreamDetector(rules, th)
+		batch := NewEventDetector(rules, th)
+		var lines []PingLine
+		for i, p := range randomPings(int64(n+10), []string{"v4"}, 400) {
+			row := pingRow(ProbeResult{StartTime: p.StartTime, EndTime: p.EndTime, Status: p.Status, Family: p.Family})
+			lines = append(lines, PingLine{StartTime: row[0], EndTime: row[1], Latency: p.Latency, Status: p.Status, Family: p.Family})
+			stream.Add(ProbeResult{StartTime: p.StartTime, EndTime: p.EndTime, Latency: time.Duration(p.Latency) * time.Millisecond, Status: p.Status, Family: p.Family})
+
+			if got, want := stream.Status(), batch.Status(lines); !sameStatus(got, want) {
+				t.Fatalf("%+v: after ping %d expected %+v, got %+v", rules, i, want, got)
+			}
+		}
+	}
+}
+
+// TestStreamDetectorTransitions tests the transitions emitted over an event
+func TestStreamDetectorTransitions(t *testing.T) {
+	base := time.Date(2026, 1, 7, 14, 0, 0, 0, time.UTC)
+	d := NewStreamDetector(DefaultEventRules, DefaultThresholds)
+	var kinds []EventTransitionKind
+	var last EventTransition
+	for i, status := range []string{"ok", "timeout", "timeout", "timeout", "ok", "ok", "ok", "ok", "ok"} {
+		at := base.Add(time.Duration(i) * 15 * time.Second)
+		if tr, ok := d.Add(ProbeResult{StartTime: at, EndTime: at, Latency: 10 * time.Millisecond, Status: status, Family: "v4"}); ok {
+			kinds = append(kinds, tr.Kind)
+			last = tr
+		}
+	}
+	want := []EventTransitionKind{EventStarted, EventUpdated, EventUpdated, EventUpdated, EventUpdated, EventEnded}
+	if len(kinds) != len(want) {
+		t.Fatalf("Expected transitions %v, got %v", want, kinds)
+	}
+	for i := range want {
+		if kinds[i] != want[i] {
+			t.Errorf("Transition %d: expected %v, got %v", i, want[i], kinds[i])
+		}
+	}
+	if last.Event.IsActive || !last.Event.StartTime.Equal(base.Add(15*time.Second)) || !last.Event.EndTime.Equal(base.Add(time.Minute)) ||
+		!last.Event.LastBadPing.Equal(base.Add(45*time.Second)) || last.BadPings != 3 || last.TotalPings != 3 {
+		t.Errorf("Unexpected end %+v", last)
+	}
+	if status := d.Status(); status.IsActive || !status.LatestPingTime.Equal(base.Add(2*time.Minute)) || status.Duration != 45*time.Second {
+		t.Errorf("Expected the ended event to be kept with the latest ping, got %+v", status)
+	}
+
+	// Dual stack: the v6 event is seen first, then v4 turns out to have gone
+	// bad earlier, and the event lasts until both recover
+	d = NewStreamDetector(DefaultEventRules, DefaultThresholds)
+	ticks := [][2]string{{"ok", "ok"}, {"timeout", "ok"}, {"ok", "timeout"}, {"ok", "timeout"}, {"timeout", "ok"}, {"ok", "ok"}, {"ok", "ok"}, {"ok", "ok"}, {"ok", "ok"}, {"ok", "ok"}, {"ok", "ok"}, {"ok", "ok"}}
+	var transitions []EventTransition
+	for i, tick := range ticks {
+		at := base.Add(time.Duration(i) * 15 * time.Second)
+		for j, family := range []string{"v4", "v6"} {
+			if tr, ok := d.Add(ProbeResult{StartTime: at, EndTime: at, Latency: 10 * time.Millisecond, Status: tick[j], Family: family}); ok && tr.Kind != EventUpdated {
+				transitions = append(transitions, tr)
+			}
+		}
+	}
+	if len(transitions) != 2 || transitions[0].Kind != EventStarted || transitions[1].Kind != EventEnded {
+		t.Fatalf("Expected one start and one end, got %+v", transitions)
+	}
+	if start := transitions[0].Event.StartTime; !start.Equal(base.Add(30 * time.Second)) {
+		t.Errorf("Expected the v6 start first, got %v", start)
+	}
+	end := transitions[1]
+	if !end.Event.StartTime.Equal(base.Add(15*time.Second)) || !end.Event.EndTime.Equal(base.Add(75*time.Second)) || end.BadPings != 4 || end.TotalPings != 6 {
+		t.Errorf("Unexpected end %+v", end)
+	}
+}
+
+// TestLiveStateSubscribe tests that subscribers get transitions without holding up the probes
+func TestLiveStateSubscribe(t *testing.T) {
+	target := TargetConfig{Name: "isp", Thresholds: DefaultThresholds}
+	state := NewLiveState([]TargetConfig{target})
+	transitions, cancel := state.Subscribe(16)
+	slow, cancelSlow := state.Subscribe(1) // never read
+
+	base := time.Date(2026, 1, 7, 14, 0, 0, 0, time.UTC)
+	for i, status := range []string{"ok", "timeout", "timeout", "ok", "ok", "ok", "ok"} {
+		recordPing(state, target, base.Add(time.Duration(i)*15*time.Second), "v4", status, 10*time.Millisecond)
+	}
+	cancel()
+	cancelSlow()
+
+	var kinds []EventTransitionKind
+	for tr := range transitions {
+		if tr.Target != "isp" {
+			t.Errorf("Unexpected target %q", tr.Target)
+		}
+		kinds = append(kinds, tr.Kind)
+	}
+	if len(kinds) != 5 || kinds[0] != EventStarted || kinds[4] != EventEnded {
+		t.Errorf("Expected a start, updates and an end, got %v", kinds)
+	}
+	// The slow subscriber kept what fit in its buffer and missed the rest
+	if tr, ok := <-slow; !ok || tr.Kind != EventStarted {
+		t.Errorf("Expected the slow subscriber to keep the first transition, got %v", tr.Kind)
+	}
+	if _, ok := <-slow; ok {
+		t.Error("Expected the slow subscriber's channel to be closed after the first transition")
+	}
+}
+
+// TestTailmonkeFeedsNewLines tests that tailmonke's event state follows a
+// growing file without rescanning it, and starts over on a different file
+func TestTailmonkeFeedsNewLines(t *testing.T) {
+	var all []PingLine
+	for _, p := range randomPings(3, []string{"v4"}, 300) {
+		row := pingRow(ProbeResult{StartTime: p.StartTime, EndTime: p.EndTime, Status: p.Status, Family: p.Family})
+		all = append(all, PingLine{StartTime: row[0], EndTime: row[1], Latency: p.Latency, Status: p.Status, Family: p.Family})
+	}
+
+	m := NewTailmonkeModelWithOptions("2026-01-07-pings.csv", defaultTailConfig(20), true, DefaultThresholds)
+	batch := NewEventDetector(DefaultEventRules, DefaultThresholds)
+	for n := 0; n <= len(all); n += 7 {
+		m.lines = all[:n]
+		m.updateHealthState()
+		if want := batch.Status(all[:n]); !sameStatus(m.eventStatus, want) {
+			t.Fatalf("After %d lines expected %+v, got %+v", n, want, m.eventStatus)
+		}
+	}
+	fed := m.detector
+
+	m.filePath = "2026-01-08-pings.csv"
+	m.lines = all[100:150]
+	m.updateHealthState()
+	if m.detector == fed || !sameStatus(m.eventStatus, batch.Status(all[100:150])) {
+		t.Errorf("Expected a new file to start detection over, got %+v", m.eventStatus)
+	}
+}
@@ -4,7 +4,8 @@ import (
 	"encoding/csv"
 	"fmt"
 	"os"
-	"strconv"
+	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 )
@@ -15,66 +16,71 @@ type PingRecord struct {
 	EndTime   time.Time
 	Latency   int64 // milliseconds
 	Status    string
+	Family    string // "v4", "v6", or "" for logs written before the AF column existed
 }
 
 // Event represents a network event (outage/degradation)
 type Event struct {
-	StartIndex int // index in records
-	EndIndex   int // index in records
+	StartIndex int // index in records of the first bad ping
+	EndIndex   int // index in records of the last bad ping
 	StartTime  time.Time
-	EndTime    time.Time
+	EndTime    time.Time // when the pings came good again, or the latest ping if Ongoing
+	Family     string    // affected address family in dual-stack logs: "v4", "v6" or "both"
+	Ongoing    bool      // the pings ended before the event recovered
+	Class      string    // EventOutage, EventDegradation, EventMixed, EventFlapping or EventMicroBlip
+	Severity   float64   // 0 to 100
+
+	mix eventMix // the event's pings by how they went wrong
 }
 
-func generateSummary(logFile string, cfg Config) {
-	generateSummaryWithLogging(logFile, cfg, false)
+func generateSummary(logFile string, cfg Config, th Thresholds) {
+	generateSummaryWithLogging(logFile, cfg, th, false)
 }
 
-func generateSummaryWithLogging(logFile string, cfg Config, returnMessage bool) string {
+func generateSummaryWithLogging(logFile string, cfg Config, th Thresholds, returnMessage bool) string {
 	var messages []string
-	f, err := os.Open(logFile)
-	if err != nil {
+	log, err := readPingLog(logFile)
+	if err != nil && (log == nil || len(log.rows) == 0) {
 		if returnMessage {
 			return fmt.Sprintf("[Summary] Error opening log file: %v", err)
 		}
 		fmt.Println("[Summary] Error opening log file:", err)
 		return ""
 	}
-	defer f.Close()
-
-	reader := csv.NewReader(f)
-	records, _ := reader.ReadAll()
-	records = records[1:] // skip header
-
-	// Parse records
-	var pings []PingRecord
-	okCount, delayedCount, timeoutCount := 0, 0, 0
-	for _, row := range records {
-		startTime, _ := time.Parse(time.RFC3339, row[0])
-		endTime, _ := time.Parse(time.RFC3339, row[1])
-		latency, _ := strconv.ParseInt(row[2], 10, 64)
-		status := row[3]
-
-		pings = append(pings, PingRecord{
-			StartTime: startTime,
-			EndTime:   endTime,
-			Latency:   latency,
-			Status:    status,
-		})
-
-		switch status {
-		case "ok":
+	if skipped := log.malformedSummary(); skipped != "" {
+		if returnMessage {
+			messages = append(messages, "[Summary] "+skipped)
+		} else {
+			fmt.Println("[Summary]", skipped)
+		}
+	}
+
+	// Count records
+	pings := log.records()
+	okCount, delayedCount, timeoutCount, failedCount := 0, 0, 0, 0
+	degradedCount, severeCount := 0, 0
+	for _, p := range pings {
+		switch status := p.Status; {
+		case status == "ok":
 			okCount++
-		case "delayed":
+		case status == "degraded":
+			degradedCount++
+		case status == "delayed":
 			delayedCount++
-		case "timeout":
+		case status == "severe":
+			severeCount++
+		case status == "timeout":
 			timeoutCount++
+		case isFailureStatus(status):
+			failedCount++ // explicit failures: unreachable, nxdomain, servfail, bad_status, ...
 		}
 	}
 
 	// Detect events
-	events := detectEvents(pings, cfg.DebugMode)
+	events := cfg.EventDetector(th).Detect(pings)
 
-	summaryFile := logFile[:len(logFile)-4] + "-summary.csv"
+	logName := strings.TrimSuffix(logFile, gzipExt)
+	summaryFile := strings.TrimSuffix(logName, filepath.Ext(logName)) + "-summary.csv"
 	sf, err := os.Create(summaryFile)
 	if err != nil {
 		if returnMessage {
@@ -87,15 +93,19 @@ func generateSummaryWithLogging(logFile string, cfg Config, returnMessage bool)
 
 	writer := csv.NewWriter(sf)
 
-	// Write summary counts
-	writer.Write([]string{"Total", "OK", "Delayed", "Timeout", "Events"})
-	writer.Write([]string{
+	// Write summary counts, then latency and loss statistics
+	stats := computeStats(pings, th)
+	writer.Write(append([]string{"Total", "OK", "Delayed", "Timeout", "Events", "Failed", "Degraded", "Severe"}, statsHeader...))
+	writer.Write(append([]string{
 		fmt.Sprintf("%d", len(pings)),
 		fmt.Sprintf("%d", okCount),
 		fmt.Sprintf("%d", delayedCount),
 		fmt.Sprintf("%d", timeoutCount),
 		fmt.Sprintf("%d", len(events)),
-	})
+		fmt.Sprintf("%d", failedCount),
+		fmt.Sprintf("%d", degradedCount),
+		fmt.Sprintf("%d", severeCount),
+	}, stats.row()...))
 	writer.Write([]string{}) // blank line
 
 	// Write events
@@ -115,19 +125,30 @@ func generateSummaryWithLogging(logFile string, cfg Config, returnMessage bool)
 				"Duration",
 				fmt.Sprintf("%v", duration),
 			})
+			writer.Write([]string{
+				"Class",
+				event.Class,
+			})
+			writer.Write([]string{
+				"Severity",
+				fmt.Sprintf("%.1f", event.Severity),
+			})
+			writer.Write([]string{
+				"Statuses",
+				eventStatusBreakdown(pings[event.StartIndex:event.EndIndex+1], th),
+			})
+			if event.Family != "" {
+				writer.Write([]string{
+					"Scope",
+					eventScopeLabel(event.Family),
+				})
+			}
 
 			// Get context: 3 pings before and after
-			startContext := event.StartIndex - 3
-			if startContext < 0 {
-				startContext = 0
-			}
-			endContext := event.EndIndex + 3
-			if endContext >= len(pings) {
-				endContext = len(pings) - 1
-			}
+			startContext, endContext := eventContext(event, len(pings))
 
 			writer.Write([]string{}) // blank line
-			writer.Write([]string{"Ping Init", "Ping Rec", "Ping Time (ms)", "Status"})
+			writer.Write([]string{"Ping Init", "Ping Rec", "Ping Time (ms)", "Status", "AF"})
 
 			for j := startContext; j <= endContext; j++ {
 				p := pings[j]
@@ -140,6 +161,7 @@ func generateSummaryWithLogging(logFile string, cfg Config, returnMessage bool)
 					formatTimestampForSummary(p.EndTime),
 					fmt.Sprintf("%d", p.Latency),
 					p.Status,
+					p.Family,
 				})
 			}
 			writer.Write([]string{}) // blank line
@@ -154,94 +176,78 @@ func generateSummaryWithLogging(logFile string, cfg Config, returnMessage bool)
 		fmt.Println(msg)
 	}
 
+	if cfg.SummaryHTML {
+		htmlFile := strings.TrimSuffix(summaryFile, ".csv") + ".html"
+		msg := fmt.Sprintf("[Summary] HTML summary written to %s", htmlFile)
+		if err := writeSummaryHTML(htmlFile, logFile, pings, events, th); err != nil {
+			msg = fmt.Sprintf("[Summary] Error writing HTML summary: %v", err)
+		}
+		if returnMessage {
+			messages = append(messages, msg)
+		} else {
+			fmt.Println(msg)
+		}
+	}
+
 	if returnMessage {
 		return strings.Join(messages, " | ")
 	}
 	return ""
 }
 
+// readPingRecords parses a ping log into records, skipping malformed rows
+// and a final row still being written.
+func readPingRecords(logFile string) ([]PingRecord, error) {
+	log, err := readPingLog(logFile)
+	if err != nil {
+		return nil, err
+	}
+	return log.records(), nil
+}
+
 // formatTimestampForSummary formats a timestamp as YYYY-MM-DD HH:MM:SS.mmm
 func formatTimestampForSummary(t time.Time) string {
 	return t.Format("2006-01-02 15:04:05.000")
 }
 
-// detectEvents finds network events based on ping quality
-// For debug mode: event starts with 2+ bad pings within 15 seconds, ends after 15 seconds of good pings
-// For normal mode: event starts with 2+ bad pings within 60 seconds, ends after 60 seconds of good pings
-func detectEvents(pings []PingRecord, debugMode bool) []Event {
-	var events []Event
-
-	windowDuration := 60 * time.Second
-	if debugMode {
-		windowDuration = 15 * time.Second
-	}
-
-	i := 0
-	for i < len(pings) {
-		// Look for event start: 2 bad pings within window duration
-		eventStart := -1
-		for j := i; j < len(pings)-1; j++ {
-			if isBadPing(pings[j]) && isBadPing(pings[j+1]) {
-				// Check if they're within window duration
-				timeDiff := pings[j+1].StartTime.Sub(pings[j].StartTime)
-				if timeDiff <= windowDuration {
-					eventStart = j
-					break
-				}
-			}
-		}
-
-		if eventStart == -1 {
-			break
-		}
-
-		// Find event end: windowDuration of good pings
-		eventEnd := eventStart
-		goodCount := 0
-		goodStartTime := time.Time{}
-
-		for j := eventStart; j < len(pings); j++ {
-			if isBadPing(pings[j]) {
-				goodCount = 0
-				goodStartTime = time.Time{}
-				eventEnd = j
-			} else {
-				if goodCount == 0 {
-					goodStartTime = pings[j].StartTime
-				}
-				goodCount++
-				if goodStartTime.Add(windowDuration).Before(pings[j].StartTime) || goodStartTime.Add(windowDuration).Equal(pings[j].StartTime) {
-					// We have windowDuration of good pings
-					eventEnd = j
-					events = append(events, Event{
-						StartIndex: eventStart,
-						EndIndex:   eventEnd,
-						StartTime:  pings[eventStart].StartTime,
-						EndTime:    pings[eventEnd].EndTime,
-					})
-					i = j + 1
-					goto nextEvent
-				}
-			}
+// eventStatusBreakdown counts the bad pings of an event by status, e.g.
+// "nxdomain=3 timeout=1", so DNS failures stand out from packet loss.
+func eventStatusBreakdown(pings []PingRecord, th Thresholds) string {
+	counts := map[string]int{}
+	for _, p := range pings {
+		if isBadPing(p, th) {
+			counts[p.Status]++
 		}
+	}
+	statuses := make([]string, 0, len(counts))
+	for status := range counts {
+		statuses = append(statuses, status)
+	}
+	sort.Strings(statuses)
 
-		// Event goes to end of period
-		if eventStart < len(pings) {
-			events = append(events, Event{
-				StartIndex: eventStart,
-				EndIndex:   len(pings) - 1,
-				StartTime:  pings[eventStart].StartTime,
-				EndTime:    pings[len(pings)-1].EndTime,
-			})
-		}
-		break
-
-	nextEvent:
+	parts := make([]string, len(statuses))
+	for i, status := range statuses {
+		parts[i] = fmt.Sprintf("%s=%d", status, counts[status])
 	}
+	return strings.Join(parts, " ")
+}
 
-	return events
+// eventScopeLabel describes which address families an event affected; what
+// kind of event it was is left to its class
+func eventScopeLabel(family string) string {
+	switch family {
+	case "v4":
+		return "IPv4 only"
+	case "v6":
+		return "IPv6 only"
+	case "both":
+		return "Both families"
+	}
+	return family
 }
 
-func isBadPing(p PingRecord) bool {
-	return p.Status == "timeout" || p.Latency >= 100
+// isBadPing judges a ping by its recorded latency against the target's
+// thresholds, the same way spawnPing classified it.
+func isBadPing(p PingRecord, th Thresholds) bool {
+	return isBadResult(p.Status, p.Latency, th)
 }
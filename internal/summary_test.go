@@ -5,8 +5,8 @@ import (
 	"time"
 )
 
-// TestEventDetectionDebugMode tests event detection with 15 second windows
-func TestEventDetectionDebugMode(t *testing.T) {
+// TestEventDetectionDebugInterval tests event detection at the 5 second debug interval
+func TestEventDetectionDebugInterval(t *testing.T) {
 	baseTime := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
 
 	tests := []struct {
@@ -56,7 +56,7 @@ func TestEventDetectionDebugMode(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			events := detectEvents(tt.pings, true) // debug mode = true
+			events := NewEventDetector(DefaultEventRules, DefaultThresholds).Detect(tt.pings)
 			if len(events) != tt.expectedEvents {
 				t.Errorf("Expected %d events, got %d", tt.expectedEvents, len(events))
 			}
@@ -64,8 +64,8 @@ func TestEventDetectionDebugMode(t *testing.T) {
 	}
 }
 
-// TestEventDetectionNormalMode tests event detection with 60 second windows
-func TestEventDetectionNormalMode(t *testing.T) {
+// TestEventDetectionNormalInterval tests event detection with bad pings up to 60 seconds apart
+func TestEventDetectionNormalInterval(t *testing.T) {
 	baseTime := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
 
 	tests := []struct {
@@ -106,7 +106,7 @@ func TestEventDetectionNormalMode(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			events := detectEvents(tt.pings, false) // debug mode = false
+			events := NewEventDetector(DefaultEventRules, DefaultThresholds).Detect(tt.pings)
 			if len(events) != tt.expectedEvents {
 				t.Errorf("Expected %d events, got %d", tt.expectedEvents, len(events))
 			}
@@ -150,10 +150,126 @@ func TestIsBadPing(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := isBadPing(tt.ping)
+			result := isBadPing(tt.ping, DefaultThresholds)
 			if result != tt.isBad {
 				t.Errorf("Expected isBadPing to be %v, got %v", tt.isBad, result)
 			}
 		})
 	}
 }
+
+// TestIsBadPingTiers tests that only the delayed and severe tiers count as bad
+func TestIsBadPingTiers(t *testing.T) {
+	th := Thresholds{OK: 30 * time.Millisecond, Degraded: 80 * time.Millisecond, Delayed: 250 * time.Millisecond}
+	tests := []struct {
+		name  string
+		ping  PingRecord
+		isBad bool
+	}{
+		{"Ok tier", PingRecord{Status: "ok", Latency: 20}, false},
+		{"Degraded tier", PingRecord{Status: "degraded", Latency: 50}, false},
+		{"Delayed tier", PingRecord{Status: "delayed", Latency: 120}, true},
+		{"Severe tier", PingRecord{Status: "severe", Latency: 400}, true},
+		{"Failure regardless of latency", PingRecord{Status: "unreachable", Latency: 0}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := isBadPing(tt.ping, th)
+			if result != tt.isBad {
+				t.Errorf("Expected isBadPing to be %v, got %v", tt.isBad, result)
+			}
+		})
+	}
+}
+
+// TestEventDetectionDualStack tests that per-family outages are told apart from full outages
+func TestEventDetectionDualStack(t *testing.T) {
+	baseTime := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	// tick builds one v4 and one v6 ping at the given offset
+	tick := func(offset time.Duration, v4Status, v6Status string) []PingRecord {
+		latency := func(status string) int64 {
+			if status == "timeout" {
+				return 0
+			}
+			return 20
+		}
+		return []PingRecord{
+			{StartTime: baseTime.Add(offset), EndTime: baseTime.Add(offset), Status: v4Status, Latency: latency(v4Status), Family: "v4"},
+			{StartTime: baseTime.Add(offset), EndTime: baseTime.Add(offset), Status: v6Status, Latency: latency(v6Status), Family: "v6"},
+		}
+	}
+
+	tests := []struct {
+		name           string
+		ticks          [][2]string
+		expectedFamily []string
+	}{
+		{
+			name:           "Both families healthy",
+			ticks:          [][2]string{{"ok", "ok"}, {"ok", "ok"}, {"ok", "ok"}, {"ok", "ok"}},
+			expectedFamily: nil,
+		},
+		{
+			name:           "IPv6-only outage",
+			ticks:          [][2]string{{"ok", "ok"}, {"ok", "timeout"}, {"ok", "timeout"}, {"ok", "ok"}, {"ok", "ok"}, {"ok", "ok"}, {"ok", "ok"}},
+			expectedFamily: []string{"v6"},
+		},
+		{
+			name:           "Full outage",
+			ticks:          [][2]string{{"ok", "ok"}, {"timeout", "timeout"}, {"timeout", "timeout"}, {"ok", "ok"}, {"ok", "ok"}, {"ok", "ok"}, {"ok", "ok"}},
+			expectedFamily: []string{"both"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var pings []PingRecord
+			for i, statuses := range tt.ticks {
+				pings = append(pings, tick(time.Duration(i)*5*time.Second, statuses[0], statuses[1])...)
+			}
+
+			events := NewEventDetector(DefaultEventRules, DefaultThresholds).Detect(pings)
+			if len(events) != len(tt.expectedFamily) {
+				t.Fatalf("Expected %d events, got %d", len(tt.expectedFamily), len(events))
+			}
+			for i, event := range events {
+				if event.Family != tt.expectedFamily[i] {
+					t.Errorf("Event %d: expected family %q, got %q", i, tt.expectedFamily[i], event.Family)
+				}
+			}
+		})
+	}
+}
+
+// TestEventScopeLabel tests that the scope names the families and not the kind of event
+func TestEventScopeLabel(t *testing.T) {
+	for family, want := range map[string]string{"v4": "IPv4 only", "v6": "IPv6 only", "both": "Both families"} {
+		if got := eventScopeLabel(family); got != want {
+			t.Errorf("eventScopeLabel(%q) = %q, want %q", family, got, want)
+		}
+	}
+}
+
+// TestProbeFamilies tests expansion of the address_family setting
+func TestProbeFamilies(t *testing.T) {
+	tests := map[string][]string{
+		"v4":   {"v4"},
+		"v6":   {"v6"},
+		"both": {"v4", "v6"},
+		"":     {"v4"},
+	}
+	for setting, want := range tests {
+		got := probeFamilies(setting)
+		if len(got) != len(want) {
+			t.Errorf("probeFamilies(%q) = %v, want %v", setting, got, want)
+			continue
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Errorf("probeFamilies(%q) = %v, want %v", setting, got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,214 @@
+// internal/targets.go
+// @version multi-target
+// @description Per-target probe settings and normalisation of legacy single-target configs
+
+package internal
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// TargetConfig describes one monitored host. Fields left empty inherit the
+// top-level settings in Config.
+type TargetConfig struct {
+	Name          string          `yaml:"name"`
+	Host          string          `yaml:"host"`
+	Probe         string          `yaml:"probe"` // tcp, icmp, http or dns
+	Port          int             `yaml:"port"`
+	Interval      time.Duration   `yaml:"interval"`
+	AddressFamily string          `yaml:"address_family"` // v4, v6 or both
+	Thresholds    Thresholds      `yaml:"thresholds"`
+	HTTP          HTTPProbeConfig `yaml:"http"`
+	DNS           DNSProbeConfig  `yaml:"dns"`
+}
+
+// Thresholds holds the latency cutoffs used to classify successful probes.
+// Each is the exclusive upper bound of its tier; Degraded and Delayed are
+// optional and disabled when 0.
+type Thresholds struct {
+	OK       time.Duration `yaml:"ok"`
+	Degraded time.Duration `yaml:"degraded"`
+	Delayed  time.Duration `yaml:"delayed"` // when set, slower pings are severe
+}
+
+// DefaultThresholds matches the fixed 100ms cutoff pingmonke has always used.
+var DefaultThresholds = Thresholds{OK: 100 * time.Millisecond}
+
+// minInterval is the shortest interval a target may be probed at.
+const minInterval = time.Second
+
+// normalizeTargets fills in cfg.Targets. A config without a targets list
+// becomes a single unnamed target built from the top-level fields, so its
+// log files keep their original names.
+func normalizeTargets(cfg *Config) {
+	if len(cfg.Targets) == 0 {
+		probe := "tcp"
+		if cfg.UseICMP {
+			probe = "icmp"
+		}
+		cfg.Targets = []TargetConfig{{Host: cfg.Target, Probe: probe}}
+	}
+
+	seen := map[string]bool{}
+	for i := range cfg.Targets {
+		t := &cfg.Targets[i]
+		if t.Host == "" && t.HTTP.URL != "" {
+			if u, err := url.Parse(t.HTTP.URL); err == nil {
+				t.Host = u.Hostname()
+			}
+		}
+		if t.Host == "" {
+			t.Host = cfg.Target
+		}
+		if t.Name == "" && len(cfg.Targets) > 1 {
+			t.Name = t.Host
+		}
+		t.Name = sanitizeTargetName(t.Name)
+		if seen[t.Name] {
+			fmt.Printf("[Config] Duplicate target name %q, renaming to %q\n", t.Name, fmt.Sprintf("%s_%d", t.Name, i+1))
+			t.Name = fmt.Sprintf("%s_%d", t.Name, i+1)
+		}
+		seen[t.Name] = true
+
+		switch t.Probe {
+		case "tcp", "icmp", "http", "dns":
+		case "":
+			t.Probe = "tcp"
+			if cfg.UseICMP {
+				t.Probe = "icmp"
+			}
+		default:
+			fmt.Printf("[Config] Unknown probe %q for target %s, using tcp\n", t.Probe, t.Label())
+			t.Probe = "tcp"
+		}
+
+		if t.Port == 0 {
+			t.Port = cfg.Port
+		}
+		if t.Interval == 0 {
+			t.Interval = cfg.Interval
+		}
+		if t.Interval < minInterval {
+			fmt.Printf("[Config] Interval %v for target %s is under %v, using %v\n", t.Interval, t.Label(), minInterval, minInterval)
+			t.Interval = minInterval
+		}
+		if t.AddressFamily == "" {
+			t.AddressFamily = cfg.AddressFamily
+		}
+		switch t.AddressFamily {
+		case "v4", "v6", "both":
+		default:
+			fmt.Printf("[Config] Unknown address_family %q for target %s, using v4\n", t.AddressFamily, t.Label())
+			t.AddressFamily = "v4"
+		}
+		if t.Probe == "http" {
+			if t.HTTP.URL == "" {
+				t.HTTP.URL = defaultHTTPURL(t.Host, t.Port)
+			}
+			t.HTTP.Method = strings.ToUpper(t.HTTP.Method)
+			switch t.HTTP.Method {
+			case "GET", "HEAD":
+			case "":
+				t.HTTP.Method = "GET"
+			default:
+				fmt.Printf("[Config] Unsupported http method %q for target %s, using GET\n", t.HTTP.Method, t.Label())
+				t.HTTP.Method = "GET"
+			}
+		}
+		if t.Probe == "dns" {
+			if t.DNS.Resolver == "" {
+				t.DNS.Resolver = "system"
+			} else if t.DNS.Resolver != "system" {
+				if _, _, err := net.SplitHostPort(t.DNS.Resolver); err != nil {
+					t.DNS.Resolver = net.JoinHostPort(t.DNS.Resolver, "53")
+				}
+			}
+			if t.DNS.Name == "" {
+				t.DNS.Name = t.Host
+			}
+			t.DNS.Type = strings.ToUpper(t.DNS.Type)
+			if t.DNS.Type == "" {
+				t.DNS.Type = "A"
+			}
+			if _, ok := dnsTypes[t.DNS.Type]; !ok {
+				fmt.Printf("[Config] Unsupported dns type %q for target %s, using A\n", t.DNS.Type, t.Label())
+				t.DNS.Type = "A"
+			}
+			// The resolver's own address decides which family a query uses,
+			// so DNS targets are probed once per tick.
+			if t.AddressFamily == "both" {
+				fmt.Printf("[Config] address_family both has no effect on dns target %s\n", t.Label())
+				t.AddressFamily = "v4"
+			}
+		}
+		if t.Thresholds == (Thresholds{}) {
+			t.Thresholds = cfg.Thresholds
+		} else if t.Thresholds.OK == 0 {
+			t.Thresholds.OK = cfg.Thresholds.OK
+		}
+		t.Thresholds = validThresholds(t.Thresholds, t.Label())
+	}
+}
+
+// validThresholds fills in a missing ok cutoff and drops tiers that don't
+// increase, since classifyStatus checks them in order.
+func validThresholds(th Thresholds, label string) Thresholds {
+	if th.OK <= 0 {
+		th.OK = DefaultThresholds.OK
+	}
+	if th.Degraded != 0 && th.Degraded <= th.OK {
+		fmt.Printf("[Config] Degraded threshold %v for target %s is not above ok (%v), ignoring it\n", th.Degraded, label, th.OK)
+		th.Degraded = 0
+	}
+	if th.Delayed != 0 && th.Delayed <= max(th.OK, th.Degraded) {
+		fmt.Printf("[Config] Delayed threshold %v for target %s is not above the lower tiers, ignoring it\n", th.Delayed, label)
+		th.Delayed = 0
+	}
+	return th
+}
+
+// ThresholdsFor returns the thresholds of the target whose log files are
+// named after name ("" for a single-target config), or the top-level ones.
+func (cfg Config) ThresholdsFor(name string) Thresholds {
+	for _, t := range cfg.Targets {
+		if t.Name == name && t.Thresholds != (Thresholds{}) {
+			return t.Thresholds
+		}
+	}
+	if cfg.Thresholds != (Thresholds{}) {
+		return cfg.Thresholds
+	}
+	return DefaultThresholds
+}
+
+// Label is how the target appears in console output.
+func (t TargetConfig) Label() string {
+	if t.Name != "" {
+		return t.Name
+	}
+	return t.Host
+}
+
+// interval returns how often this target is probed in the current mode.
+func (t TargetConfig) interval(cfg Config) time.Duration {
+	if cfg.DebugMode {
+		return cfg.DebugInterval
+	}
+	return t.Interval
+}
+
+// sanitizeTargetName keeps target names safe to embed in log file names. It
+// also replaces '-', which separates the fields of a log file name.
+func sanitizeTargetName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '_':
+			return r
+		}
+		return '_'
+	}, name)
+}
@@ -0,0 +1,143 @@
+package internal
+
+import (
+	"testing"
+	"time"
+)
+
+// TestNormalizeTargetsLegacyConfig tests that a config without targets keeps working
+func TestNormalizeTargetsLegacyConfig(t *testing.T) {
+	cfg := Config{
+		Target:        "example.com",
+		Port:          443,
+		Interval:      15 * time.Second,
+		UseICMP:       true,
+		AddressFamily: "both",
+	}
+	normalizeTargets(&cfg)
+
+	if len(cfg.Targets) != 1 {
+		t.Fatalf("Expected 1 target, got %d", len(cfg.Targets))
+	}
+	target := cfg.Targets[0]
+	if target.Name != "" {
+		t.Errorf("Expected unnamed legacy target, got name %q", target.Name)
+	}
+	if target.Host != "example.com" || target.Port != 443 || target.Probe != "icmp" || target.AddressFamily != "both" {
+		t.Errorf("Legacy fields not carried over: %+v", target)
+	}
+	if target.Thresholds.OK != DefaultThresholds.OK {
+		t.Errorf("Expected default thresholds, got %+v", target.Thresholds)
+	}
+}
+
+// TestNormalizeTargetsList tests per-target settings and inheritance of top-level defaults
+func TestNormalizeTargetsList(t *testing.T) {
+	cfg := Config{
+		Port:          80,
+		Interval:      15 * time.Second,
+		AddressFamily: "v4",
+		Targets: []TargetConfig{
+			{Name: "gateway", Host: "192.168.1.1", Probe: "icmp", Interval: 5 * time.Second,
+				Thresholds: Thresholds{OK: 10 * time.Millisecond}},
+			{Host: "1.1.1.1", Port: 443},
+			{Name: "isp-hop", Host: "10.0.0.1", Probe: "bogus"},
+		},
+	}
+	normalizeTargets(&cfg)
+
+	gateway := cfg.Targets[0]
+	if gateway.Interval != 5*time.Second || gateway.Thresholds.OK != 10*time.Millisecond || gateway.Port != 80 {
+		t.Errorf("Gateway settings not kept: %+v", gateway)
+	}
+
+	anycast := cfg.Targets[1]
+	if anycast.Name != "1.1.1.1" {
+		t.Errorf("Expected unnamed target in a list to be named after its host, got %q", anycast.Name)
+	}
+	if anycast.Probe != "tcp" || anycast.Port != 443 || anycast.Interval != 15*time.Second {
+		t.Errorf("Defaults not inherited: %+v", anycast)
+	}
+
+	hop := cfg.Targets[2]
+	if hop.Name != "isp_hop" {
+		t.Errorf("Expected '-' to be sanitized from target name, got %q", hop.Name)
+	}
+	if hop.Probe != "tcp" {
+		t.Errorf("Expected unknown probe to fall back to tcp, got %q", hop.Probe)
+	}
+}
+
+// TestNormalizeTargetsInterval tests that intervals under a second are raised to one
+func TestNormalizeTargetsInterval(t *testing.T) {
+	cfg := Config{
+		Interval: 15 * time.Second,
+		Targets: []TargetConfig{
+			{Name: "fast", Host: "192.0.2.1", Interval: 500 * time.Millisecond},
+			{Name: "negative", Host: "192.0.2.2", Interval: -5 * time.Second},
+			{Name: "steady", Host: "192.0.2.3", Interval: 2 * time.Second},
+		},
+	}
+	normalizeTargets(&cfg)
+
+	for i, want := range []time.Duration{time.Second, time.Second, 2 * time.Second} {
+		if got := cfg.Targets[i].Interval; got != want {
+			t.Errorf("%s: expected interval %v, got %v", cfg.Targets[i].Name, want, got)
+		}
+	}
+}
+
+// TestLogFileTarget tests extraction of the target name from log file names
+func TestLogFileTarget(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/home/u/ping-logs/2026-01-07-pings.csv", ""},
+		{"/home/u/ping-logs/2026-01-07-gateway-pings.csv", "gateway"},
+		{"2026-01-07-1.1.1.1-pings.csv", "1.1.1.1"},
+		{"14:05:00.000-pings.csv", ""},
+		{"14:05:00.000-isp_hop-pings.csv", "isp_hop"},
+		{"2026-01-07T14-pings.csv", ""},
+		{"2026-01-07T14-gateway-pings.csv", "gateway"},
+		{"2026-01-07T1430-30-pings.csv", "30"},
+		{"2026-01-07T1430-pings.csv", ""},
+		{"2026-01-07-gateway-pings.jsonl", "gateway"},
+		{"2026-01-07T14-pings.jsonl", ""},
+	}
+
+	for _, tt := range tests {
+		if got := LogFileTarget(tt.path); got != tt.want {
+			t.Errorf("LogFileTarget(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+// TestNormalizeTargetsThresholds tests inheritance and validation of latency tiers
+func TestNormalizeTargetsThresholds(t *testing.T) {
+	cfg := Config{
+		Thresholds: Thresholds{OK: 30 * time.Millisecond, Degraded: 80 * time.Millisecond, Delayed: 250 * time.Millisecond},
+		Targets: []TargetConfig{
+			{Name: "inherits", Host: "192.0.2.1"},
+			{Name: "partial", Host: "192.0.2.2", Thresholds: Thresholds{Delayed: 500 * time.Millisecond}},
+			{Name: "unordered", Host: "192.0.2.3", Thresholds: Thresholds{OK: 50 * time.Millisecond, Degraded: 40 * time.Millisecond, Delayed: 45 * time.Millisecond}},
+		},
+	}
+	normalizeTargets(&cfg)
+
+	if got := cfg.Targets[0].Thresholds; got != cfg.Thresholds {
+		t.Errorf("Expected top-level thresholds to be inherited, got %+v", got)
+	}
+	if got := cfg.Targets[1].Thresholds; got.OK != 30*time.Millisecond || got.Degraded != 0 || got.Delayed != 500*time.Millisecond {
+		t.Errorf("Expected ok inherited and only delayed set, got %+v", got)
+	}
+	if got := cfg.Targets[2].Thresholds; got != (Thresholds{OK: 50 * time.Millisecond}) {
+		t.Errorf("Expected tiers below ok to be dropped, got %+v", got)
+	}
+	if got := cfg.ThresholdsFor("partial"); got != cfg.Targets[1].Thresholds {
+		t.Errorf("ThresholdsFor returned %+v", got)
+	}
+	if got := cfg.ThresholdsFor("unknown"); got != cfg.Thresholds {
+		t.Errorf("Expected top-level thresholds for unknown target, got %+v", got)
+	}
+}
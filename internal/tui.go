@@ -1,10 +1,7 @@
 package internal
 
 import (
-	"encoding/csv"
 	"fmt"
-	"os"
-	"strconv"
 	"time"
 
 	"github.com/charmbracelet/lipgloss"
@@ -26,70 +23,58 @@ type PingLine struct {
 	EndTime   string
 	Latency   int64
 	Status    string
+	Family    string // "v4"/"v6", empty for logs without an AF column
 	Raw       []string
 }
 
-// GetColoredLine returns the ping line with appropriate color formatting
-func (p *PingLine) GetColoredLine(widths []int) string {
+// GetColoredLine returns the ping line colored by its latency tier under th
+func (p *PingLine) GetColoredLine(widths []int, th Thresholds) string {
 	color := ColorReset
 	switch {
-	case p.Status == "timeout":
+	case isFailureStatus(p.Status):
 		color = ColorRed
-	case p.Latency >= 100:
-		color = ColorYellow
-	case p.Latency > 0 && p.Latency < 100:
-		color = ColorGreen
-	default:
+	case p.Latency <= 0:
 		color = ColorMagenta
+	default:
+		color = tierColor(classifyStatus(time.Duration(p.Latency)*time.Millisecond, th))
 	}
 
 	// Format with padding, adding "ms" suffix to latency
-	line := fmt.Sprintf("%s%-*s %-*s %-*s %-12s%s",
+	line := fmt.Sprintf("%s%-*s %-*s %-*s %-12s %-4s%s",
 		color,
 		widths[0], p.StartTime,
 		widths[1], p.EndTime,
 		widths[2], fmt.Sprintf("%dms", p.Latency),
 		p.Status,
+		p.Family,
 		ColorReset)
 	return line
 }
 
-// ReadPingFile reads the ping CSV file and returns records
+// ReadPingFile reads a ping log, CSV or JSON Lines, and returns records.
+// Malformed rows and a final row still being written are skipped.
 func ReadPingFile(filePath string) ([]PingLine, error) {
-	f, err := os.Open(filePath)
-	if err != nil {
-		return nil, err
-	}
-	defer f.Close()
-
-	reader := csv.NewReader(f)
-	records, err := reader.ReadAll()
+	log, err := readPingLog(filePath)
 	if err != nil {
 		return nil, err
 	}
+	return log.lines(), nil
+}
 
-	if len(records) == 0 {
-		return nil, fmt.Errorf("empty file")
-	}
-
-	// Skip header
-	var lines []PingLine
-	for i := 1; i < len(records); i++ {
-		if len(records[i]) < 4 {
-			continue
+// lines returns the log's rows as tailmonke lines.
+func (l *pingLog) lines() []PingLine {
+	lines := make([]PingLine, len(l.rows))
+	for i, row := range l.rows {
+		lines[i] = PingLine{
+			StartTime: row.fields[0],
+			EndTime:   row.fields[1],
+			Latency:   row.record.Latency,
+			Status:    row.record.Status,
+			Family:    row.record.Family,
+			Raw:       row.fields,
 		}
-
-		latency, _ := strconv.ParseInt(records[i][2], 10, 64)
-		lines = append(lines, PingLine{
-			StartTime: records[i][0],
-			EndTime:   records[i][1],
-			Latency:   latency,
-			Status:    records[i][3],
-			Raw:       records[i],
-		})
 	}
-
-	return lines, nil
+	return lines
 }
 
 // GetSummaryStats reads a ping file and returns summary statistics
@@ -98,20 +83,24 @@ func GetSummaryStats(filePath string) (total, ok, delayed, timeout int, avgLaten
 	if err != nil {
 		return
 	}
+	return SummarizeLines(lines)
+}
 
+// SummarizeLines computes the GetSummaryStats figures over lines
+func SummarizeLines(lines []PingLine) (total, ok, delayed, timeout int, avgLatency float64) {
 	total = len(lines)
 	var totalLatency int64
 
 	for _, line := range lines {
-		switch line.Status {
-		case "ok":
+		switch {
+		case line.Status == "ok":
 			ok++
-		case "delayed":
-			delayed++
-		case "timeout":
+		case isFailureStatus(line.Status):
 			timeout++
+		default:
+			delayed++ // degraded, delayed and severe
 		}
-		if line.Status != "timeout" {
+		if !isFailureStatus(line.Status) {
 			totalLatency += line.Latency
 		}
 	}
@@ -147,15 +136,17 @@ func FormatHeader(healthColor string) string {
 		"Ping Rec",
 		"Time",
 		"Status",
+		"AF",
 	}
 
 	// Format header: use the styled columns but without padding
 	// Match the exact widths used in GetColoredLine
-	header := fmt.Sprintf("%-27s %-27s %-8s %-12s",
+	header := fmt.Sprintf("%-27s %-27s %-8s %-12s %-4s",
 		cols[0],
 		cols[1],
 		cols[2],
-		cols[3])
+		cols[3],
+		cols[4])
 
 	return headerStyle.Render(header)
 }
@@ -193,8 +184,9 @@ func TruncateTime(timeStr string) string {
 	return t.Format("2006-01-02 15:04:05.000")
 }
 
-// FormatSummaryLine returns a formatted summary statistics line with color coding
-func FormatSummaryLine(total, ok, delayed, timeout int, avgLatency float64) string {
+// FormatSummaryLine returns a formatted summary statistics line, with the
+// average colored by its latency tier under th
+func FormatSummaryLine(total, ok, delayed, timeout int, avgLatency float64, th Thresholds) string {
 	// Determine most severe status for Total color
 	var totalColor string
 	switch {
@@ -207,14 +199,9 @@ func FormatSummaryLine(total, ok, delayed, timeout int, avgLatency float64) stri
 	}
 
 	// Determine avg color
-	var avgColor string
-	switch {
-	case avgLatency >= 100:
-		avgColor = ColorYellow
-	case avgLatency > 0:
-		avgColor = ColorGreen
-	default:
-		avgColor = ColorMagenta
+	avgColor := ColorMagenta
+	if avgLatency > 0 {
+		avgColor = tierColor(classifyStatus(time.Duration(avgLatency*float64(time.Millisecond)), th))
 	}
 
 	return fmt.Sprintf("%sTotal: %d%s | %sOK: %d%s | %sDelayed: %d%s | %sTimeout: %d%s | %sAvg: %.0fms%s",
@@ -225,6 +212,17 @@ func FormatSummaryLine(total, ok, delayed, timeout int, avgLatency float64) stri
 		avgColor, avgLatency, ColorReset)
 }
 
+// tierColor maps a latency tier from classifyStatus onto a display color
+func tierColor(status string) string {
+	switch status {
+	case "ok":
+		return ColorGreen
+	case "severe":
+		return ColorRed
+	}
+	return ColorYellow
+}
+
 // FormatEventLine returns a formatted event status line
 func FormatEventLine(event EventStatus, healthColor string) string {
 	var statusCircle string
@@ -254,6 +252,10 @@ func FormatEventLine(event EventStatus, healthColor string) string {
 		durationStr = "Last Event Duration: N/A"
 	}
 
+	if event.Class != "" {
+		durationStr += fmt.Sprintf(" (%s, severity %.1f)", event.Class, event.Severity)
+	}
+
 	return fmt.Sprintf("Event: %s%s%s | %s%s%s",
 		statusColor, statusCircle, ColorReset,
 		statusColor, durationStr, ColorReset)
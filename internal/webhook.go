@@ -0,0 +1,297 @@
+// internal/webhook.go
+// @version webhooks
+// @description Webhook alerts on event start and end, with templated bodies, retries and flap suppression
+
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// WebhooksConfig holds the webhook endpoints and their delivery settings.
+type WebhooksConfig struct {
+	Endpoints []WebhookConfig `yaml:"endpoints"`
+	Retries   int             `yaml:"retries"`  // extra attempts after a failed delivery
+	Backoff   time.Duration   `yaml:"backoff"`  // wait before the first retry, doubled after each
+	Cooldown  time.Duration   `yaml:"cooldown"` // an event restarting this soon after ending is the same event
+}
+
+// WebhookConfig is one endpoint notified about events.
+type WebhookConfig struct {
+	URL         string            `yaml:"url"`
+	Template    string            `yaml:"template"`     // text/template for the body, default is the JSON payload
+	ContentType string            `yaml:"content_type"` // default application/json
+	Headers     map[string]string `yaml:"headers"`
+	Targets     []string          `yaml:"targets"` // only these targets; empty means all
+}
+
+// EventNotice describes an event starting or ending on one target. It is
+// the data passed to webhook templates.
+type EventNotice struct {
+	Kind       string // "start" or "end"
+	Target     string
+	Host       string
+	StartTime  time.Time
+	EndTime    time.Time // zero for "start"
+	Duration   time.Duration
+	BadPings   int
+	TotalPings int
+	Class      string  // EventOutage, EventDegradation, ...; so far, for "start"
+	Severity   float64 // 0 to 100
+}
+
+// webhookPayload is the default JSON body.
+type webhookPayload struct {
+	Event           string  `json:"event"`
+	Target          string  `json:"target"`
+	Host            string  `json:"host"`
+	StartTime       string  `json:"start_time"`
+	EndTime         string  `json:"end_time,omitempty"`
+	DurationSeconds float64 `json:"duration_seconds"`
+	BadPings        int     `json:"bad_pings"`
+	TotalPings      int     `json:"total_pings"`
+	Class           string  `json:"class"`
+	Severity        float64 `json:"severity"`
+}
+
+var webhookFuncs = template.FuncMap{
+	// json quotes a value for embedding in a JSON template
+	"json": func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+// Notifier delivers event notices to the configured webhooks. Events that
+// flap (end and restart within the cooldown) are reported once: the end is
+// held for the cooldown and dropped, together with the new start, if the
+// event comes back.
+type Notifier struct {
+	cfg       WebhooksConfig
+	templates []*template.Template // per endpoint, nil for the default payload
+	client    *http.Client
+
+	mu        sync.Mutex
+	open      map[string]*openEvent // by target, from start until the end is delivered
+	closing   bool
+	deliverWg sync.WaitGroup
+}
+
+// openEvent tracks an event that has been announced but whose end hasn't been sent.
+type openEvent struct {
+	start      time.Time
+	badPings   int // from earlier parts of a flapping event
+	totalPings int
+	severity   float64     // highest of the earlier parts
+	held       *time.Timer // pending end, nil while the event is active
+	heldPart   EventNotice // the end notice of the latest part, as detected
+	heldNotice EventNotice // merged end notice waiting to be sent
+}
+
+// NewNotifier parses the endpoint templates.
+func NewNotifier(cfg WebhooksConfig) (*Notifier, error) {
+	n := &Notifier{
+		cfg:    cfg,
+		client: &http.Client{Timeout: 10 * time.Second},
+		open:   map[string]*openEvent{},
+	}
+	for i, endpoint := range cfg.Endpoints {
+		var tmpl *template.Template
+		if endpoint.Template != "" {
+			var err error
+			tmpl, err = template.New(fmt.Sprintf("webhook%d", i)).Funcs(webhookFuncs).Parse(endpoint.Template)
+			if err != nil {
+				return nil, fmt.Errorf("webhook %s: %w", endpoint.URL, err)
+			}
+		}
+		n.templates = append(n.templates, tmpl)
+	}
+	return n, nil
+}
+
+// Notify handles an event transition reported by LiveState.
+func (n *Notifier) Notify(notice EventNotice) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.closing {
+		return
+	}
+
+	o := n.open[notice.Target]
+	switch notice.Kind {
+	case "start":
+		switch {
+		case o == nil:
+			n.open[notice.Target] = &openEvent{start: notice.StartTime}
+			n.deliver(notice)
+		case o.held != nil:
+			// Restarted within the cooldown: same event, send neither. A
+			// timer that already fired sees held cleared and does nothing.
+			o.held.Stop()
+			o.badPings += o.heldPart.BadPings
+			o.totalPings += o.heldPart.TotalPings
+			o.severity = max(o.severity, o.heldPart.Severity)
+			o.held = nil
+			fmt.Printf("[Webhook] %s event resumed within %v, not re-announcing\n", notice.Target, n.cfg.Cooldown)
+		}
+		// Otherwise the event is already announced
+
+	case "end":
+		if o == nil {
+			o = &openEvent{start: notice.StartTime}
+			n.open[notice.Target] = o
+		}
+		merged := notice
+		merged.StartTime = o.start
+		merged.Duration = notice.EndTime.Sub(o.start)
+		merged.BadPings += o.badPings
+		merged.TotalPings += o.totalPings
+		if o.totalPings > 0 {
+			// It came and went within the cooldown
+			merged.Class = EventFlapping
+			merged.Severity = max(merged.Severity, o.severity)
+		}
+		if n.cfg.Cooldown <= 0 {
+			delete(n.open, notice.Target)
+			n.deliver(merged)
+			return
+		}
+		if o.held != nil {
+			o.held.Stop()
+		}
+		o.heldPart, o.heldNotice = notice, merged
+		var timer *time.Timer
+		timer = time.AfterFunc(n.cfg.Cooldown, func() {
+			n.mu.Lock()
+			defer n.mu.Unlock()
+			if n.open[notice.Target] != o || o.held != timer {
+				return
+			}
+			delete(n.open, notice.Target)
+			n.deliver(merged)
+		})
+		o.held = timer
+	}
+}
+
+// Flush sends any ends still held for the cooldown and waits up to timeout
+// for deliveries to finish. Notices arriving afterwards are ignored.
+func (n *Notifier) Flush(timeout time.Duration) {
+	n.mu.Lock()
+	n.closing = true
+	for target, o := range n.open {
+		if o.held != nil {
+			o.held.Stop()
+			o.held = nil
+			delete(n.open, target)
+			n.deliver(o.heldNotice)
+		}
+	}
+	n.mu.Unlock()
+
+	if !waitTimeout(&n.deliverWg, timeout) {
+		fmt.Println("[Webhook] Gave up waiting for deliveries at shutdown")
+	}
+}
+
+// deliver sends notice to every endpoint that covers its target. Called with n.mu held.
+func (n *Notifier) deliver(notice EventNotice) {
+	for i, endpoint := range n.cfg.Endpoints {
+		if len(endpoint.Targets) > 0 && !slices.Contains(endpoint.Targets, notice.Target) {
+			continue
+		}
+		body, err := n.render(i, notice)
+		if err != nil {
+			fmt.Printf("[Webhook] Error rendering %s for %s: %v\n", notice.Kind, endpoint.URL, err)
+			continue
+		}
+		n.deliverWg.Add(1)
+		go func() {
+			defer n.deliverWg.Done()
+			n.post(endpoint, body, notice)
+		}()
+	}
+}
+
+// render builds the request body for endpoint i.
+func (n *Notifier) render(i int, notice EventNotice) ([]byte, error) {
+	if tmpl := n.templates[i]; tmpl != nil {
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, notice); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	payload := webhookPayload{
+		Event:           notice.Kind,
+		Target:          notice.Target,
+		Host:            notice.Host,
+		StartTime:       notice.StartTime.Format(time.RFC3339),
+		DurationSeconds: notice.Duration.Seconds(),
+		BadPings:        notice.BadPings,
+		TotalPings:      notice.TotalPings,
+		Class:           notice.Class,
+		Severity:        notice.Severity,
+	}
+	if !notice.EndTime.IsZero() {
+		payload.EndTime = notice.EndTime.Format(time.RFC3339)
+	}
+	return json.Marshal(payload)
+}
+
+// post delivers body, retrying with exponential backoff until an attempt
+// gets a 2xx response or the retries run out.
+func (n *Notifier) post(endpoint WebhookConfig, body []byte, notice EventNotice) {
+	backoff := n.cfg.Backoff
+	for attempt := 0; ; attempt++ {
+		err := n.postOnce(endpoint, body)
+		if err == nil {
+			fmt.Printf("[Webhook] Sent %s %s notice to %s\n", notice.Target, notice.Kind, endpoint.URL)
+			return
+		}
+		if attempt >= n.cfg.Retries {
+			fmt.Printf("[Webhook] Giving up on %s %s notice to %s after %d attempts: %v\n", notice.Target, notice.Kind, endpoint.URL, attempt+1, err)
+			return
+		}
+		fmt.Printf("[Webhook] Delivery to %s failed (%v), retrying in %v\n", endpoint.URL, err, backoff)
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+func (n *Notifier) postOnce(endpoint WebhookConfig, body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, endpoint.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	contentType := endpoint.ContentType
+	if contentType == "" {
+		contentType = "application/json"
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("User-Agent", "pingmonke")
+	for k, v := range endpoint.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("status %s", strings.TrimSpace(resp.Status))
+	}
+	return nil
+}
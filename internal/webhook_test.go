@@ -0,0 +1,211 @@
+package internal
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// webhookRequest is one request received by a test endpoint
+type webhookRequest struct {
+	contentType string
+	header      http.Header
+	body        []byte
+}
+
+func newWebhookServer(t *testing.T) (*httptest.Server, <-chan webhookRequest) {
+	t.Helper()
+	received := make(chan webhookRequest, 16)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- webhookRequest{contentType: r.Header.Get("Content-Type"), header: r.Header, body: body}
+	}))
+	t.Cleanup(srv.Close)
+	return srv, received
+}
+
+func nextWebhook(t *testing.T, received <-chan webhookRequest) webhookRequest {
+	t.Helper()
+	select {
+	case req := <-received:
+		return req
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timed out waiting for webhook")
+	}
+	return webhookRequest{}
+}
+
+func expectNoWebhook(t *testing.T, received <-chan webhookRequest, wait time.Duration) {
+	t.Helper()
+	select {
+	case req := <-received:
+		t.Errorf("Expected no webhook, got %s", req.body)
+	case <-time.After(wait):
+	}
+}
+
+var noticeStart = time.Date(2026, 1, 7, 14, 0, 0, 0, time.UTC)
+
+func startNotice() EventNotice {
+	return EventNotice{Kind: "start", Target: "gateway", Host: "192.168.1.1", StartTime: noticeStart, Duration: 15 * time.Second, BadPings: 2, TotalPings: 2, Class: EventMicroBlip, Severity: 50}
+}
+
+func endNotice(offset time.Duration, bad int) EventNotice {
+	return EventNotice{Kind: "end", Target: "gateway", Host: "192.168.1.1", StartTime: noticeStart.Add(offset),
+		EndTime: noticeStart.Add(offset + time.Minute), Duration: time.Minute, BadPings: bad, TotalPings: bad + 2}
+}
+
+// TestNotifierDefaultPayload tests the JSON body sent on event start and end
+func TestNotifierDefaultPayload(t *testing.T) {
+	srv, received := newWebhookServer(t)
+	n, err := NewNotifier(WebhooksConfig{Endpoints: []WebhookConfig{{URL: srv.URL}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n.Notify(startNotice())
+	req := nextWebhook(t, received)
+	if req.contentType != "application/json" {
+		t.Errorf("Expected application/json, got %q", req.contentType)
+	}
+	var payload webhookPayload
+	if err := json.Unmarshal(req.body, &payload); err != nil {
+		t.Fatalf("Invalid JSON %s: %v", req.body, err)
+	}
+	want := webhookPayload{Event: "start", Target: "gateway", Host: "192.168.1.1", StartTime: "2026-01-07T14:00:00Z", DurationSeconds: 15, BadPings: 2, TotalPings: 2, Class: "micro-blip", Severity: 50}
+	if payload != want {
+		t.Errorf("Expected %+v, got %+v", want, payload)
+	}
+
+	n.Notify(endNotice(0, 5))
+	json.Unmarshal(nextWebhook(t, received).body, &payload)
+	if payload.Event != "end" || payload.EndTime != "2026-01-07T14:01:00Z" || payload.DurationSeconds != 60 || payload.BadPings != 5 {
+		t.Errorf("Unexpected end payload %+v", payload)
+	}
+	n.Flush(time.Second)
+}
+
+// TestNotifierTemplate tests templated bodies, headers and target filters
+func TestNotifierTemplate(t *testing.T) {
+	srv, received := newWebhookServer(t)
+	n, err := NewNotifier(WebhooksConfig{Endpoints: []WebhookConfig{
+		{URL: srv.URL, Template: `{"text": {{json (printf "%s event %s, %d bad pings" .Target .Kind .BadPings)}}}`},
+		{URL: srv.URL, Template: "{{.Target}} is down", ContentType: "text/plain", Headers: map[string]string{"Title": "pingmonke"}, Targets: []string{"other"}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n.Notify(startNotice())
+	req := nextWebhook(t, received)
+	if string(req.body) != `{"text": "gateway event start, 2 bad pings"}` {
+		t.Errorf("Unexpected body %s", req.body)
+	}
+	expectNoWebhook(t, received, 100*time.Millisecond) // second endpoint only covers "other"
+
+	if _, err := NewNotifier(WebhooksConfig{Endpoints: []WebhookConfig{{URL: srv.URL, Template: "{{.Target"}}}); err == nil {
+		t.Error("Expected an invalid template to be rejected")
+	}
+}
+
+// TestNotifierRetry tests that failed deliveries are retried with backoff
+func TestNotifierRetry(t *testing.T) {
+	var attempts atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if attempts.Add(1) < 3 {
+			http.Error(w, "busy", http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	n, _ := NewNotifier(WebhooksConfig{Endpoints: []WebhookConfig{{URL: srv.URL}}, Retries: 3, Backoff: 10 * time.Millisecond})
+	n.Notify(startNotice())
+	n.Flush(2 * time.Second)
+	if got := attempts.Load(); got != 3 {
+		t.Errorf("Expected success on the 3rd attempt, got %d attempts", got)
+	}
+
+	attempts.Store(-100) // keep failing
+	n, _ = NewNotifier(WebhooksConfig{Endpoints: []WebhookConfig{{URL: srv.URL}}, Retries: 2, Backoff: time.Millisecond})
+	n.Notify(startNotice())
+	n.Flush(2 * time.Second)
+	if got := attempts.Load(); got != -97 {
+		t.Errorf("Expected 3 attempts before giving up, got %d", got+100)
+	}
+}
+
+// TestNotifierFlapping tests that an event restarting within the cooldown is reported once
+func TestNotifierFlapping(t *testing.T) {
+	srv, received := newWebhookServer(t)
+	n, _ := NewNotifier(WebhooksConfig{Endpoints: []WebhookConfig{{URL: srv.URL}}, Cooldown: 200 * time.Millisecond})
+
+	n.Notify(startNotice())
+	nextWebhook(t, received)
+
+	n.Notify(endNotice(0, 3))
+	n.Notify(EventNotice{Kind: "start", Target: "gateway", StartTime: noticeStart.Add(90 * time.Second)})
+	n.Notify(endNotice(90*time.Second, 4))
+	expectNoWebhook(t, received, 100*time.Millisecond)
+
+	var payload webhookPayload
+	json.Unmarshal(nextWebhook(t, received).body, &payload)
+	if payload.Event != "end" || payload.StartTime != "2026-01-07T14:00:00Z" || payload.DurationSeconds != 150 || payload.BadPings != 7 ||
+		payload.Class != EventFlapping {
+		t.Errorf("Expected one merged end notice, got %+v", payload)
+	}
+	expectNoWebhook(t, received, 300*time.Millisecond)
+}
+
+// TestNotifierFlushSendsHeldEnd tests that shutdown doesn't lose an end waiting out the cooldown
+func TestNotifierFlushSendsHeldEnd(t *testing.T) {
+	srv, received := newWebhookServer(t)
+	n, _ := NewNotifier(WebhooksConfig{Endpoints: []WebhookConfig{{URL: srv.URL}}, Cooldown: time.Hour})
+
+	n.Notify(startNotice())
+	nextWebhook(t, received)
+	n.Notify(endNotice(0, 3))
+	n.Flush(time.Second)
+
+	var payload webhookPayload
+	json.Unmarshal(nextWebhook(t, received).body, &payload)
+	if payload.Event != "end" {
+		t.Errorf("Expected the held end to be sent at shutdown, got %+v", payload)
+	}
+}
+
+// TestLiveStateNotifiesTransitions tests that LiveState reports event start and end
+func TestLiveStateNotifiesTransitions(t *testing.T) {
+	target := TargetConfig{Name: "isp", Host: "10.0.0.1", Thresholds: DefaultThresholds}
+	state := NewLiveState([]TargetConfig{target})
+	var mu sync.Mutex
+	var notices []EventNotice
+	state.OnEvent(func(n EventNotice) {
+		mu.Lock()
+		notices = append(notices, n)
+		mu.Unlock()
+	})
+
+	loc := time.FixedZone("CST", -6*60*60)
+	base := time.Date(2026, 1, 7, 14, 0, 0, 0, loc)
+	statuses := []string{"ok", "ok", "timeout", "timeout", "ok", "ok", "ok", "ok", "ok"}
+	for i, status := range statuses {
+		recordPing(state, target, base.Add(time.Duration(i)*15*time.Second), "v4", status, 10*time.Millisecond)
+	}
+
+	if len(notices) != 2 {
+		t.Fatalf("Expected start and end notices, got %+v", notices)
+	}
+	start, end := notices[0], notices[1]
+	if start.Kind != "start" || start.Target != "isp" || start.Host != "10.0.0.1" || !start.StartTime.Equal(base.Add(30*time.Second)) {
+		t.Errorf("Unexpected start notice %+v", start)
+	}
+	// Pings are counted from the first bad one to the last, as the sqlite sink stores them
+	if end.Kind != "end" || !end.EndTime.Equal(base.Add(60*time.Second)) || end.Duration != 30*time.Second || end.BadPings != 2 || end.TotalPings != 2 {
+		t.Errorf("Unexpected end notice %+v", end)
+	}
+}